@@ -0,0 +1,88 @@
+package tmigo
+
+import (
+	"cmp"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffPolicy decides how long the client waits before a reconnect.
+// Connection.Backoff defaults to an exponential policy without jitter built
+// from ReconnectInterval, ReconnectDecay and MaxReconnectInterval; clients
+// that share an outage should use a jittered policy so they do not reconnect
+// at the same moment.
+//
+// attempt is 1 for the first reconnect after a lost connection and grows with
+// every failed attempt. previous is the delay returned for the attempt
+// before, or 0 for the first attempt. The attempt count and previous delay
+// start over once a session has been up for Connection.BackoffReset.
+type BackoffPolicy interface {
+	Next(attempt int, previous time.Duration) time.Duration
+}
+
+// ExponentialBackoff waits Initial * Multiplier^(attempt-1), capped at Max.
+// With Jitter, the delay is drawn uniformly between 0 and that value ("full
+// jitter"), which spreads out clients that lost their connection together.
+type ExponentialBackoff struct {
+	Initial    time.Duration // Default 1s
+	Max        time.Duration // Default 30s
+	Multiplier float64       // Default 2
+	Jitter     bool
+}
+
+// Next implements BackoffPolicy
+func (b ExponentialBackoff) Next(attempt int, previous time.Duration) time.Duration {
+	initial := cmp.Or(b.Initial, time.Second)
+	limit := cmp.Or(b.Max, 30*time.Second)
+	multiplier := cmp.Or(b.Multiplier, 2)
+
+	delay := float64(initial) * math.Pow(multiplier, float64(max(attempt, 1)-1))
+	delay = min(delay, float64(limit))
+	if b.Jitter {
+		return randDuration(0, time.Duration(delay))
+	}
+	return time.Duration(delay)
+}
+
+// DecorrelatedJitterBackoff waits a random time between Base and three times
+// the previous delay, capped at Max. Delays grow like ExponentialBackoff but
+// each client follows its own sequence.
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration // Default 1s
+	Max  time.Duration // Default 30s
+}
+
+// Next implements BackoffPolicy
+func (b DecorrelatedJitterBackoff) Next(attempt int, previous time.Duration) time.Duration {
+	base := cmp.Or(b.Base, time.Second)
+	limit := cmp.Or(b.Max, 30*time.Second)
+
+	upper := max(previous*3, base)
+	return min(randDuration(base, upper), limit)
+}
+
+// FixedBackoff always waits Interval
+type FixedBackoff struct {
+	Interval time.Duration
+}
+
+// Next implements BackoffPolicy
+func (b FixedBackoff) Next(attempt int, previous time.Duration) time.Duration {
+	return b.Interval
+}
+
+// ReconnectHook is called before every automatic reconnect with the attempt
+// number, the delay picked by the backoff policy and the reason the
+// connection was lost. It returns the delay to wait instead, and false to
+// give up reconnecting. It runs outside the client's lock, so it may call
+// client methods.
+type ReconnectHook func(attempt int, delay time.Duration, reason string) (time.Duration, bool)
+
+// randDuration returns a random duration in [low, high]
+func randDuration(low, high time.Duration) time.Duration {
+	if high <= low {
+		return low
+	}
+	return low + time.Duration(rand.Int63n(int64(high-low)+1))
+}
@@ -0,0 +1,138 @@
+package tmigo
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	var previous time.Duration
+	for i, w := range want {
+		got := b.Next(i+1, previous)
+		if got != w*time.Millisecond {
+			t.Errorf("Next(%d) = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+		previous = got
+	}
+}
+
+func TestExponentialBackoff_Jitter(t *testing.T) {
+	b := ExponentialBackoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2, Jitter: true}
+
+	distinct := make(map[time.Duration]bool)
+	for range 100 {
+		got := b.Next(3, 0)
+		if got < 0 || got > 400*time.Millisecond {
+			t.Fatalf("Next(3) = %v, want within [0, 400ms]", got)
+		}
+		distinct[got] = true
+	}
+	if len(distinct) < 10 {
+		t.Errorf("Next(3) returned %d distinct delays in 100 calls", len(distinct))
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	b := DecorrelatedJitterBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+
+	var previous time.Duration
+	for attempt := 1; attempt <= 50; attempt++ {
+		got := b.Next(attempt, previous)
+		upper := min(max(previous*3, b.Base), b.Max)
+		if got < b.Base || got > upper {
+			t.Fatalf("Next(%d, %v) = %v, want within [%v, %v]", attempt, previous, got, b.Base, upper)
+		}
+		previous = got
+	}
+}
+
+func TestFixedBackoff(t *testing.T) {
+	b := FixedBackoff{Interval: 250 * time.Millisecond}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if got := b.Next(attempt, time.Second); got != b.Interval {
+			t.Errorf("Next(%d) = %v, want %v", attempt, got, b.Interval)
+		}
+	}
+}
+
+func TestClient_ReconnectHookVeto(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	var reasons []string
+	var mu sync.Mutex
+	opts.Connection.ReconnectHook = func(attempt int, delay time.Duration, reason string) (time.Duration, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		reasons = append(reasons, reason)
+		return delay, false
+	}
+	client := connectTest(t, opts)
+
+	reconnects := 0
+	client.On("reconnect", func(args ...any) { reconnects++ })
+	wait := waitFor(t, client, "disconnected", 1)
+	server.Drop()
+	wait()
+	time.Sleep(100 * time.Millisecond)
+
+	if n := server.ConnectionCount(); n != 1 {
+		t.Errorf("server saw %d connections after a vetoed reconnect, want 1", n)
+	}
+	if reconnects != 0 {
+		t.Errorf("reconnect fired %d times after a veto", reconnects)
+	}
+	if client.isReconnecting() {
+		t.Error("client is still reconnecting after a veto")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reasons) != 1 || reasons[0] == "" {
+		t.Errorf("hook called with reasons %q, want one reason", reasons)
+	}
+}
+
+func TestClient_BackoffReset(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset time.Duration
+		want  []int
+	}{
+		{"every login", 0, []int{1, 1}},
+		{"stable session", time.Hour, []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, opts := testOptions(t, "#a")
+			opts.Connection.Backoff = FixedBackoff{Interval: 10 * time.Millisecond}
+			opts.Connection.BackoffReset = tt.reset
+
+			var mu sync.Mutex
+			var attempts []int
+			opts.Connection.ReconnectHook = func(attempt int, delay time.Duration, reason string) (time.Duration, bool) {
+				mu.Lock()
+				defer mu.Unlock()
+				attempts = append(attempts, attempt)
+				return delay, true
+			}
+			client := connectTest(t, opts)
+
+			for range tt.want {
+				wait := waitFor(t, client, "roomstate", 1)
+				server.Drop()
+				wait()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(attempts, tt.want) {
+				t.Errorf("hook saw attempts %v, want %v", attempts, tt.want)
+			}
+		})
+	}
+}
@@ -1,24 +1,34 @@
 package tmigo
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/gorilla/websocket"
 )
 
 // Client represents a Twitch IRC client
 type Client struct {
 	*EventEmitter
-	state  *clientState
-	ctx    context.Context
-	cancel context.CancelFunc
-	mu     sync.RWMutex
+	state    *clientState
+	waiters  *responseWaiters
+	limiter  *rateLimiter
+	joins    *joinScheduler
+	health   *healthMonitor
+	dispatch *dispatcher
+	inbound  InboundHandler
+	outbound OutboundHandler
+	handoff  atomic.Pointer[handoverState]
+	mu       sync.RWMutex
+
+	// Set on the shards and the front client of a ShardedClient
+	forward func(m EventMeta, eventType string, args []any)
+	route   func(channel string) *Client
 }
 
 // NewClient creates a new Twitch IRC client
@@ -37,6 +47,21 @@ func NewClient(opts *ClientOptions) *Client {
 	if opts.Identity == nil {
 		opts.Identity = &Identity{}
 	}
+	if opts.RateLimit == nil {
+		opts.RateLimit = &RateLimit{}
+	}
+	if opts.Health == nil {
+		opts.Health = &Health{}
+	}
+	if opts.Dispatch == nil {
+		opts.Dispatch = &Dispatch{}
+	}
+	if opts.Streams == nil {
+		opts.Streams = &Streams{}
+	}
+	if opts.Middleware == nil {
+		opts.Middleware = &Middleware{}
+	}
 	if opts.Channels == nil {
 		opts.Channels = []string{}
 	}
@@ -45,24 +70,31 @@ func NewClient(opts *ClientOptions) *Client {
 	if opts.Options.GlobalDefaultChannel == "" {
 		opts.Options.GlobalDefaultChannel = "#tmijs"
 	}
-	if opts.Options.JoinInterval == 0 {
-		opts.Options.JoinInterval = 2000
-	}
 	if opts.Options.MessagesLogLevel == "" {
 		opts.Options.MessagesLogLevel = "info"
 	}
 
-	// Apply connection defaults
+	opts.Streams.BufferSize = cmp.Or(opts.Streams.BufferSize, 64)
+	opts.Streams.Overflow = cmp.Or(opts.Streams.Overflow, OverflowDropOldest)
+
+	// Apply connection defaults. The server and ports depend on the transport.
+	if opts.Connection.Transport == nil {
+		opts.Connection.Transport = WebSocketTransport{}
+	}
+	server, port, securePort := WebSocketTransport{}.defaults()
+	if d, ok := opts.Connection.Transport.(transportDefaults); ok {
+		server, port, securePort = d.defaults()
+	}
 	if opts.Connection.Server == "" {
-		opts.Connection.Server = "irc-ws.chat.twitch.tv"
+		opts.Connection.Server = server
 	}
 	if opts.Connection.Port == 0 {
-		opts.Connection.Port = 80
-	}
-	if opts.Connection.Secure {
-		opts.Connection.Port = 443
+		opts.Connection.Port = port
+		if opts.Connection.Secure {
+			opts.Connection.Port = securePort
+		}
 	}
-	if opts.Connection.Port == 443 {
+	if opts.Connection.Port == securePort {
 		opts.Connection.Secure = true
 	}
 	if opts.Connection.ReconnectInterval == 0 {
@@ -77,9 +109,22 @@ func NewClient(opts *ClientOptions) *Client {
 	if opts.Connection.MaxReconnectAttempts == 0 {
 		opts.Connection.MaxReconnectAttempts = 999999 // Effectively infinite
 	}
+	if opts.Connection.Backoff == nil {
+		opts.Connection.Backoff = ExponentialBackoff{
+			Initial:    opts.Connection.ReconnectInterval,
+			Max:        opts.Connection.MaxReconnectInterval,
+			Multiplier: opts.Connection.ReconnectDecay,
+		}
+	}
 	if opts.Connection.Timeout == 0 {
 		opts.Connection.Timeout = 9999 * time.Millisecond
 	}
+	if opts.Connection.WriteTimeout == 0 {
+		opts.Connection.WriteTimeout = 10 * time.Second
+	}
+	if opts.Connection.WriteQueueSize == 0 {
+		opts.Connection.WriteQueueSize = 64
+	}
 	opts.Connection.Reconnect = true // Default to true
 
 	// Create logger
@@ -98,8 +143,6 @@ func NewClient(opts *ClientOptions) *Client {
 		opts.Channels[i] = Channel(ch)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-
 	state := &clientState{
 		opts:                 opts,
 		globalDefaultChannel: Channel(opts.Options.GlobalDefaultChannel),
@@ -108,12 +151,7 @@ func NewClient(opts *ClientOptions) *Client {
 		port:                 opts.Connection.Port,
 		secure:               opts.Connection.Secure,
 		reconnect:            opts.Connection.Reconnect,
-		reconnectDecay:       opts.Connection.ReconnectDecay,
-		reconnectInterval:    opts.Connection.ReconnectInterval,
-		maxReconnectInterval: opts.Connection.MaxReconnectInterval,
 		maxReconnectAttempts: opts.Connection.MaxReconnectAttempts,
-		reconnectTimer:       opts.Connection.ReconnectInterval,
-		reconnecting:         false,
 		reconnections:        0,
 		username:             Username(opts.Identity.Username),
 		channels:             []string{},
@@ -122,10 +160,11 @@ func NewClient(opts *ClientOptions) *Client {
 		globalUserState:      GlobalUserState{},
 		userState:            make(map[string]UserState),
 		moderators:           make(map[string][]string),
+		roomStates:           make(map[string]RoomState),
 		log:                  logger,
 		currentLatency:       0,
 		latency:              time.Now(),
-		wasCloseCalled:       false,
+		connState:            StateIdle,
 	}
 
 	// Generate justinfan username if none provided
@@ -136,9 +175,18 @@ func NewClient(opts *ClientOptions) *Client {
 	client := &Client{
 		EventEmitter: NewEventEmitter(),
 		state:        state,
-		ctx:          ctx,
-		cancel:       cancel,
+		waiters:      newResponseWaiters(),
+		limiter:      newRateLimiter(opts.RateLimit),
+		health:       newHealthMonitor(opts),
 	}
+	client.dispatch = newDispatcher(opts.Dispatch, client.reportPanic)
+	client.EventEmitter.recover = client.dispatch.recovered
+	client.inbound = chainInbound(opts.Middleware.Inbound, client.processMessage)
+	client.outbound = chainOutbound(opts.Middleware.Outbound, client.writeOutbound)
+
+	client.joins = newJoinScheduler(opts, client.sendJoin, client.isConnected, func(progress JoinProgress) {
+		client.Emit("joinprogress", progress)
+	})
 
 	client.SetMaxListeners(0)
 
@@ -149,41 +197,182 @@ func (c *Client) SetLogLevel(logLevel string) error {
 	return c.state.log.SetLevel(logLevel)
 }
 
-// Connect establishes a connection to the Twitch IRC server
+// Connect establishes a connection to the Twitch IRC server. It returns once
+// the login has been sent; use ConnectContext to wait until the session is
+// ready. While the client is already connecting or connected it returns a
+// *TransitionError.
 func (c *Client) Connect() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	return c.connect(context.Background())
+}
 
-	// Calculate reconnect timer
-	c.state.reconnectTimer = time.Duration(float64(c.state.reconnectTimer) * c.state.reconnectDecay)
-	c.state.reconnectTimer = min(c.state.reconnectTimer, c.state.maxReconnectInterval)
+// ConnectContext connects and blocks until the session is ready as decided
+// by until. If the login fails, the connection closes, or ctx ends first, the
+// connection is closed without reconnecting and a *ConnectError is returned.
+func (c *Client) ConnectContext(ctx context.Context, until ConnectReady) error {
+	connected := c.waiters.add("_promiseConnect", nil)
+	authFailed := c.waiters.add("authfailed", nil)
+	// A login retried with a refreshed token is not the end of the attempt
+	closed := c.waiters.add("disconnected", func(args []any) bool {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return !c.state.tokenRetry
+	})
+	defer c.waiters.remove("_promiseConnect", connected)
+	defer c.waiters.remove("authfailed", authFailed)
+	defer c.waiters.remove("disconnected", closed)
+
+	// Register for the join results before the joins can be sent
+	var channels []string
+	joins := make(map[string]*responseWaiter)
+	if until == ReadyChannelsJoined {
+		c.mu.RLock()
+		configured := slices.Clone(c.state.opts.Channels)
+		c.mu.RUnlock()
+
+		for _, channel := range configured {
+			if _, ok := joins[channel]; ok {
+				continue
+			}
+			channels = append(channels, channel)
+			joins[channel] = c.waiters.add("joinprogress", func(args []any) bool {
+				p, ok := args[0].(JoinProgress)
+				return ok && p.Channel == channel && p.Status != JoinQueued
+			})
+		}
+		defer func() {
+			for _, w := range joins {
+				c.waiters.remove("joinprogress", w)
+			}
+		}()
+	}
 
-	return c.openConnection()
-}
+	fail := func(err *ConnectError) error {
+		c.Disconnect()
+		return err
+	}
+	closedErr := func(args []any) *ConnectError {
+		// A rejected login also closes the connection
+		select {
+		case failed := <-authFailed.ch:
+			reason, _ := failed[0].(string)
+			return &ConnectError{Reason: reason, Err: ErrAuthFailed}
+		default:
+		}
+		reason, _ := args[0].(string)
+		return &ConnectError{Reason: reason, Err: ErrConnectionClosed}
+	}
 
-// openConnection opens a WebSocket connection to the server
-func (c *Client) openConnection() error {
-	protocol := "ws"
-	if c.state.secure {
-		protocol = "wss"
+	if err := c.connect(ctx); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			// Already connecting or connected; leave that connection alone
+			return err
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fail(&ConnectError{Err: err})
 	}
 
-	url := fmt.Sprintf("%s://%s:%d/", protocol, c.state.server, c.state.port)
+	select {
+	case <-connected.ch:
+	case args := <-authFailed.ch:
+		reason, _ := args[0].(string)
+		return fail(&ConnectError{Reason: reason, Err: ErrAuthFailed})
+	case args := <-closed.ch:
+		return fail(closedErr(args))
+	case <-ctx.Done():
+		return fail(&ConnectError{Err: ctx.Err()})
+	}
 
+	for _, channel := range channels {
+		select {
+		case args := <-joins[channel].ch:
+			if p := args[0].(JoinProgress); p.Status == JoinFailed {
+				return fail(&ConnectError{Channel: channel, Err: p.Err})
+			}
+		case args := <-closed.ch:
+			return fail(closedErr(args))
+		case <-ctx.Done():
+			return fail(&ConnectError{Err: ctx.Err()})
+		}
+	}
+
+	return nil
+}
+
+// connect starts a connection attempt with ctx bounding the dial
+func (c *Client) connect(ctx context.Context) error {
+	c.mu.Lock()
+	change, err := c.transition(StateConnecting, "")
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	// An auth failure disables reconnecting until the next Connect
+	c.state.reconnect = c.state.opts.Connection.Reconnect
+	c.state.tokenRetry = false
+	c.cancelReconnect()
+	c.mu.Unlock()
+
+	c.announceState(change)
+	return c.openConnection(ctx)
+}
+
+// openConnection opens a connection to the server through the transport.
+// ctx bounds the dial only.
+func (c *Client) openConnection(ctx context.Context) error {
 	c.state.log.Info(fmt.Sprintf("Connecting to %s on port %d..", c.state.server, c.state.port))
 	c.Emit("connecting", c.state.server, c.state.port)
 
-	dialer := websocket.DefaultDialer
-	ws, _, err := dialer.Dial(url, nil)
+	if timeout := c.state.opts.Connection.HandshakeTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	conn, err := c.state.opts.Connection.Transport.Dial(ctx, c.state.opts.Connection)
 	if err != nil {
 		c.state.log.Error(fmt.Sprintf("Connection error: %v", err))
+
+		c.mu.Lock()
+		if c.state.connState != StateConnecting {
+			// Disconnect was called during the dial
+			c.mu.Unlock()
+			return err
+		}
+
+		// A failed reconnect attempt schedules the next one
+		retry := c.state.reconnected != nil
+		var plan reconnectPlan
+		if retry {
+			plan = c.planReconnect(DisconnectNetworkError, fmt.Sprintf("Unable to connect: %v", err))
+		} else {
+			plan.change, _ = c.transition(StateClosed, DisconnectNetworkError)
+		}
+		c.mu.Unlock()
+
+		c.announceState(plan.change)
+		if retry {
+			c.announceReconnect(plan)
+		}
 		return err
 	}
 
-	c.state.ws = ws
+	c.mu.Lock()
+	if c.state.connState != StateConnecting {
+		// Disconnect was called during the dial
+		c.mu.Unlock()
+		conn.Close()
+		return ErrConnectionClosed
+	}
+	c.setConnection(conn)
+	writer := c.state.writer
+	change, _ := c.transition(StateAuthenticating, "")
+	c.mu.Unlock()
+	c.announceState(change)
 
 	// Start handling messages
-	go c.handleMessages()
+	go c.handleMessages(conn, writer)
 
 	// Send authentication
 	return c.authenticate()
@@ -194,119 +383,209 @@ func (c *Client) authenticate() error {
 	c.state.log.Info("Sending authentication to server..")
 	c.Emit("logon")
 
+	err := c.sendLogin(c.write)
+	if err != nil {
+		// Without a login the server would close the connection later
+		c.mu.Lock()
+		c.state.closeMessage = fmt.Sprintf("Unable to log in: %v", err)
+		conn := c.state.conn
+		c.mu.Unlock()
+		if conn != nil {
+			conn.Close()
+		}
+	}
+	return err
+}
+
+// sendLogin writes the capability request, PASS and NICK
+func (c *Client) sendLogin(write func(line string) error) error {
+	username := c.GetUsername()
+
 	// Request capabilities
 	caps := "twitch.tv/tags twitch.tv/commands"
 	if !c.state.skipMembership {
 		caps += " twitch.tv/membership"
 	}
 
-	if err := c.state.ws.WriteMessage(websocket.TextMessage, fmt.Appendf(nil, "CAP REQ :%s", caps)); err != nil {
+	if err := write(fmt.Sprintf("CAP REQ :%s", caps)); err != nil {
 		return err
 	}
 
 	// Send password if provided
-	password := c.state.opts.Identity.Password
+	password, err := c.password()
+	if err != nil {
+		return err
+	}
 	if password != "" {
 		password = Password(password)
-		if err := c.state.ws.WriteMessage(websocket.TextMessage, fmt.Appendf(nil, "PASS %s", password)); err != nil {
+		if err := write(fmt.Sprintf("PASS %s", password)); err != nil {
 			return err
 		}
-	} else if IsJustinfan(c.state.username) {
-		if err := c.state.ws.WriteMessage(websocket.TextMessage, []byte("PASS SCHMOOPIIE")); err != nil {
+	} else if IsJustinfan(username) {
+		if err := write("PASS SCHMOOPIIE"); err != nil {
 			return err
 		}
 	}
 
 	// Send NICK
-	if err := c.state.ws.WriteMessage(websocket.TextMessage, fmt.Appendf(nil, "NICK %s", c.state.username)); err != nil {
+	if err := write(fmt.Sprintf("NICK %s", username)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// handleMessages processes incoming WebSocket messages
-func (c *Client) handleMessages() {
+// handleMessages processes incoming messages until the connection
+// is closed. writer is the connection's writer.
+func (c *Client) handleMessages(conn Conn, writer *outboundWriter) {
 	for {
-		select {
-		case <-c.ctx.Done():
+		data, err := conn.ReadMessage()
+		if err != nil {
+			c.handleError(conn, err)
 			return
-		default:
-			_, data, err := c.state.ws.ReadMessage()
-			if err != nil {
-				c.handleError(err)
-				return
-			}
+		}
 
-			// Split by \r\n for multiple messages
-			messages := strings.Split(strings.TrimSpace(string(data)), "\r\n")
-			for _, msgStr := range messages {
-				if msgStr == "" {
-					continue
-				}
-				msg := ParseMessage(msgStr)
-				if msg != nil {
-					c.handleMessage(msg)
-				}
+		// Split by \r\n for multiple messages
+		messages := strings.Split(strings.TrimSpace(string(data)), "\r\n")
+		for _, msgStr := range messages {
+			if msgStr == "" {
+				continue
+			}
+			msg := ParseMessage(msgStr)
+			if msg == nil {
+				continue
 			}
+			// During a handover the old connection stops delivering once
+			// the new one took over
+			if h := c.handoff.Load(); h != nil && h.old == conn && !h.deliver(lineKey(msgStr, msg)) {
+				return
+			}
+			writer.reading.Store(true)
+			c.handleMessage(msg)
+			writer.reading.Store(false)
 		}
 	}
 }
 
-// handleError handles connection errors
-func (c *Client) handleError(err error) {
+// setConnection makes conn the current connection with a fresh writer and
+// connection context. c.mu must be held.
+func (c *Client) setConnection(conn Conn) {
+	c.state.conn = conn
+	c.state.writer = newOutboundWriter(conn, c.state.opts.Connection.WriteQueueSize, c.state.opts.Connection.WriteTimeout)
+	c.state.connCtx, c.state.connCancel = context.WithCancel(context.Background())
+}
+
+// connContext returns the context of the current connection. It is done once
+// that connection is closed, or right away without a connection.
+func (c *Client) connContext() context.Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.state.connCtx == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+	return c.state.connCtx
+}
+
+// resetConnection forgets the current connection and everything learned on
+// it. It returns the connection's writer so the caller can close it once
+// c.mu is released. c.mu must be held.
+func (c *Client) resetConnection() *outboundWriter {
+	writer := c.state.writer
+	c.state.conn = nil
+	c.state.writer = nil
+	if c.state.connCancel != nil {
+		c.state.connCancel()
+		c.state.connCtx, c.state.connCancel = nil, nil
+	}
+
 	c.state.moderators = make(map[string][]string)
 	c.state.userState = make(map[string]UserState)
+	c.state.roomStates = make(map[string]RoomState)
 	c.state.globalUserState = GlobalUserState{}
 
-	if c.state.pingLoop != nil {
-		c.state.pingLoop.Stop()
-	}
 	if c.state.pingTimeout != nil {
 		c.state.pingTimeout.Stop()
+		c.state.pingTimeout = nil
 	}
 
-	c.state.reason = "Connection closed."
-	if err != nil {
-		c.state.reason = fmt.Sprintf("Unable to connect: %v", err)
+	return writer
+}
+
+// handleError handles the loss of a connection. Connections already torn
+// down by Disconnect or replaced by a handover are ignored.
+func (c *Client) handleError(conn Conn, err error) {
+	c.mu.Lock()
+	if c.state.conn != conn {
+		c.mu.Unlock()
+		return
 	}
 
-	c.Emit("disconnected", c.state.reason)
+	writer := c.resetConnection()
 
-	// Reconnect logic
-	if c.state.reconnect && c.state.reconnections < c.state.maxReconnectAttempts && !c.state.wasCloseCalled {
-		c.state.reconnecting = true
-		c.state.reconnections++
+	// Keep a reason set before the connection was closed, e.g. an auth failure
+	reason := c.state.closeReason
+	if reason == "" {
+		reason = disconnectReason(err)
+	}
+	message := c.state.closeMessage
+	if message == "" {
+		message = "Connection closed."
+		if err != nil {
+			message = fmt.Sprintf("Unable to connect: %v", err)
+		}
+	}
+	c.state.closeReason, c.state.closeMessage = "", ""
 
-		c.state.log.Error(fmt.Sprintf("Reconnecting in %v..", c.state.reconnectTimer))
-		c.Emit("reconnect")
+	plan := c.planReconnect(reason, message)
+	c.mu.Unlock()
 
-		time.AfterFunc(c.state.reconnectTimer, func() {
-			c.state.reconnecting = false
-			c.Connect()
-		})
-	} else if c.state.reconnections >= c.state.maxReconnectAttempts {
-		c.Emit("maxreconnect")
-		c.state.log.Error("Maximum reconnection attempts reached.")
-	}
+	// Lines still queued for the writer wait for the next connection
+	writer.abandon()
 
-	c.state.ws = nil
+	c.announceState(plan.change)
+	c.Emit("disconnected", message)
+	c.announceReconnect(plan)
 }
 
-// Disconnect closes the connection to the server
+// Disconnect closes the connection to the server, or cancels a connection
+// attempt or a pending reconnect. It returns ErrNotConnected when the client
+// is idle or already closed.
 func (c *Client) Disconnect() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.state.ws == nil {
-		return errors.New("not connected to server")
+	change, err := c.transition(StateClosed, DisconnectUserClosed)
+	if err != nil {
+		c.mu.Unlock()
+		return ErrNotConnected
 	}
+	c.cancelReconnect()
+	c.endReconnect()
+	conn := c.state.conn
+	if conn == nil {
+		c.mu.Unlock()
+		c.announceState(change)
+		if c.limiter != nil {
+			c.limiter.flush()
+		}
+		return nil
+	}
+
+	writer := c.resetConnection()
+	c.mu.Unlock()
+	c.announceState(change)
 
-	c.state.wasCloseCalled = true
 	c.state.log.Info("Disconnecting from server..")
 
-	err := c.state.ws.Close()
-	c.cancel()
+	// Write what is already queued before closing the connection
+	writer.close()
+
+	err = conn.Close()
+
+	if c.limiter != nil {
+		c.limiter.flush()
+	}
 
 	c.Emit("disconnected", "Connection closed.")
 
@@ -344,23 +623,35 @@ func (c *Client) IsMod(channel, username string) bool {
 	return slices.Contains(mods, user)
 }
 
-// ReadyState returns the current connection state
-func (c *Client) ReadyState() string {
+// RoomState returns the last known state of a joined channel. The state is
+// built from the initial ROOMSTATE and every partial update after it, and is
+// forgotten when the channel is left or the connection drops.
+func (c *Client) RoomState(channel string) (RoomState, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if c.state.ws == nil {
+	state, ok := c.state.roomStates[Channel(channel)]
+	return state, ok
+}
+
+// ReadyState returns the state of the connection like a websocket's
+// readyState: CONNECTING, OPEN or CLOSED. Use State for the full lifecycle.
+func (c *Client) ReadyState() string {
+	switch c.State() {
+	case StateConnecting:
+		return "CONNECTING"
+	case StateAuthenticating, StateConnected:
+		return "OPEN"
+	default:
 		return "CLOSED"
 	}
-
-	// WebSocket states: connecting(0), open(1), closing(2), closed(3)
-	// We'll just return OPEN or CLOSED for simplicity
-	return "OPEN"
 }
 
-// isConnected checks if the WebSocket is connected
+// isConnected checks if there is an open connection
 func (c *Client) isConnected() bool {
-	return c.state.ws != nil
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state.conn != nil
 }
 
 // Type-safe event handlers
@@ -368,592 +659,459 @@ func (c *Client) isConnected() bool {
 // instead of requiring manual type assertions from ...any arguments.
 
 // OnMessage registers a type-safe handler for message events (both chat and action)
-func (c *Client) OnMessage(handler func(channel string, userstate ChatUserstate, message string, self bool)) *Client {
-	c.On("message", func(args ...any) {
-		if len(args) >= 4 {
-			channel, _ := args[0].(string)
-			userstate, _ := args[1].(ChatUserstate)
-			message, _ := args[2].(string)
-			self, _ := args[3].(bool)
-			handler(channel, userstate, message, self)
-		}
+func (c *Client) OnMessage(handler func(channel string, userstate ChatUserstate, message string, self bool)) *Subscription {
+	return On(c, func(e MessageEvent) {
+		handler(e.Channel, e.Userstate, e.Message, e.Self)
 	})
-	return c
 }
 
 // OnChat registers a type-safe handler for regular chat messages
-func (c *Client) OnChat(handler func(channel string, userstate ChatUserstate, message string, self bool)) *Client {
-	c.On("chat", func(args ...any) {
-		if len(args) >= 4 {
-			channel, _ := args[0].(string)
-			userstate, _ := args[1].(ChatUserstate)
-			message, _ := args[2].(string)
-			self, _ := args[3].(bool)
-			handler(channel, userstate, message, self)
-		}
+func (c *Client) OnChat(handler func(channel string, userstate ChatUserstate, message string, self bool)) *Subscription {
+	return On(c, func(e ChatEvent) {
+		handler(e.Channel, e.Userstate, e.Message, e.Self)
 	})
-	return c
 }
 
 // OnAction registers a type-safe handler for action messages (/me)
-func (c *Client) OnAction(handler func(channel string, userstate ChatUserstate, message string, self bool)) *Client {
-	c.On("action", func(args ...any) {
-		if len(args) >= 4 {
-			channel, _ := args[0].(string)
-			userstate, _ := args[1].(ChatUserstate)
-			message, _ := args[2].(string)
-			self, _ := args[3].(bool)
-			handler(channel, userstate, message, self)
-		}
+func (c *Client) OnAction(handler func(channel string, userstate ChatUserstate, message string, self bool)) *Subscription {
+	return On(c, func(e ActionEvent) {
+		handler(e.Channel, e.Userstate, e.Message, e.Self)
 	})
-	return c
 }
 
 // OnWhisper registers a type-safe handler for whisper messages
-func (c *Client) OnWhisper(handler func(from string, userstate ChatUserstate, message string, self bool)) *Client {
-	c.On("whisper", func(args ...any) {
-		if len(args) >= 4 {
-			from, _ := args[0].(string)
-			userstate, _ := args[1].(ChatUserstate)
-			message, _ := args[2].(string)
-			self, _ := args[3].(bool)
-			handler(from, userstate, message, self)
-		}
+func (c *Client) OnWhisper(handler func(from string, userstate ChatUserstate, message string, self bool)) *Subscription {
+	return On(c, func(e WhisperEvent) {
+		handler(e.From, e.Userstate, e.Message, e.Self)
 	})
-	return c
 }
 
 // OnCheer registers a type-safe handler for cheer (bits) events
-func (c *Client) OnCheer(handler func(channel string, userstate ChatUserstate, message string)) *Client {
-	c.On("cheer", func(args ...any) {
-		if len(args) >= 3 {
-			channel, _ := args[0].(string)
-			userstate, _ := args[1].(ChatUserstate)
-			message, _ := args[2].(string)
-			handler(channel, userstate, message)
-		}
+func (c *Client) OnCheer(handler func(channel string, userstate ChatUserstate, message string)) *Subscription {
+	return On(c, func(e CheerEvent) {
+		handler(e.Channel, e.Userstate, e.Message)
 	})
-	return c
 }
 
 // OnSubscription registers a type-safe handler for subscription events
-func (c *Client) OnSubscription(handler func(channel string, username string, methods SubMethods, message string, userstate SubUserstate)) *Client {
-	c.On("subscription", func(args ...any) {
-		if len(args) >= 5 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			methods, _ := args[2].(SubMethods)
-			message, _ := args[3].(string)
-			userstate, _ := args[4].(SubUserstate)
-			handler(channel, username, methods, message, userstate)
-		}
+func (c *Client) OnSubscription(handler func(channel string, username string, methods SubMethods, message string, userstate SubUserstate)) *Subscription {
+	return On(c, func(e SubEvent) {
+		handler(e.Channel, e.Username, e.Methods, e.Message, e.Userstate)
 	})
-	return c
 }
 
 // OnResub registers a type-safe handler for resubscription events
-func (c *Client) OnResub(handler func(channel string, username string, months int, message string, userstate SubUserstate, methods SubMethods)) *Client {
-	c.On("resub", func(args ...any) {
-		if len(args) >= 6 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			months, _ := args[2].(int)
-			message, _ := args[3].(string)
-			userstate, _ := args[4].(SubUserstate)
-			methods, _ := args[5].(SubMethods)
-			handler(channel, username, months, message, userstate, methods)
-		}
+func (c *Client) OnResub(handler func(channel string, username string, months int, message string, userstate SubUserstate, methods SubMethods)) *Subscription {
+	return On(c, func(e ResubEvent) {
+		handler(e.Channel, e.Username, e.Months, e.Message, e.Userstate, e.Methods)
 	})
-	return c
 }
 
 // OnSubGift registers a type-safe handler for gifted subscription events
-func (c *Client) OnSubGift(handler func(channel string, username string, streakMonths int, recipient string, methods SubMethods, userstate SubGiftUserstate)) *Client {
-	c.On("subgift", func(args ...any) {
-		if len(args) >= 6 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			streakMonths, _ := args[2].(int)
-			recipient, _ := args[3].(string)
-			methods, _ := args[4].(SubMethods)
-			userstate, _ := args[5].(SubGiftUserstate)
-			handler(channel, username, streakMonths, recipient, methods, userstate)
-		}
+func (c *Client) OnSubGift(handler func(channel string, username string, streakMonths int, recipient string, methods SubMethods, userstate SubGiftUserstate)) *Subscription {
+	return On(c, func(e SubGiftEvent) {
+		handler(e.Channel, e.Username, e.StreakMonths, e.Recipient, e.Methods, e.Userstate)
 	})
-	return c
 }
 
 // OnSubMysteryGift registers a type-safe handler for mystery gift subscription events
-func (c *Client) OnSubMysteryGift(handler func(channel string, username string, numbOfSubs int, methods SubMethods, userstate SubMysteryGiftUserstate)) *Client {
-	c.On("submysterygift", func(args ...any) {
-		if len(args) >= 5 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			numbOfSubs, _ := args[2].(int)
-			methods, _ := args[3].(SubMethods)
-			userstate, _ := args[4].(SubMysteryGiftUserstate)
-			handler(channel, username, numbOfSubs, methods, userstate)
-		}
+func (c *Client) OnSubMysteryGift(handler func(channel string, username string, numbOfSubs int, methods SubMethods, userstate SubMysteryGiftUserstate)) *Subscription {
+	return On(c, func(e SubMysteryGiftEvent) {
+		handler(e.Channel, e.Username, e.NumbOfSubs, e.Methods, e.Userstate)
 	})
-	return c
 }
 
 // OnAnonSubGift registers a type-safe handler for anonymous gifted subscriptions
-func (c *Client) OnAnonSubGift(handler func(channel string, streakMonths int, recipient string, methods SubMethods, userstate AnonSubGiftUserstate)) *Client {
-	c.On("anonsubgift", func(args ...any) {
-		if len(args) >= 5 {
-			channel, _ := args[0].(string)
-			streakMonths, _ := args[1].(int)
-			recipient, _ := args[2].(string)
-			methods, _ := args[3].(SubMethods)
-			userstate, _ := args[4].(AnonSubGiftUserstate)
-			handler(channel, streakMonths, recipient, methods, userstate)
-		}
+func (c *Client) OnAnonSubGift(handler func(channel string, streakMonths int, recipient string, methods SubMethods, userstate AnonSubGiftUserstate)) *Subscription {
+	return On(c, func(e AnonSubGiftEvent) {
+		handler(e.Channel, e.StreakMonths, e.Recipient, e.Methods, e.Userstate)
 	})
-	return c
 }
 
 // OnAnonSubMysteryGift registers a type-safe handler for anonymous mystery gift subscriptions
-func (c *Client) OnAnonSubMysteryGift(handler func(channel string, numbOfSubs int, methods SubMethods, userstate AnonSubMysteryGiftUserstate)) *Client {
-	c.On("anonsubmysterygift", func(args ...any) {
-		if len(args) >= 4 {
-			channel, _ := args[0].(string)
-			numbOfSubs, _ := args[1].(int)
-			methods, _ := args[2].(SubMethods)
-			userstate, _ := args[3].(AnonSubMysteryGiftUserstate)
-			handler(channel, numbOfSubs, methods, userstate)
-		}
+func (c *Client) OnAnonSubMysteryGift(handler func(channel string, numbOfSubs int, methods SubMethods, userstate AnonSubMysteryGiftUserstate)) *Subscription {
+	return On(c, func(e AnonSubMysteryGiftEvent) {
+		handler(e.Channel, e.NumbOfSubs, e.Methods, e.Userstate)
 	})
-	return c
 }
 
 // OnGiftPaidUpgrade registers a type-safe handler for gift subscription upgrades
-func (c *Client) OnGiftPaidUpgrade(handler func(channel string, username string, sender string, userstate SubGiftUpgradeUserstate)) *Client {
-	c.On("giftpaidupgrade", func(args ...any) {
-		if len(args) >= 4 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			sender, _ := args[2].(string)
-			userstate, _ := args[3].(SubGiftUpgradeUserstate)
-			handler(channel, username, sender, userstate)
-		}
+func (c *Client) OnGiftPaidUpgrade(handler func(channel string, username string, sender string, userstate SubGiftUpgradeUserstate)) *Subscription {
+	return On(c, func(e GiftPaidUpgradeEvent) {
+		handler(e.Channel, e.Username, e.Sender, e.Userstate)
 	})
-	return c
 }
 
 // OnAnonGiftPaidUpgrade registers a type-safe handler for anonymous gift subscription upgrades
-func (c *Client) OnAnonGiftPaidUpgrade(handler func(channel string, username string, userstate AnonSubGiftUpgradeUserstate)) *Client {
-	c.On("anongiftpaidupgrade", func(args ...any) {
-		if len(args) >= 3 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			userstate, _ := args[2].(AnonSubGiftUpgradeUserstate)
-			handler(channel, username, userstate)
-		}
+func (c *Client) OnAnonGiftPaidUpgrade(handler func(channel string, username string, userstate AnonSubGiftUpgradeUserstate)) *Subscription {
+	return On(c, func(e AnonGiftPaidUpgradeEvent) {
+		handler(e.Channel, e.Username, e.Userstate)
 	})
-	return c
 }
 
 // OnPrimePaidUpgrade registers a type-safe handler for Prime subscription upgrades
-func (c *Client) OnPrimePaidUpgrade(handler func(channel string, username string, methods SubMethods, userstate PrimeUpgradeUserstate)) *Client {
-	c.On("primepaidupgrade", func(args ...any) {
-		if len(args) >= 4 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			methods, _ := args[2].(SubMethods)
-			userstate, _ := args[3].(PrimeUpgradeUserstate)
-			handler(channel, username, methods, userstate)
-		}
+func (c *Client) OnPrimePaidUpgrade(handler func(channel string, username string, methods SubMethods, userstate PrimeUpgradeUserstate)) *Subscription {
+	return On(c, func(e PrimePaidUpgradeEvent) {
+		handler(e.Channel, e.Username, e.Methods, e.Userstate)
 	})
-	return c
 }
 
 // OnRaided registers a type-safe handler for raid events
-func (c *Client) OnRaided(handler func(channel string, username string, viewers int)) *Client {
-	c.On("raided", func(args ...any) {
-		if len(args) >= 3 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			viewers, _ := args[2].(int)
-			handler(channel, username, viewers)
-		}
+func (c *Client) OnRaided(handler func(channel string, username string, viewers int)) *Subscription {
+	return On(c, func(e RaidEvent) {
+		handler(e.Channel, e.Username, e.Viewers)
+	})
+}
+
+// OnUnraid registers a type-safe handler for cancelled raids
+func (c *Client) OnUnraid(handler func(channel string, userstate UnraidUserstate)) *Subscription {
+	return On(c, func(e UnraidEvent) {
+		handler(e.Channel, e.Userstate)
+	})
+}
+
+// OnRitual registers a type-safe handler for ritual events (e.g. new chatters)
+func (c *Client) OnRitual(handler func(channel string, username string, ritualName string, message string, userstate RitualUserstate)) *Subscription {
+	return On(c, func(e RitualEvent) {
+		handler(e.Channel, e.Username, e.RitualName, e.Message, e.Userstate)
+	})
+}
+
+// OnBitsBadgeTier registers a type-safe handler for bits badge tier events
+func (c *Client) OnBitsBadgeTier(handler func(channel string, username string, threshold int, message string, userstate BitsBadgeTierUserstate)) *Subscription {
+	return On(c, func(e BitsBadgeTierEvent) {
+		handler(e.Channel, e.Username, e.Threshold, e.Message, e.Userstate)
+	})
+}
+
+// OnViewerMilestone registers a type-safe handler for viewer milestone events
+func (c *Client) OnViewerMilestone(handler func(channel string, username string, category string, value int, message string, userstate ViewerMilestoneUserstate)) *Subscription {
+	return On(c, func(e ViewerMilestoneEvent) {
+		handler(e.Channel, e.Username, e.Category, e.Value, e.Message, e.Userstate)
+	})
+}
+
+// OnSharedChatNotice registers a type-safe handler for notices relayed from a shared chat session
+func (c *Client) OnSharedChatNotice(handler func(channel string, sourceMsgID string, message string, userstate SharedChatNoticeUserstate)) *Subscription {
+	return On(c, func(e SharedChatNoticeEvent) {
+		handler(e.Channel, e.SourceMsgID, e.Message, e.Userstate)
 	})
-	return c
 }
 
 // OnRedeem registers a type-safe handler for channel point redemption events
-func (c *Client) OnRedeem(handler func(channel string, username string, rewardType string, tags ChatUserstate, message string)) *Client {
-	c.On("redeem", func(args ...any) {
-		if len(args) >= 5 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			rewardType, _ := args[2].(string)
-			tags, _ := args[3].(ChatUserstate)
-			message, _ := args[4].(string)
-			handler(channel, username, rewardType, tags, message)
-		}
+func (c *Client) OnRedeem(handler func(channel string, username string, rewardType string, tags ChatUserstate, message string)) *Subscription {
+	return On(c, func(e RedeemEvent) {
+		handler(e.Channel, e.Username, e.RewardType, e.Userstate, e.Message)
 	})
-	return c
 }
 
 // OnBan registers a type-safe handler for ban events
-func (c *Client) OnBan(handler func(channel string, username string, reason string, userstate BanUserstate)) *Client {
-	c.On("ban", func(args ...any) {
-		if len(args) >= 4 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			reason, _ := args[2].(string)
-			userstate, _ := args[3].(BanUserstate)
-			handler(channel, username, reason, userstate)
-		}
+func (c *Client) OnBan(handler func(channel string, username string, reason string, userstate BanUserstate)) *Subscription {
+	return On(c, func(e BanEvent) {
+		handler(e.Channel, e.Username, e.Reason, e.Userstate)
 	})
-	return c
 }
 
 // OnTimeout registers a type-safe handler for timeout events
-func (c *Client) OnTimeout(handler func(channel string, username string, reason string, duration int, userstate TimeoutUserstate)) *Client {
-	c.On("timeout", func(args ...any) {
-		if len(args) >= 5 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			reason, _ := args[2].(string)
-			duration, _ := args[3].(int)
-			userstate, _ := args[4].(TimeoutUserstate)
-			handler(channel, username, reason, duration, userstate)
-		}
+func (c *Client) OnTimeout(handler func(channel string, username string, reason string, duration int, userstate TimeoutUserstate)) *Subscription {
+	return On(c, func(e TimeoutEvent) {
+		handler(e.Channel, e.Username, e.Reason, e.Duration, e.Userstate)
 	})
-	return c
 }
 
 // OnMessageDeleted registers a type-safe handler for deleted message events
-func (c *Client) OnMessageDeleted(handler func(channel string, username string, deletedMessage string, userstate DeleteUserstate)) *Client {
-	c.On("messagedeleted", func(args ...any) {
-		if len(args) >= 4 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			deletedMessage, _ := args[2].(string)
-			userstate, _ := args[3].(DeleteUserstate)
-			handler(channel, username, deletedMessage, userstate)
-		}
+func (c *Client) OnMessageDeleted(handler func(channel string, username string, deletedMessage string, userstate DeleteUserstate)) *Subscription {
+	return On(c, func(e MessageDeletedEvent) {
+		handler(e.Channel, e.Username, e.DeletedMessage, e.Userstate)
 	})
-	return c
 }
 
 // OnJoin registers a type-safe handler for join events
-func (c *Client) OnJoin(handler func(channel string, username string, self bool)) *Client {
-	c.On("join", func(args ...any) {
-		if len(args) >= 3 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			self, _ := args[2].(bool)
-			handler(channel, username, self)
-		}
+func (c *Client) OnJoin(handler func(channel string, username string, self bool)) *Subscription {
+	return On(c, func(e JoinEvent) {
+		handler(e.Channel, e.Username, e.Self)
 	})
-	return c
 }
 
 // OnPart registers a type-safe handler for part (leave) events
-func (c *Client) OnPart(handler func(channel string, username string, self bool)) *Client {
-	c.On("part", func(args ...any) {
-		if len(args) >= 3 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			self, _ := args[2].(bool)
-			handler(channel, username, self)
-		}
+func (c *Client) OnPart(handler func(channel string, username string, self bool)) *Subscription {
+	return On(c, func(e PartEvent) {
+		handler(e.Channel, e.Username, e.Self)
 	})
-	return c
 }
 
 // OnHosted registers a type-safe handler for hosted events
-func (c *Client) OnHosted(handler func(channel string, username string, viewers int, autohost bool)) *Client {
-	c.On("hosted", func(args ...any) {
-		if len(args) >= 4 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			viewers, _ := args[2].(int)
-			autohost, _ := args[3].(bool)
-			handler(channel, username, viewers, autohost)
-		}
+func (c *Client) OnHosted(handler func(channel string, username string, viewers int, autohost bool)) *Subscription {
+	return On(c, func(e HostedEvent) {
+		handler(e.Channel, e.Username, e.Viewers, e.Autohost)
 	})
-	return c
 }
 
 // OnHosting registers a type-safe handler for hosting events
-func (c *Client) OnHosting(handler func(channel string, target string, viewers int)) *Client {
-	c.On("hosting", func(args ...any) {
-		if len(args) >= 3 {
-			channel, _ := args[0].(string)
-			target, _ := args[1].(string)
-			viewers, _ := args[2].(int)
-			handler(channel, target, viewers)
-		}
+func (c *Client) OnHosting(handler func(channel string, target string, viewers int)) *Subscription {
+	return On(c, func(e HostingEvent) {
+		handler(e.Channel, e.Target, e.Viewers)
 	})
-	return c
 }
 
 // OnUnhost registers a type-safe handler for unhost events
-func (c *Client) OnUnhost(handler func(channel string, viewers int)) *Client {
-	c.On("unhost", func(args ...any) {
-		if len(args) >= 2 {
-			channel, _ := args[0].(string)
-			viewers, _ := args[1].(int)
-			handler(channel, viewers)
-		}
+func (c *Client) OnUnhost(handler func(channel string, viewers int)) *Subscription {
+	return On(c, func(e UnhostEvent) {
+		handler(e.Channel, e.Viewers)
 	})
-	return c
 }
 
 // OnMod registers a type-safe handler for mod events
-func (c *Client) OnMod(handler func(channel string, username string)) *Client {
-	c.On("mod", func(args ...any) {
-		if len(args) >= 2 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			handler(channel, username)
-		}
+func (c *Client) OnMod(handler func(channel string, username string)) *Subscription {
+	return On(c, func(e ModEvent) {
+		handler(e.Channel, e.Username)
 	})
-	return c
 }
 
 // OnUnmod registers a type-safe handler for unmod events
-func (c *Client) OnUnmod(handler func(channel string, username string)) *Client {
-	c.On("unmod", func(args ...any) {
-		if len(args) >= 2 {
-			channel, _ := args[0].(string)
-			username, _ := args[1].(string)
-			handler(channel, username)
-		}
+func (c *Client) OnUnmod(handler func(channel string, username string)) *Subscription {
+	return On(c, func(e UnmodEvent) {
+		handler(e.Channel, e.Username)
 	})
-	return c
 }
 
 // OnMods registers a type-safe handler for mods list events
-func (c *Client) OnMods(handler func(channel string, mods []string)) *Client {
-	c.On("mods", func(args ...any) {
-		if len(args) >= 2 {
-			channel, _ := args[0].(string)
-			mods, _ := args[1].([]string)
-			handler(channel, mods)
-		}
+func (c *Client) OnMods(handler func(channel string, mods []string)) *Subscription {
+	return On(c, func(e ModsEvent) {
+		handler(e.Channel, e.Mods)
 	})
-	return c
 }
 
 // OnVips registers a type-safe handler for VIPs list events
-func (c *Client) OnVips(handler func(channel string, vips []string)) *Client {
-	c.On("vips", func(args ...any) {
-		if len(args) >= 2 {
-			channel, _ := args[0].(string)
-			vips, _ := args[1].([]string)
-			handler(channel, vips)
-		}
+func (c *Client) OnVips(handler func(channel string, vips []string)) *Subscription {
+	return On(c, func(e VipsEvent) {
+		handler(e.Channel, e.Vips)
 	})
-	return c
 }
 
 // OnNotice registers a type-safe handler for notice events
-func (c *Client) OnNotice(handler func(channel string, msgid MsgID, message string)) *Client {
-	c.On("notice", func(args ...any) {
-		if len(args) >= 3 {
-			channel, _ := args[0].(string)
-			msgid, _ := args[1].(MsgID)
-			message, _ := args[2].(string)
-			handler(channel, msgid, message)
-		}
+func (c *Client) OnNotice(handler func(channel string, msgid MsgID, message string)) *Subscription {
+	return On(c, func(e NoticeEvent) {
+		handler(e.Channel, e.MsgID, e.Message)
+	})
+}
+
+// OnJoinProgress registers a type-safe handler for join scheduler progress events
+func (c *Client) OnJoinProgress(handler func(progress JoinProgress)) *Subscription {
+	return On(c, func(e JoinProgressEvent) {
+		handler(e.Progress)
+	})
+}
+
+// OnMessageDropped registers a type-safe handler for outbound messages dropped by the rate limiter
+func (c *Client) OnMessageDropped(handler func(channel string, message string, reason DropReason)) *Subscription {
+	return On(c, func(e MessageDroppedEvent) {
+		handler(e.Channel, e.Message, e.Reason)
+	})
+}
+
+// OnMsgRatelimit registers a type-safe handler for messages rejected for exceeding the rate limit
+func (c *Client) OnMsgRatelimit(handler func(channel string, msgid MsgID, message string)) *Subscription {
+	return On(c, func(e MsgRatelimitEvent) {
+		handler(e.Channel, e.MsgID, e.Message)
+	})
+}
+
+// OnMsgDuplicate registers a type-safe handler for messages rejected as duplicates
+func (c *Client) OnMsgDuplicate(handler func(channel string, msgid MsgID, message string)) *Subscription {
+	return On(c, func(e MsgDuplicateEvent) {
+		handler(e.Channel, e.MsgID, e.Message)
+	})
+}
+
+// OnMsgBanned registers a type-safe handler for messages rejected because the client is banned
+func (c *Client) OnMsgBanned(handler func(channel string, msgid MsgID, message string)) *Subscription {
+	return On(c, func(e MsgBannedEvent) {
+		handler(e.Channel, e.MsgID, e.Message)
+	})
+}
+
+// OnMsgSubsonly registers a type-safe handler for messages rejected by subscribers-only mode
+func (c *Client) OnMsgSubsonly(handler func(channel string, msgid MsgID, message string)) *Subscription {
+	return On(c, func(e MsgSubsonlyEvent) {
+		handler(e.Channel, e.MsgID, e.Message)
+	})
+}
+
+// OnMsgEmoteonly registers a type-safe handler for messages rejected by emote-only mode
+func (c *Client) OnMsgEmoteonly(handler func(channel string, msgid MsgID, message string)) *Subscription {
+	return On(c, func(e MsgEmoteonlyEvent) {
+		handler(e.Channel, e.MsgID, e.Message)
+	})
+}
+
+// OnChannelSuspended registers a type-safe handler for notices that a channel is suspended
+func (c *Client) OnChannelSuspended(handler func(channel string, msgid MsgID, message string)) *Subscription {
+	return On(c, func(e ChannelSuspendedEvent) {
+		handler(e.Channel, e.MsgID, e.Message)
+	})
+}
+
+// OnWhisperLimit registers a type-safe handler for whispers rejected by the per-second or per-minute limit
+func (c *Client) OnWhisperLimit(handler func(channel string, msgid MsgID, message string)) *Subscription {
+	return On(c, func(e WhisperLimitEvent) {
+		handler(e.Channel, e.MsgID, e.Message)
+	})
+}
+
+// OnAutomod registers a type-safe handler for messages held or rejected by AutoMod
+func (c *Client) OnAutomod(handler func(channel string, msgid MsgID, message string)) *Subscription {
+	return On(c, func(e AutomodEvent) {
+		handler(e.Channel, e.MsgID, e.Message)
+	})
+}
+
+// OnAuthFailed registers a type-safe handler for rejected logins
+func (c *Client) OnAuthFailed(handler func(reason string)) *Subscription {
+	return On(c, func(e AuthFailedEvent) {
+		handler(e.Reason)
 	})
-	return c
 }
 
 // OnRoomstate registers a type-safe handler for roomstate events
-func (c *Client) OnRoomstate(handler func(channel string, state RoomState)) *Client {
-	c.On("roomstate", func(args ...any) {
-		if len(args) >= 2 {
-			channel, _ := args[0].(string)
-			state, _ := args[1].(RoomState)
-			handler(channel, state)
-		}
+func (c *Client) OnRoomstate(handler func(channel string, state RoomState)) *Subscription {
+	return On(c, func(e RoomstateEvent) {
+		handler(e.Channel, e.State)
 	})
-	return c
 }
 
 // OnClearchat registers a type-safe handler for clearchat events
-func (c *Client) OnClearchat(handler func(channel string)) *Client {
-	c.On("clearchat", func(args ...any) {
-		if len(args) >= 1 {
-			channel, _ := args[0].(string)
-			handler(channel)
-		}
+func (c *Client) OnClearchat(handler func(channel string)) *Subscription {
+	return On(c, func(e ClearchatEvent) {
+		handler(e.Channel)
 	})
-	return c
 }
 
 // OnEmoteonly registers a type-safe handler for emote-only mode events
-func (c *Client) OnEmoteonly(handler func(channel string, enabled bool)) *Client {
-	c.On("emoteonly", func(args ...any) {
-		if len(args) >= 2 {
-			channel, _ := args[0].(string)
-			enabled, _ := args[1].(bool)
-			handler(channel, enabled)
-		}
+func (c *Client) OnEmoteonly(handler func(channel string, enabled bool)) *Subscription {
+	return On(c, func(e EmoteonlyEvent) {
+		handler(e.Channel, e.Enabled)
 	})
-	return c
 }
 
 // OnFollowersonly registers a type-safe handler for followers-only mode events
-func (c *Client) OnFollowersonly(handler func(channel string, enabled bool, length int)) *Client {
-	c.On("followersonly", func(args ...any) {
-		if len(args) >= 3 {
-			channel, _ := args[0].(string)
-			enabled, _ := args[1].(bool)
-			length, _ := args[2].(int)
-			handler(channel, enabled, length)
-		}
+func (c *Client) OnFollowersonly(handler func(channel string, enabled bool, length int)) *Subscription {
+	return On(c, func(e FollowersonlyEvent) {
+		handler(e.Channel, e.Enabled, e.Length)
 	})
-	return c
 }
 
 // OnSlowmode registers a type-safe handler for slow mode events
-func (c *Client) OnSlowmode(handler func(channel string, enabled bool, length int)) *Client {
-	c.On("slowmode", func(args ...any) {
-		if len(args) >= 3 {
-			channel, _ := args[0].(string)
-			enabled, _ := args[1].(bool)
-			length, _ := args[2].(int)
-			handler(channel, enabled, length)
-		}
+func (c *Client) OnSlowmode(handler func(channel string, enabled bool, length int)) *Subscription {
+	return On(c, func(e SlowmodeEvent) {
+		handler(e.Channel, e.Enabled, e.Length)
 	})
-	return c
 }
 
 // OnSubscribers registers a type-safe handler for subscribers-only mode events
-func (c *Client) OnSubscribers(handler func(channel string, enabled bool)) *Client {
-	c.On("subscribers", func(args ...any) {
-		if len(args) >= 2 {
-			channel, _ := args[0].(string)
-			enabled, _ := args[1].(bool)
-			handler(channel, enabled)
-		}
+func (c *Client) OnSubscribers(handler func(channel string, enabled bool)) *Subscription {
+	return On(c, func(e SubscribersEvent) {
+		handler(e.Channel, e.Enabled)
 	})
-	return c
 }
 
 // OnR9kbeta registers a type-safe handler for R9K mode events
-func (c *Client) OnR9kbeta(handler func(channel string, enabled bool)) *Client {
-	c.On("r9kbeta", func(args ...any) {
-		if len(args) >= 2 {
-			channel, _ := args[0].(string)
-			enabled, _ := args[1].(bool)
-			handler(channel, enabled)
-		}
+func (c *Client) OnR9kbeta(handler func(channel string, enabled bool)) *Subscription {
+	return On(c, func(e R9kbetaEvent) {
+		handler(e.Channel, e.Enabled)
 	})
-	return c
 }
 
 // OnConnected registers a type-safe handler for connected events
-func (c *Client) OnConnected(handler func(address string, port int)) *Client {
-	c.On("connected", func(args ...any) {
-		if len(args) >= 2 {
-			address, _ := args[0].(string)
-			port, _ := args[1].(int)
-			handler(address, port)
-		}
+func (c *Client) OnConnected(handler func(address string, port int)) *Subscription {
+	return On(c, func(e ConnectedEvent) {
+		handler(e.Address, e.Port)
 	})
-	return c
 }
 
 // OnConnecting registers a type-safe handler for connecting events
-func (c *Client) OnConnecting(handler func(address string, port int)) *Client {
-	c.On("connecting", func(args ...any) {
-		if len(args) >= 2 {
-			address, _ := args[0].(string)
-			port, _ := args[1].(int)
-			handler(address, port)
-		}
+func (c *Client) OnConnecting(handler func(address string, port int)) *Subscription {
+	return On(c, func(e ConnectingEvent) {
+		handler(e.Address, e.Port)
 	})
-	return c
 }
 
 // OnDisconnected registers a type-safe handler for disconnected events
-func (c *Client) OnDisconnected(handler func(reason string)) *Client {
-	c.On("disconnected", func(args ...any) {
-		if len(args) >= 1 {
-			reason, _ := args[0].(string)
-			handler(reason)
-		}
+func (c *Client) OnDisconnected(handler func(reason string)) *Subscription {
+	return On(c, func(e DisconnectedEvent) {
+		handler(e.Reason)
 	})
-	return c
 }
 
 // OnLogon registers a type-safe handler for logon events
-func (c *Client) OnLogon(handler func()) *Client {
-	c.On("logon", func(args ...any) {
+func (c *Client) OnLogon(handler func()) *Subscription {
+	return On(c, func(LogonEvent) {
 		handler()
 	})
-	return c
 }
 
 // OnReconnect registers a type-safe handler for reconnect events
-func (c *Client) OnReconnect(handler func()) *Client {
-	c.On("reconnect", func(args ...any) {
+func (c *Client) OnReconnect(handler func()) *Subscription {
+	return On(c, func(ReconnectEvent) {
 		handler()
 	})
-	return c
 }
 
 // OnPing registers a type-safe handler for ping events
-func (c *Client) OnPing(handler func()) *Client {
-	c.On("ping", func(args ...any) {
+func (c *Client) OnPing(handler func()) *Subscription {
+	return On(c, func(PingEvent) {
 		handler()
 	})
-	return c
 }
 
 // OnPong registers a type-safe handler for pong events
-func (c *Client) OnPong(handler func(latency float64)) *Client {
-	c.On("pong", func(args ...any) {
-		if len(args) >= 1 {
-			latency, _ := args[0].(float64)
-			handler(latency)
-		}
+func (c *Client) OnPong(handler func(latency float64)) *Subscription {
+	return On(c, func(e PongEvent) {
+		handler(e.Latency)
+	})
+}
+
+// OnDegraded registers a type-safe handler for events when the keepalive
+// latency reaches Health.DegradedLatency
+func (c *Client) OnDegraded(handler func(health HealthSnapshot)) *Subscription {
+	return On(c, func(e DegradedEvent) {
+		handler(e.Health)
+	})
+}
+
+// OnRecovered registers a type-safe handler for events when the keepalive
+// latency drops below Health.DegradedLatency again
+func (c *Client) OnRecovered(handler func(health HealthSnapshot)) *Subscription {
+	return On(c, func(e RecoveredEvent) {
+		handler(e.Health)
+	})
+}
+
+// OnStateChange registers a type-safe handler for connection state
+// transitions. reason is set when a lost connection caused the transition.
+func (c *Client) OnStateChange(handler func(old, new ConnState, reason DisconnectReason)) *Subscription {
+	return On(c, func(e StateChangeEvent) {
+		handler(e.Old, e.New, e.Reason)
 	})
-	return c
 }
 
 // OnEmotesets registers a type-safe handler for emoteset events
-func (c *Client) OnEmotesets(handler func(sets string, obj map[string]any)) *Client {
-	c.On("emotesets", func(args ...any) {
-		if len(args) >= 2 {
-			sets, _ := args[0].(string)
-			obj, _ := args[1].(map[string]any)
-			handler(sets, obj)
-		}
+func (c *Client) OnEmotesets(handler func(sets string, obj map[string]any)) *Subscription {
+	return On(c, func(e EmotesetsEvent) {
+		handler(e.Sets, e.Obj)
 	})
-	return c
 }
 
 // OnRawMessage registers a type-safe handler for raw IRC message events
-func (c *Client) OnRawMessage(handler func(message *IRCMessage)) *Client {
-	c.On("raw_message", func(args ...any) {
-		if len(args) >= 1 {
-			message, _ := args[0].(*IRCMessage)
-			handler(message)
-		}
+func (c *Client) OnRawMessage(handler func(message *IRCMessage)) *Subscription {
+	return On(c, func(e RawMessageEvent) {
+		handler(e.Message)
 	})
-	return c
 }
 
 // OnAnnouncement registers a type-safe handler for announcement events
-func (c *Client) OnAnnouncement(handler func(channel string, userstate ChatUserstate, message string, self bool, color string)) *Client {
-	c.On("announcement", func(args ...any) {
-		if len(args) >= 5 {
-			channel, _ := args[0].(string)
-			userstate, _ := args[1].(ChatUserstate)
-			message, _ := args[2].(string)
-			self, _ := args[3].(bool)
-			color, _ := args[4].(string)
-			handler(channel, userstate, message, self, color)
-		}
+func (c *Client) OnAnnouncement(handler func(channel string, userstate ChatUserstate, message string, self bool, color string)) *Subscription {
+	return On(c, func(e AnnouncementEvent) {
+		handler(e.Channel, e.Userstate, e.Message, e.Self, e.Color)
 	})
-	return c
 }
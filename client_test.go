@@ -0,0 +1,330 @@
+package tmigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ktnuity/tmigo/tmigotest"
+)
+
+// testOptions returns client options that connect to a tmigotest server
+func testOptions(t *testing.T, channels ...string) (*tmigotest.Server, *ClientOptions) {
+	t.Helper()
+
+	server := tmigotest.NewServer()
+	t.Cleanup(server.Close)
+
+	return server, &ClientOptions{
+		Connection: &Connection{
+			Server:            server.Host(),
+			Port:              server.Port(),
+			ReconnectInterval: 10 * time.Millisecond,
+		},
+		Identity: &Identity{Username: "bot", Password: "oauth:token"},
+		Channels: channels,
+	}
+}
+
+// waitFor fails the test unless an event is emitted count times before the
+// timeout. It must be registered before the event can fire.
+func waitFor(t *testing.T, client *Client, event string, count int) func() {
+	t.Helper()
+
+	fired := make(chan struct{}, count)
+	client.On(event, func(args ...any) {
+		select {
+		case fired <- struct{}{}:
+		default:
+		}
+	})
+
+	return func() {
+		t.Helper()
+		for i := range count {
+			select {
+			case <-fired:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s fired %d times, want %d", event, i, count)
+			}
+		}
+	}
+}
+
+func TestClient_ConnectAndJoin(t *testing.T) {
+	_, opts := testOptions(t, "#a", "#b")
+	client := NewClient(opts)
+
+	wait := waitFor(t, client, "roomstate", 2)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Disconnect()
+	wait()
+
+	channels := client.GetChannels()
+	slices.Sort(channels)
+	if !slices.Equal(channels, []string{"#a", "#b"}) {
+		t.Errorf("GetChannels() = %v, want [#a #b]", channels)
+	}
+	if _, ok := client.RoomState("a"); !ok {
+		t.Error("RoomState(a) ok = false after joining")
+	}
+	if client.ReadyState() != "OPEN" {
+		t.Errorf("ReadyState() = %q, want OPEN", client.ReadyState())
+	}
+}
+
+func TestClient_ConcurrentUse(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	opts.RateLimit = &RateLimit{Disabled: true}
+	client := NewClient(opts)
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Disconnect()
+	wait()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				client.Say("a", fmt.Sprintf("message %d-%d", i, j))
+				client.IsMod("a", "bot")
+				client.GetChannels()
+				client.RoomState("a")
+				client.GetUsername()
+				client.ReadyState()
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 20 {
+			server.Send(
+				fmt.Sprintf("@badges=;color=;display-name=viewer;mod=0;user-type= :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #a :hello %d", i),
+				":tmi.twitch.tv MODE #a +o viewer",
+				fmt.Sprintf("@room-id=12345;slow=%d :tmi.twitch.tv ROOMSTATE #a", i+2),
+			)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err := client.PingContext(ctx); err != nil {
+			t.Errorf("PingContext() error = %v", err)
+		}
+	}()
+
+	wg.Wait()
+}
+
+func TestClient_Disconnect(t *testing.T) {
+	_, opts := testOptions(t, "#a")
+	client := NewClient(opts)
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	wait()
+
+	var mu sync.Mutex
+	disconnects := 0
+	client.On("disconnected", func(args ...any) {
+		mu.Lock()
+		disconnects++
+		mu.Unlock()
+	})
+	reconnected := false
+	client.On("reconnect", func(args ...any) {
+		mu.Lock()
+		reconnected = true
+		mu.Unlock()
+	})
+
+	if err := client.Disconnect(); err != nil {
+		t.Fatalf("Disconnect() error = %v", err)
+	}
+	if err := client.Disconnect(); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("second Disconnect() error = %v, want ErrNotConnected", err)
+	}
+
+	// Give the read loop time to notice the closed connection
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if disconnects != 1 {
+		t.Errorf("disconnected fired %d times, want 1", disconnects)
+	}
+	if reconnected {
+		t.Error("client reconnected after Disconnect")
+	}
+	if client.ReadyState() != "CLOSED" {
+		t.Errorf("ReadyState() = %q, want CLOSED", client.ReadyState())
+	}
+}
+
+func TestClient_ReconnectRejoins(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	client := NewClient(opts)
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Disconnect()
+	wait()
+
+	waitReconnect := waitFor(t, client, "connected", 1)
+	waitRejoin := waitFor(t, client, "roomstate", 1)
+	server.Drop()
+	waitReconnect()
+	waitRejoin()
+
+	if channels := client.GetChannels(); !slices.Equal(channels, []string{"#a"}) {
+		t.Errorf("GetChannels() = %v after reconnect, want [#a]", channels)
+	}
+}
+
+func TestClient_ConnectContext(t *testing.T) {
+	for _, until := range []ConnectReady{ReadyLoggedOn, ReadyChannelsJoined} {
+		t.Run(string(until), func(t *testing.T) {
+			_, opts := testOptions(t, "#a", "#b")
+			client := NewClient(opts)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := client.ConnectContext(ctx, until); err != nil {
+				t.Fatalf("ConnectContext() error = %v", err)
+			}
+			defer client.Disconnect()
+
+			if client.ReadyState() != "OPEN" {
+				t.Errorf("ReadyState() = %q, want OPEN", client.ReadyState())
+			}
+			if until == ReadyChannelsJoined {
+				channels := client.GetChannels()
+				slices.Sort(channels)
+				if !slices.Equal(channels, []string{"#a", "#b"}) {
+					t.Errorf("GetChannels() = %v, want [#a #b]", channels)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_ConnectContextAuthFailed(t *testing.T) {
+	server, opts := testOptions(t)
+	server.RejectLogin = true
+	client := NewClient(opts)
+
+	err := client.ConnectContext(context.Background(), ReadyLoggedOn)
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("ConnectContext() error = %v, want ErrAuthFailed", err)
+	}
+	var connErr *ConnectError
+	if !errors.As(err, &connErr) || connErr.Reason != "Login authentication failed" {
+		t.Errorf("ConnectContext() error = %#v, want ConnectError with the NOTICE", err)
+	}
+
+	// Give a reconnect time to happen
+	time.Sleep(50 * time.Millisecond)
+	if n := server.ConnectionCount(); n != 1 {
+		t.Errorf("server saw %d connections, want 1", n)
+	}
+	if client.ReadyState() != "CLOSED" {
+		t.Errorf("ReadyState() = %q, want CLOSED", client.ReadyState())
+	}
+}
+
+func TestClient_ConnectContextTimeout(t *testing.T) {
+	// Accept connections but never finish the login
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	client := NewClient(&ClientOptions{
+		Connection: &Connection{Transport: TCPTransport{}, Server: addr.IP.String(), Port: addr.Port},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = client.ConnectContext(ctx, ReadyLoggedOn)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ConnectContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if client.ReadyState() != "CLOSED" {
+		t.Errorf("ReadyState() = %q, want CLOSED", client.ReadyState())
+	}
+}
+
+func TestClient_DisconnectCancelsReconnect(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	opts.Connection.ReconnectInterval = 50 * time.Millisecond
+	client := NewClient(opts)
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	wait()
+
+	waitReconnect := waitFor(t, client, "reconnect", 1)
+	server.Drop()
+	waitReconnect()
+
+	if err := client.Disconnect(); err != nil {
+		t.Errorf("Disconnect() error = %v while a reconnect was pending", err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if n := server.ConnectionCount(); n != 1 {
+		t.Errorf("server saw %d connections after Disconnect, want 1", n)
+	}
+}
+
+func TestClient_TypedHandlerUnsubscribe(t *testing.T) {
+	client := NewClient(nil)
+	var plugin, core int
+
+	sub := client.OnMessage(func(channel string, userstate ChatUserstate, message string, self bool) { plugin++ })
+	client.OnMessage(func(channel string, userstate ChatUserstate, message string, self bool) { core++ })
+
+	client.Emit("message", "#a", ChatUserstate{}, "hello", false)
+	sub.Unsubscribe()
+	client.Emit("message", "#a", ChatUserstate{}, "hello", false)
+
+	if plugin != 1 || core != 2 {
+		t.Errorf("plugin handler called %d times, core %d, want 1 and 2", plugin, core)
+	}
+}
@@ -1,12 +1,20 @@
 package tmigo
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 )
 
+// Every command below comes in two flavours. The plain variant sends the
+// command and returns as soon as it has been written. The Context variant
+// additionally blocks until Twitch confirms the command, rejects it with a
+// NOTICE (returned as a *CommandError), the promise delay elapses
+// (ErrNoResponse) or ctx is done.
+
 // Say sends a message to a channel
 func (c *Client) Say(channel, message string, tags ...map[string]string) error {
 	channel = Channel(channel)
@@ -29,349 +37,485 @@ func (c *Client) Action(channel, message string, tags ...map[string]string) erro
 	return c.sendMessage(channel, message, tags...)
 }
 
-// Join joins a channel
+// Join queues a channel in the join scheduler. The JOIN is sent once the
+// join budget allows it, or once the client is back while reconnecting;
+// progress is reported by the joinprogress event.
 func (c *Client) Join(channel string) error {
-	channel = Channel(channel)
+	if !c.canJoin() {
+		return ErrNotConnected
+	}
 
-	return c.sendCommandWithResponse(
-		"",
-		fmt.Sprintf("JOIN %s", channel),
-		"_promiseJoin",
-		c.getPromiseDelay(),
-		nil,
-	)
+	c.joins.add(Channel(channel))
+	return nil
+}
+
+// JoinContext joins a channel and waits until the join is confirmed. The
+// wait includes the time spent in the join scheduler.
+func (c *Client) JoinContext(ctx context.Context, channel string) error {
+	if !c.canJoin() {
+		return ErrNotConnected
+	}
+
+	select {
+	case err := <-c.joins.add(Channel(channel)):
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// JoinMultiple joins 1 or more channels
+// JoinMultiple queues 1 or more channels in the join scheduler
 func (c *Client) JoinMultiple(channels []string) error {
 	if len(channels) == 0 {
 		return nil
 	}
 
+	if !c.canJoin() {
+		return ErrNotConnected
+	}
+
+	for _, channel := range ChannelAll(channels) {
+		c.joins.add(channel)
+	}
+	return nil
+}
+
+// JoinMultipleContext joins 1 or more channels and waits until every join is
+// confirmed. Failed joins are combined into the returned error.
+func (c *Client) JoinMultipleContext(ctx context.Context, channels []string) error {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	if !c.canJoin() {
+		return ErrNotConnected
+	}
+
 	channels = ChannelAll(channels)
+	results := make([]<-chan error, len(channels))
+	for i, channel := range channels {
+		results[i] = c.joins.add(channel)
+	}
 
-	return c.sendCommandWithResponse(
-		"",
-		fmt.Sprintf("JOIN %s", strings.Join(channels, ",")),
-		"_promiseJoin",
-		c.getPromiseDelay(),
-		nil,
-	)
+	errs := []error{}
+	for i, result := range results {
+		var err error
+		select {
+		case err = <-result:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channels[i], err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sendJoin sends the JOIN for a channel taken from the join scheduler and
+// reports the outcome back to it
+func (c *Client) sendJoin(channel string) {
+	command := fmt.Sprintf("JOIN %s", channel)
+	ctx := c.connContext()
+
+	w := c.waiters.add("_promiseJoin", matchChannel(channel))
+	if err := c.sendCommandRaw(command); err != nil {
+		c.waiters.remove("_promiseJoin", w)
+		if errors.Is(err, errUnsent) {
+			// Requeued once the client let go of the lost connection
+			go func() {
+				<-ctx.Done()
+				c.finishJoin(ctx, channel, err)
+			}()
+			return
+		}
+		c.finishJoin(ctx, channel, err)
+		return
+	}
+
+	go func() {
+		_, msgid, err := c.awaitResponse(ctx, "_promiseJoin", w, c.getPromiseDelay())
+		if err == nil && msgid != "" {
+			err = &CommandError{Command: command, Channel: channel, MsgID: msgid}
+		}
+		c.finishJoin(ctx, channel, err)
+	}()
+}
+
+// finishJoin reports a JOIN's outcome to the scheduler. A JOIN lost with its
+// connection is sent again after a reconnect instead of failing.
+func (c *Client) finishJoin(ctx context.Context, channel string, err error) {
+	if err != nil && ctx.Err() != nil && c.isReconnecting() {
+		c.joins.requeue(channel)
+		return
+	}
+	c.joins.finish(channel, err)
+}
+
+// canJoin reports whether joins can be queued: while connected, or while a
+// reconnect is in progress
+func (c *Client) canJoin() bool {
+	return c.isConnected() || c.isReconnecting()
+}
+
+// forgetChannel stops rejoining a channel on reconnect
+func (c *Client) forgetChannel(channel string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.channels = slices.DeleteFunc(c.state.channels, func(ch string) bool { return ch == channel })
+	c.state.opts.Channels = slices.DeleteFunc(c.state.opts.Channels, func(ch string) bool { return ch == channel })
 }
 
-// Part leaves a channel
+// Part leaves a channel. A channel still waiting in the join scheduler is
+// removed from it instead, and while reconnecting the channel is simply not
+// rejoined.
 func (c *Client) Part(channel string) error {
 	channel = Channel(channel)
+	if c.joins.cancel(channel) && !slices.Contains(c.GetChannels(), channel) {
+		return nil
+	}
+	if c.isReconnecting() {
+		c.forgetChannel(channel)
+		return nil
+	}
+	return c.dispatchCommand("", fmt.Sprintf("PART %s", channel))
+}
+
+// PartContext leaves a channel and waits until the part is confirmed. A
+// channel still waiting in the join scheduler or not yet rejoined after a
+// reconnect is dropped without waiting.
+func (c *Client) PartContext(ctx context.Context, channel string) error {
+	channel = Channel(channel)
+	if c.joins.cancel(channel) && !slices.Contains(c.GetChannels(), channel) {
+		return nil
+	}
+	if c.isReconnecting() {
+		c.forgetChannel(channel)
+		return nil
+	}
 
-	return c.sendCommandWithResponse(
+	_, err := c.sendCommandWithResponse(
+		ctx,
 		"",
 		fmt.Sprintf("PART %s", channel),
 		"_promisePart",
 		c.getPromiseDelay(),
-		nil,
+		matchChannel(channel),
 	)
+	return err
 }
 
 // Ban bans a user from a channel
 func (c *Client) Ban(channel, username, reason string) error {
-	username = Username(username)
-	if reason == "" {
-		reason = ""
-	}
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/ban %s %s", username, reason),
-		"_promiseBan",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, banCommand(username, reason))
+}
+
+// BanContext bans a user from a channel and waits for the result
+func (c *Client) BanContext(ctx context.Context, channel, username, reason string) error {
+	return c.channelCommand(ctx, channel, banCommand(username, reason), "_promiseBan")
+}
+
+func banCommand(username, reason string) string {
+	return fmt.Sprintf("/ban %s %s", Username(username), reason)
 }
 
 // Timeout times out a user in a channel
 func (c *Client) Timeout(channel, username string, seconds int, reason string) error {
-	username = Username(username)
+	return c.dispatchCommand(channel, timeoutCommand(username, seconds, reason))
+}
+
+// TimeoutContext times out a user in a channel and waits for the result
+func (c *Client) TimeoutContext(ctx context.Context, channel, username string, seconds int, reason string) error {
+	return c.channelCommand(ctx, channel, timeoutCommand(username, seconds, reason), "_promiseTimeout")
+}
+
+func timeoutCommand(username string, seconds int, reason string) string {
 	if seconds == 0 {
 		seconds = 300
 	}
-	if reason == "" {
-		reason = ""
-	}
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/timeout %s %d %s", username, seconds, reason),
-		"_promiseTimeout",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return fmt.Sprintf("/timeout %s %d %s", Username(username), seconds, reason)
 }
 
 // Unban unbans a user from a channel
 func (c *Client) Unban(channel, username string) error {
-	username = Username(username)
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/unban %s", username),
-		"_promiseUnban",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, fmt.Sprintf("/unban %s", Username(username)))
+}
+
+// UnbanContext unbans a user from a channel and waits for the result
+func (c *Client) UnbanContext(ctx context.Context, channel, username string) error {
+	return c.channelCommand(ctx, channel, fmt.Sprintf("/unban %s", Username(username)), "_promiseUnban")
 }
 
 // Clear clears chat in a channel
 func (c *Client) Clear(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/clear",
-		"_promiseClear",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/clear")
+}
+
+// ClearContext clears chat in a channel and waits for the result
+func (c *Client) ClearContext(ctx context.Context, channel string) error {
+	return c.channelCommand(ctx, channel, "/clear", "_promiseClear")
 }
 
 // Color changes the client's username color
 func (c *Client) Color(newColor string) error {
-	return c.sendCommandWithResponse(
+	return c.dispatchCommand(c.state.globalDefaultChannel, fmt.Sprintf("/color %s", newColor))
+}
+
+// ColorContext changes the client's username color and waits for the result
+func (c *Client) ColorContext(ctx context.Context, newColor string) error {
+	_, err := c.sendCommandWithResponse(
+		ctx,
 		c.state.globalDefaultChannel,
 		fmt.Sprintf("/color %s", newColor),
 		"_promiseColor",
 		c.getPromiseDelay(),
 		nil,
 	)
+	return err
 }
 
 // Commercial runs a commercial on a channel
 func (c *Client) Commercial(channel string, seconds int) error {
+	return c.dispatchCommand(channel, commercialCommand(seconds))
+}
+
+// CommercialContext runs a commercial on a channel and waits for the result
+func (c *Client) CommercialContext(ctx context.Context, channel string, seconds int) error {
+	return c.channelCommand(ctx, channel, commercialCommand(seconds), "_promiseCommercial")
+}
+
+func commercialCommand(seconds int) string {
 	if seconds == 0 {
 		seconds = 30
 	}
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/commercial %d", seconds),
-		"_promiseCommercial",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return fmt.Sprintf("/commercial %d", seconds)
 }
 
 // DeleteMessage deletes a specific message
 func (c *Client) DeleteMessage(channel, messageUUID string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/delete %s", messageUUID),
-		"_promiseDeletemessage",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, fmt.Sprintf("/delete %s", messageUUID))
+}
+
+// DeleteMessageContext deletes a specific message and waits for the result
+func (c *Client) DeleteMessageContext(ctx context.Context, channel, messageUUID string) error {
+	return c.channelCommand(ctx, channel, fmt.Sprintf("/delete %s", messageUUID), "_promiseDeletemessage")
 }
 
 // EmoteOnly enables emote-only mode in a channel
 func (c *Client) EmoteOnly(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/emoteonly",
-		"_promiseEmoteonly",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/emoteonly")
+}
+
+// EmoteOnlyContext enables emote-only mode in a channel and waits for the result
+func (c *Client) EmoteOnlyContext(ctx context.Context, channel string) error {
+	return c.channelCommand(ctx, channel, "/emoteonly", "_promiseEmoteonly")
 }
 
 // EmoteOnlyOff disables emote-only mode in a channel
 func (c *Client) EmoteOnlyOff(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/emoteonlyoff",
-		"_promiseEmoteonlyoff",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/emoteonlyoff")
+}
+
+// EmoteOnlyOffContext disables emote-only mode in a channel and waits for the result
+func (c *Client) EmoteOnlyOffContext(ctx context.Context, channel string) error {
+	return c.channelCommand(ctx, channel, "/emoteonlyoff", "_promiseEmoteonlyoff")
 }
 
 // FollowersOnly enables followers-only mode in a channel
 func (c *Client) FollowersOnly(channel string, minutes int) error {
+	return c.dispatchCommand(channel, followersCommand(minutes))
+}
+
+// FollowersOnlyContext enables followers-only mode in a channel and waits for the result
+func (c *Client) FollowersOnlyContext(ctx context.Context, channel string, minutes int) error {
+	return c.channelCommand(ctx, channel, followersCommand(minutes), "_promiseFollowers")
+}
+
+func followersCommand(minutes int) string {
 	if minutes == 0 {
 		minutes = 30
 	}
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/followers %d", minutes),
-		"_promiseFollowers",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return fmt.Sprintf("/followers %d", minutes)
 }
 
 // FollowersOnlyOff disables followers-only mode in a channel
 func (c *Client) FollowersOnlyOff(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/followersoff",
-		"_promiseFollowersoff",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/followersoff")
+}
+
+// FollowersOnlyOffContext disables followers-only mode in a channel and waits for the result
+func (c *Client) FollowersOnlyOffContext(ctx context.Context, channel string) error {
+	return c.channelCommand(ctx, channel, "/followersoff", "_promiseFollowersoff")
 }
 
 // Host hosts another channel
 func (c *Client) Host(channel, target string) error {
-	target = Username(target)
-	return c.sendCommandWithResponse(
+	return c.dispatchCommand(channel, fmt.Sprintf("/host %s", Username(target)))
+}
+
+// HostContext hosts another channel and returns the number of hosts remaining
+func (c *Client) HostContext(ctx context.Context, channel, target string) (int, error) {
+	channel = Channel(channel)
+
+	args, err := c.sendCommandWithResponse(
+		ctx,
 		channel,
-		fmt.Sprintf("/host %s", target),
+		fmt.Sprintf("/host %s", Username(target)),
 		"_promiseHost",
 		2*time.Second,
-		nil,
+		matchChannel(channel),
 	)
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := 0
+	if len(args) > 2 {
+		remaining, _ = args[2].(int)
+	}
+	return remaining, nil
 }
 
 // Unhost stops hosting
 func (c *Client) Unhost(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/unhost",
-		"_promiseUnhost",
-		2*time.Second,
-		nil,
-	)
+	return c.dispatchCommand(channel, "/unhost")
+}
+
+// UnhostContext stops hosting and waits for the result
+func (c *Client) UnhostContext(ctx context.Context, channel string) error {
+	channel = Channel(channel)
+
+	_, err := c.sendCommandWithResponse(ctx, channel, "/unhost", "_promiseUnhost", 2*time.Second, matchChannel(channel))
+	return err
 }
 
 // Mod gives mod status to a user
 func (c *Client) Mod(channel, username string) error {
-	username = Username(username)
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/mod %s", username),
-		"_promiseMod",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, fmt.Sprintf("/mod %s", Username(username)))
+}
+
+// ModContext gives mod status to a user and waits for the result
+func (c *Client) ModContext(ctx context.Context, channel, username string) error {
+	return c.channelCommand(ctx, channel, fmt.Sprintf("/mod %s", Username(username)), "_promiseMod")
 }
 
 // Unmod removes mod status from a user
 func (c *Client) Unmod(channel, username string) error {
-	username = Username(username)
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/unmod %s", username),
-		"_promiseUnmod",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, fmt.Sprintf("/unmod %s", Username(username)))
+}
+
+// UnmodContext removes mod status from a user and waits for the result
+func (c *Client) UnmodContext(ctx context.Context, channel, username string) error {
+	return c.channelCommand(ctx, channel, fmt.Sprintf("/unmod %s", Username(username)), "_promiseUnmod")
 }
 
 // Mods gets the list of moderators in a channel
 func (c *Client) Mods(channel string) error {
-	channel = Channel(channel)
-	return c.sendCommandWithResponse(
-		channel,
-		"/mods",
-		"_promiseMods",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(Channel(channel), "/mods")
+}
+
+// ModsContext gets the list of moderators in a channel
+func (c *Client) ModsContext(ctx context.Context, channel string) ([]string, error) {
+	return c.listCommand(ctx, channel, "/mods", "_promiseMods")
 }
 
 // VIP gives VIP status to a user
 func (c *Client) VIP(channel, username string) error {
-	username = Username(username)
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/vip %s", username),
-		"_promiseVip",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, fmt.Sprintf("/vip %s", Username(username)))
+}
+
+// VIPContext gives VIP status to a user and waits for the result
+func (c *Client) VIPContext(ctx context.Context, channel, username string) error {
+	return c.channelCommand(ctx, channel, fmt.Sprintf("/vip %s", Username(username)), "_promiseVip")
 }
 
 // Unvip removes VIP status from a user
 func (c *Client) Unvip(channel, username string) error {
-	username = Username(username)
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/unvip %s", username),
-		"_promiseUnvip",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, fmt.Sprintf("/unvip %s", Username(username)))
+}
+
+// UnvipContext removes VIP status from a user and waits for the result
+func (c *Client) UnvipContext(ctx context.Context, channel, username string) error {
+	return c.channelCommand(ctx, channel, fmt.Sprintf("/unvip %s", Username(username)), "_promiseUnvip")
 }
 
 // VIPs gets the list of VIPs in a channel
 func (c *Client) VIPs(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/vips",
-		"_promiseVips",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/vips")
+}
+
+// VIPsContext gets the list of VIPs in a channel
+func (c *Client) VIPsContext(ctx context.Context, channel string) ([]string, error) {
+	return c.listCommand(ctx, channel, "/vips", "_promiseVips")
 }
 
 // R9KBeta enables R9K mode in a channel
 func (c *Client) R9KBeta(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/r9kbeta",
-		"_promiseR9kbeta",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/r9kbeta")
+}
+
+// R9KBetaContext enables R9K mode in a channel and waits for the result
+func (c *Client) R9KBetaContext(ctx context.Context, channel string) error {
+	return c.channelCommand(ctx, channel, "/r9kbeta", "_promiseR9kbeta")
 }
 
 // R9KBetaOff disables R9K mode in a channel
 func (c *Client) R9KBetaOff(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/r9kbetaoff",
-		"_promiseR9kbetaoff",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/r9kbetaoff")
+}
+
+// R9KBetaOffContext disables R9K mode in a channel and waits for the result
+func (c *Client) R9KBetaOffContext(ctx context.Context, channel string) error {
+	return c.channelCommand(ctx, channel, "/r9kbetaoff", "_promiseR9kbetaoff")
 }
 
 // Slow enables slow mode in a channel
 func (c *Client) Slow(channel string, seconds int) error {
+	return c.dispatchCommand(channel, slowCommand(seconds))
+}
+
+// SlowContext enables slow mode in a channel and waits for the result
+func (c *Client) SlowContext(ctx context.Context, channel string, seconds int) error {
+	return c.channelCommand(ctx, channel, slowCommand(seconds), "_promiseSlow")
+}
+
+func slowCommand(seconds int) string {
 	if seconds == 0 {
 		seconds = 300
 	}
-	return c.sendCommandWithResponse(
-		channel,
-		fmt.Sprintf("/slow %d", seconds),
-		"_promiseSlow",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return fmt.Sprintf("/slow %d", seconds)
 }
 
 // SlowOff disables slow mode in a channel
 func (c *Client) SlowOff(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/slowoff",
-		"_promiseSlowoff",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/slowoff")
+}
+
+// SlowOffContext disables slow mode in a channel and waits for the result
+func (c *Client) SlowOffContext(ctx context.Context, channel string) error {
+	return c.channelCommand(ctx, channel, "/slowoff", "_promiseSlowoff")
 }
 
 // Subscribers enables subscribers-only mode in a channel
 func (c *Client) Subscribers(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/subscribers",
-		"_promiseSubscribers",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/subscribers")
+}
+
+// SubscribersContext enables subscribers-only mode in a channel and waits for the result
+func (c *Client) SubscribersContext(ctx context.Context, channel string) error {
+	return c.channelCommand(ctx, channel, "/subscribers", "_promiseSubscribers")
 }
 
 // SubscribersOff disables subscribers-only mode in a channel
 func (c *Client) SubscribersOff(channel string) error {
-	return c.sendCommandWithResponse(
-		channel,
-		"/subscribersoff",
-		"_promiseSubscribersoff",
-		c.getPromiseDelay(),
-		nil,
-	)
+	return c.dispatchCommand(channel, "/subscribersoff")
+}
+
+// SubscribersOffContext disables subscribers-only mode in a channel and waits for the result
+func (c *Client) SubscribersOffContext(ctx context.Context, channel string) error {
+	return c.channelCommand(ctx, channel, "/subscribersoff", "_promiseSubscribersoff")
 }
 
 // Whisper sends a whisper to a user
@@ -382,33 +526,89 @@ func (c *Client) Whisper(username, message string) error {
 		return errors.New("cannot send a whisper to the same account")
 	}
 
-	return c.sendCommandWithResponse(
+	return c.dispatchCommand(c.state.globalDefaultChannel, fmt.Sprintf("/w %s %s", username, message))
+}
+
+// WhisperContext sends a whisper to a user. Twitch only answers whispers
+// when they fail, so the whisper counts as delivered once the promise delay
+// passes without a failure NOTICE.
+func (c *Client) WhisperContext(ctx context.Context, username, message string) error {
+	username = Username(username)
+
+	if username == c.GetUsername() {
+		return errors.New("cannot send a whisper to the same account")
+	}
+
+	_, err := c.sendCommandWithResponse(
+		ctx,
 		c.state.globalDefaultChannel,
 		fmt.Sprintf("/w %s %s", username, message),
 		"_promiseWhisper",
 		c.getPromiseDelay(),
 		nil,
 	)
+	if errors.Is(err, ErrNoResponse) {
+		return nil
+	}
+	return err
 }
 
 // Ping sends a ping to the server
 func (c *Client) Ping() error {
+	c = c.shard("")
+	c.startPingTimeout()
+	return c.sendCommandRaw("PING", nil)
+}
+
+// PingContext sends a ping to the server and returns the measured latency
+func (c *Client) PingContext(ctx context.Context) (time.Duration, error) {
+	c = c.shard("")
+	if !c.isConnected() {
+		return 0, ErrNotConnected
+	}
+	c.startPingTimeout()
+
+	args, err := c.sendCommandWithResponse(ctx, "", "PING", "_promisePing", c.getPromiseDelay(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	latency := 0.0
+	if len(args) > 0 {
+		latency, _ = args[0].(float64)
+	}
+	return time.Duration(latency * float64(time.Second)), nil
+}
+
+// startPingTimeout closes the connection if the server does not answer a
+// PING within the connection timeout
+func (c *Client) startPingTimeout() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn := c.state.conn
+	if conn == nil {
+		return
+	}
+
 	c.state.latency = time.Now()
-	c.state.pingTimeout = time.AfterFunc(c.state.opts.Connection.Timeout, func() {
-		if c.state.ws != nil {
-			c.state.wasCloseCalled = false
-			c.state.log.Error("Ping timeout.")
-			c.state.ws.Close()
-			if c.state.pingLoop != nil {
-				c.state.pingLoop.Stop()
-			}
-			if c.state.pingTimeout != nil {
-				c.state.pingTimeout.Stop()
-			}
+	if c.state.pingTimeout != nil {
+		c.state.pingTimeout.Stop()
+	}
+	c.state.pingTimeout = time.AfterFunc(c.health.opts.PingTimeout, func() {
+		c.mu.Lock()
+		if c.state.conn != conn {
+			c.mu.Unlock()
+			return
 		}
-	})
+		c.state.closeReason = DisconnectPingTimeout
+		c.state.closeMessage = "Ping timeout."
+		c.mu.Unlock()
 
-	return c.sendCommandRaw("PING", nil)
+		c.health.timedOut()
+		c.state.log.Error("Ping timeout.")
+		conn.Close()
+	})
 }
 
 // Raw sends a raw IRC command
@@ -440,8 +640,11 @@ func (c *Client) Reply(channel, message, replyParentMsgID string, tags ...map[st
 
 // sendMessage sends a message to a channel
 func (c *Client) sendMessage(channel, message string, tags ...map[string]string) error {
+	if shard := c.shard(channel); shard != c {
+		return shard.sendMessage(channel, message, tags...)
+	}
 	if !c.isConnected() {
-		return errors.New("not connected to server")
+		return ErrNotConnected
 	}
 
 	if IsJustinfan(c.GetUsername()) {
@@ -463,94 +666,123 @@ func (c *Client) sendMessage(channel, message string, tags ...map[string]string)
 			return err
 		}
 
-		time.Sleep(350 * time.Millisecond)
 		return c.sendMessage(channel, message[lastSpace:], tags...)
 	}
 
-	tagStr := ""
-	if len(tags) > 0 && tags[0] != nil {
-		tagStr = FormTags(tags[0])
-		if tagStr != "" {
-			tagStr += " "
-		}
-	}
-
-	return c.state.ws.WriteMessage(1, fmt.Appendf(nil, "%sPRIVMSG %s :%s", tagStr, channel, message))
+	return c.send(channel, message, tags...)
 }
 
 // sendCommand sends a command to a channel
 func (c *Client) sendCommand(channel, command string, tags ...map[string]string) error {
+	if shard := c.shard(channel); shard != c {
+		return shard.sendCommand(channel, command, tags...)
+	}
 	if !c.isConnected() {
-		return errors.New("not connected to server")
+		return ErrNotConnected
 	}
 
 	channel = Channel(channel)
 
-	tagStr := ""
-	if len(tags) > 0 && tags[0] != nil {
-		tagStr = FormTags(tags[0])
-		if tagStr != "" {
-			tagStr += " "
-		}
-	}
-
 	if channel != "" {
 		c.state.log.Info(fmt.Sprintf("[%s] Executing command: %s", channel, command))
-		return c.state.ws.WriteMessage(1, fmt.Appendf(nil, "%sPRIVMSG %s :%s", tagStr, channel, command))
 	} else {
 		c.state.log.Info(fmt.Sprintf("Executing command: %s", command))
-		return c.state.ws.WriteMessage(1, fmt.Appendf(nil, "%s%s", tagStr, command))
 	}
-
+	return c.send(channel, command, tags...)
 }
 
 // sendCommandRaw sends a raw command
 func (c *Client) sendCommandRaw(command string, tags ...map[string]string) error {
-	if !c.isConnected() {
-		return errors.New("not connected to server")
+	if shard := c.shard(""); shard != c {
+		return shard.sendCommandRaw(command, tags...)
 	}
-
-	tagStr := ""
-	if len(tags) > 0 && tags[0] != nil {
-		tagStr = FormTags(tags[0])
-		if tagStr != "" {
-			tagStr += " "
-		}
+	if !c.isConnected() {
+		return ErrNotConnected
 	}
 
 	c.state.log.Info(fmt.Sprintf("Executing command: %s", command))
-	return c.state.ws.WriteMessage(1, fmt.Appendf(nil, "%s%s", tagStr, command))
+	return c.send("", command, tags...)
 }
 
-// sendCommandWithResponse sends a command and waits for a response event
-func (c *Client) sendCommandWithResponse(channel, command, responseEvent string, timeout time.Duration, tags ...map[string]string) error {
+// dispatchCommand sends a command to a channel, or as a raw command when no
+// channel is given
+func (c *Client) dispatchCommand(channel, command string, tags ...map[string]string) error {
+	if channel != "" {
+		return c.sendCommand(channel, command, tags...)
+	}
+	return c.sendCommandRaw(command, tags...)
+}
+
+// sendCommandWithResponse sends a command and waits for a response event.
+// match narrows down which responses belong to this command; nil accepts any.
+func (c *Client) sendCommandWithResponse(ctx context.Context, channel, command, responseEvent string, timeout time.Duration, match func(args []any) bool, tags ...map[string]string) ([]any, error) {
+	if shard := c.shard(channel); shard != c {
+		return shard.sendCommandWithResponse(ctx, channel, command, responseEvent, timeout, match, tags...)
+	}
 	if !c.isConnected() {
-		return errors.New("not connected to server")
+		return nil, ErrNotConnected
 	}
 
-	// Send the command
-	if channel != "" {
-		err := c.sendCommand(channel, command, tags...)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := c.sendCommandRaw(command, tags...)
-		if err != nil {
-			return err
+	// Register before sending so a fast response cannot be missed
+	w := c.waiters.add(responseEvent, match)
+
+	if err := c.dispatchCommand(channel, command, tags...); err != nil {
+		c.waiters.remove(responseEvent, w)
+		return nil, err
+	}
+
+	args, msgid, err := c.awaitResponse(ctx, responseEvent, w, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if msgid != "" {
+		return args, &CommandError{Command: command, Channel: channel, MsgID: msgid}
+	}
+
+	return args, nil
+}
+
+// channelCommand sends a command to a channel and waits for its response
+func (c *Client) channelCommand(ctx context.Context, channel, command, responseEvent string) error {
+	channel = Channel(channel)
+
+	_, err := c.sendCommandWithResponse(ctx, channel, command, responseEvent, c.getPromiseDelay(), matchChannel(channel))
+	return err
+}
+
+// listCommand sends a command that responds with a list of usernames
+func (c *Client) listCommand(ctx context.Context, channel, command, responseEvent string) ([]string, error) {
+	channel = Channel(channel)
+
+	args, err := c.sendCommandWithResponse(ctx, channel, command, responseEvent, c.getPromiseDelay(), matchChannel(channel))
+	if err != nil {
+		return nil, err
+	}
+
+	list := []string{}
+	if len(args) > 2 {
+		if val, ok := args[2].([]string); ok {
+			list = val
 		}
 	}
+	return list, nil
+}
 
-	// In a real implementation, we'd wait for the response event
-	// For now, we'll just return nil
-	// The event will be emitted when the server responds
-	return nil
+// shard returns the client that sends for a channel: c itself, or the shard
+// that joined the channel when c is the front of a ShardedClient
+func (c *Client) shard(channel string) *Client {
+	if c.route == nil {
+		return c
+	}
+	return c.route(channel)
 }
 
 // getPromiseDelay returns the promise delay based on latency
 func (c *Client) getPromiseDelay() time.Duration {
 	minDelay := 600 * time.Millisecond
+	c.mu.RLock()
 	latencyDelay := c.state.currentLatency + 100*time.Millisecond
+	c.mu.RUnlock()
 	return max(latencyDelay, minDelay)
 }
 
@@ -590,3 +822,39 @@ func (c *Client) UniqueChat(channel string) error {
 func (c *Client) UniqueChatOff(channel string) error {
 	return c.R9KBetaOff(channel)
 }
+
+func (c *Client) FollowersModeContext(ctx context.Context, channel string, minutes int) error {
+	return c.FollowersOnlyContext(ctx, channel, minutes)
+}
+
+func (c *Client) FollowersModeOffContext(ctx context.Context, channel string) error {
+	return c.FollowersOnlyOffContext(ctx, channel)
+}
+
+func (c *Client) LeaveContext(ctx context.Context, channel string) error {
+	return c.PartContext(ctx, channel)
+}
+
+func (c *Client) SlowModeContext(ctx context.Context, channel string, seconds int) error {
+	return c.SlowContext(ctx, channel, seconds)
+}
+
+func (c *Client) SlowModeOffContext(ctx context.Context, channel string) error {
+	return c.SlowOffContext(ctx, channel)
+}
+
+func (c *Client) R9KModeContext(ctx context.Context, channel string) error {
+	return c.R9KBetaContext(ctx, channel)
+}
+
+func (c *Client) R9KModeOffContext(ctx context.Context, channel string) error {
+	return c.R9KBetaOffContext(ctx, channel)
+}
+
+func (c *Client) UniqueChatContext(ctx context.Context, channel string) error {
+	return c.R9KBetaContext(ctx, channel)
+}
+
+func (c *Client) UniqueChatOffContext(ctx context.Context, channel string) error {
+	return c.R9KBetaOffContext(ctx, channel)
+}
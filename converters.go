@@ -2,6 +2,21 @@ package tmigo
 
 // Converter functions to transform map[string]any tags into typed structs
 
+// tagCount reads a numeric tag as a string. Tags holding "1" or "0" have
+// already been turned into booleans, so those are mapped back.
+func tagCount(tags map[string]any, key string) (string, bool) {
+	switch v := tags[key].(type) {
+	case string:
+		return v, true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	}
+	return "", false
+}
+
 // convertToChatUserstate converts tags to ChatUserstate
 func convertToChatUserstate(tags map[string]any) ChatUserstate {
 	userstate := ChatUserstate{
@@ -24,13 +39,13 @@ func convertToSubUserstate(tags map[string]any) SubUserstate {
 		CommonSubUserstate: convertToCommonSubUserstate(tags),
 	}
 
-	if val, ok := tags["msg-param-cumulative-months"].(string); ok {
+	if val, ok := tagCount(tags, "msg-param-cumulative-months"); ok {
 		userstate.MsgParamCumulativeMonths = val
 	}
 	if val, ok := tags["msg-param-should-share-streak"].(bool); ok {
 		userstate.MsgParamShouldShareStreak = val
 	}
-	if val, ok := tags["msg-param-streak-months"].(string); ok {
+	if val, ok := tagCount(tags, "msg-param-streak-months"); ok {
 		userstate.MsgParamStreakMonths = val
 	}
 
@@ -43,7 +58,10 @@ func convertToSubMysteryGiftUserstate(tags map[string]any) SubMysteryGiftUsersta
 		CommonSubUserstate: convertToCommonSubUserstate(tags),
 	}
 
-	if val, ok := tags["msg-param-sender-count"].(string); ok {
+	if val, ok := tagCount(tags, "msg-param-mass-gift-count"); ok {
+		userstate.MsgParamMassGiftCount = val
+	}
+	if val, ok := tagCount(tags, "msg-param-sender-count"); ok {
 		userstate.MsgParamSenderCount = val
 	}
 	if val, ok := tags["msg-param-origin-id"].(string); ok {
@@ -59,7 +77,7 @@ func convertToSubGiftUserstate(tags map[string]any) SubGiftUserstate {
 		CommonGiftSubUserstate: convertToCommonGiftSubUserstate(tags),
 	}
 
-	if val, ok := tags["msg-param-sender-count"].(string); ok {
+	if val, ok := tagCount(tags, "msg-param-sender-count"); ok {
 		userstate.MsgParamSenderCount = val
 	}
 	if val, ok := tags["msg-param-origin-id"].(string); ok {
@@ -78,9 +96,15 @@ func convertToAnonSubGiftUserstate(tags map[string]any) AnonSubGiftUserstate {
 
 // convertToAnonSubMysteryGiftUserstate converts tags to AnonSubMysteryGiftUserstate
 func convertToAnonSubMysteryGiftUserstate(tags map[string]any) AnonSubMysteryGiftUserstate {
-	return AnonSubMysteryGiftUserstate{
+	userstate := AnonSubMysteryGiftUserstate{
 		CommonSubUserstate: convertToCommonSubUserstate(tags),
 	}
+
+	if val, ok := tagCount(tags, "msg-param-mass-gift-count"); ok {
+		userstate.MsgParamMassGiftCount = val
+	}
+
+	return userstate
 }
 
 // convertToSubGiftUpgradeUserstate converts tags to SubGiftUpgradeUserstate
@@ -125,7 +149,7 @@ func convertToRaidUserstate(tags map[string]any) RaidUserstate {
 	if val, ok := tags["msg-param-login"].(string); ok {
 		userstate.MsgParamLogin = val
 	}
-	if val, ok := tags["msg-param-viewerCount"].(string); ok {
+	if val, ok := tagCount(tags, "msg-param-viewerCount"); ok {
 		userstate.MsgParamViewerCount = val
 	}
 
@@ -145,6 +169,64 @@ func convertToRitualUserstate(tags map[string]any) RitualUserstate {
 	return userstate
 }
 
+// convertToUnraidUserstate converts tags to UnraidUserstate
+func convertToUnraidUserstate(tags map[string]any) UnraidUserstate {
+	return UnraidUserstate{
+		UserNoticeState: convertToUserNoticeState(tags),
+	}
+}
+
+// convertToBitsBadgeTierUserstate converts tags to BitsBadgeTierUserstate
+func convertToBitsBadgeTierUserstate(tags map[string]any) BitsBadgeTierUserstate {
+	userstate := BitsBadgeTierUserstate{
+		UserNoticeState: convertToUserNoticeState(tags),
+	}
+
+	if val, ok := tagCount(tags, "msg-param-threshold"); ok {
+		userstate.MsgParamThreshold = val
+	}
+
+	return userstate
+}
+
+// convertToViewerMilestoneUserstate converts tags to ViewerMilestoneUserstate
+func convertToViewerMilestoneUserstate(tags map[string]any) ViewerMilestoneUserstate {
+	userstate := ViewerMilestoneUserstate{
+		UserNoticeState: convertToUserNoticeState(tags),
+	}
+
+	if val, ok := tags["msg-param-category"].(string); ok {
+		userstate.MsgParamCategory = val
+	}
+	if val, ok := tagCount(tags, "msg-param-value"); ok {
+		userstate.MsgParamValue = val
+	}
+	if val, ok := tags["msg-param-copoReward"].(string); ok {
+		userstate.MsgParamCopoReward = val
+	}
+
+	return userstate
+}
+
+// convertToSharedChatNoticeUserstate converts tags to SharedChatNoticeUserstate
+func convertToSharedChatNoticeUserstate(tags map[string]any) SharedChatNoticeUserstate {
+	userstate := SharedChatNoticeUserstate{
+		UserNoticeState: convertToUserNoticeState(tags),
+	}
+
+	if val, ok := tags["source-msg-id"].(string); ok {
+		userstate.SourceMsgID = val
+	}
+	if val, ok := tags["source-room-id"].(string); ok {
+		userstate.SourceRoomID = val
+	}
+	if val, ok := tags["source-id"].(string); ok {
+		userstate.SourceID = val
+	}
+
+	return userstate
+}
+
 // convertToBanUserstate converts tags to BanUserstate
 func convertToBanUserstate(tags map[string]any) BanUserstate {
 	userstate := BanUserstate{}
@@ -194,8 +276,12 @@ func convertToDeleteUserstate(tags map[string]any) DeleteUserstate {
 
 // convertToRoomState converts tags to RoomState
 func convertToRoomState(tags map[string]any) RoomState {
-	roomstate := RoomState{}
+	return mergeRoomState(RoomState{}, tags)
+}
 
+// mergeRoomState applies the tags present in a ROOMSTATE to a known state.
+// Twitch only sends the changed tags after the initial ROOMSTATE.
+func mergeRoomState(roomstate RoomState, tags map[string]any) RoomState {
 	if val, ok := tags["broadcaster-lang"].(string); ok {
 		roomstate.BroadcasterLang = val
 	}
@@ -348,7 +434,7 @@ func convertToCommonGiftSubUserstate(tags map[string]any) CommonGiftSubUserstate
 	if val, ok := tags["msg-param-recipient-user-name"].(string); ok {
 		userstate.MsgParamRecipientUserName = val
 	}
-	if val, ok := tags["msg-param-months"].(string); ok {
+	if val, ok := tagCount(tags, "msg-param-months"); ok {
 		userstate.MsgParamMonths = val
 	}
 
@@ -0,0 +1,206 @@
+package tmigo
+
+import (
+	"cmp"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DispatchStats describes the event dispatcher at one moment
+type DispatchStats struct {
+	Queued   int    // Events waiting for a worker
+	Dropped  uint64 // Events dropped by the overflow policy
+	Panicked uint64 // Listener panics that were recovered
+}
+
+// dispatcher runs the listeners of emitted events, either right away or on
+// one of its workers
+type dispatcher struct {
+	opts    *Dispatch
+	workers []*dispatchWorker
+	report  func(err *ListenerPanicError)
+
+	dropped  atomic.Uint64
+	panicked atomic.Uint64
+}
+
+// dispatchWorker runs queued events in order. Its goroutine only runs while
+// there is something queued.
+type dispatchWorker struct {
+	mu      sync.Mutex
+	space   *sync.Cond // Signalled when an event leaves the queue
+	queue   []dispatchJob
+	running bool
+}
+
+type dispatchJob struct {
+	eventType string
+	run       func()
+}
+
+// newDispatcher creates a dispatcher and applies the defaults of the dispatch
+// options. report is called for every recovered panic.
+func newDispatcher(opts *Dispatch, report func(err *ListenerPanicError)) *dispatcher {
+	opts.Workers = cmp.Or(opts.Workers, 4)
+	opts.QueueSize = cmp.Or(opts.QueueSize, 256)
+	opts.Overflow = cmp.Or(opts.Overflow, OverflowBlock)
+
+	d := &dispatcher{opts: opts, report: report}
+	if opts.Async {
+		d.workers = make([]*dispatchWorker, opts.Workers)
+		for i := range d.workers {
+			w := &dispatchWorker{}
+			w.space = sync.NewCond(&w.mu)
+			d.workers[i] = w
+		}
+	}
+	return d
+}
+
+// snapshotMessage copies raw, its tags and its params for listeners on a
+// worker. Arguments that are raw or its tags are replaced by the copies.
+func snapshotMessage(raw *IRCMessage, args []any) (*IRCMessage, []any) {
+	snapshot := *raw
+	snapshot.Tags = maps.Clone(raw.Tags)
+	snapshot.Params = slices.Clone(raw.Params)
+
+	args = slices.Clone(args)
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case *IRCMessage:
+			if v == raw {
+				args[i] = &snapshot
+			}
+		case map[string]any:
+			if v != nil && reflect.ValueOf(v).UnsafePointer() == reflect.ValueOf(raw.Tags).UnsafePointer() {
+				args[i] = snapshot.Tags
+			}
+		}
+	}
+	return &snapshot, args
+}
+
+// dispatch runs an event's listeners. Events with the same key run in the
+// order they were dispatched. It reports whether the event was not dropped.
+func (d *dispatcher) dispatch(key, eventType string, run func()) bool {
+	if !d.opts.Async {
+		d.guard(eventType, run)
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	w := d.workers[h.Sum32()%uint32(len(d.workers))]
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.queue) >= d.opts.QueueSize {
+		switch d.opts.Overflow {
+		case OverflowDropNewest:
+			d.dropped.Add(1)
+			return false
+		case OverflowDropOldest:
+			w.queue[0] = dispatchJob{}
+			w.queue = w.queue[1:]
+			d.dropped.Add(1)
+		default:
+			w.space.Wait()
+		}
+	}
+
+	w.queue = append(w.queue, dispatchJob{eventType: eventType, run: run})
+	if !w.running {
+		w.running = true
+		go d.work(w)
+	}
+	return true
+}
+
+// work runs the worker's queue until it is empty
+func (d *dispatcher) work(w *dispatchWorker) {
+	for {
+		w.mu.Lock()
+		if len(w.queue) == 0 {
+			w.running = false
+			w.mu.Unlock()
+			return
+		}
+		job := w.queue[0]
+		w.queue[0] = dispatchJob{}
+		w.queue = w.queue[1:]
+		w.space.Signal()
+		w.mu.Unlock()
+
+		d.guard(job.eventType, job.run)
+	}
+}
+
+// guard calls run and recovers a panic escaping from it
+func (d *dispatcher) guard(eventType string, run func()) {
+	defer func() {
+		if v := recover(); v != nil {
+			d.recovered(eventType, v, debug.Stack())
+		}
+	}()
+	run()
+}
+
+// recovered counts and reports a listener panic
+func (d *dispatcher) recovered(eventType string, value any, stack []byte) {
+	d.panicked.Add(1)
+	d.report(&ListenerPanicError{Event: eventType, Value: value, Stack: stack})
+}
+
+// stats returns the queue depth and counters
+func (d *dispatcher) stats() DispatchStats {
+	s := DispatchStats{Dropped: d.dropped.Load(), Panicked: d.panicked.Load()}
+	for _, w := range d.workers {
+		w.mu.Lock()
+		s.Queued += len(w.queue)
+		w.mu.Unlock()
+	}
+	return s
+}
+
+// dispatchKey returns the channel an event belongs to, taken from its first
+// argument. Events without a channel share one key.
+func dispatchKey(args []any) string {
+	if len(args) > 0 {
+		if channel, ok := args[0].(string); ok && strings.HasPrefix(channel, "#") {
+			return channel
+		}
+	}
+	return ""
+}
+
+// DispatchStats returns the number of queued events and how many were dropped
+// or panicked
+func (c *Client) DispatchStats() DispatchStats {
+	return c.dispatch.stats()
+}
+
+// reportPanic runs the error listeners for a recovered listener panic on the
+// current goroutine, so the report cannot wait behind a full queue. A panic
+// in an error listener is only logged.
+func (c *Client) reportPanic(err *ListenerPanicError) {
+	c.state.log.Error(err.Error())
+	if err.Event == "error" {
+		return
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			c.state.log.Error(fmt.Sprintf("error listener panicked: %v", v))
+		}
+	}()
+	c.waiters.resolve("error", []any{err})
+	c.emitListeners(newEventMeta(nil), "error", []any{err})
+}
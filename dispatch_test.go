@@ -0,0 +1,194 @@
+package tmigo
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcher_PerChannelOrder(t *testing.T) {
+	client := NewClient(&ClientOptions{Dispatch: &Dispatch{Async: true, Workers: 3}})
+
+	var mu sync.Mutex
+	got := make(map[string][]int)
+	var wg sync.WaitGroup
+	client.On("chat", func(args ...any) {
+		defer wg.Done()
+		channel, _ := args[0].(string)
+		n, _ := args[1].(int)
+		mu.Lock()
+		got[channel] = append(got[channel], n)
+		mu.Unlock()
+	})
+
+	channels := []string{"#a", "#b", "#c", "#d"}
+	for i := range 100 {
+		for _, channel := range channels {
+			wg.Add(1)
+			client.Emit("chat", channel, i)
+		}
+	}
+	wg.Wait()
+
+	for _, channel := range channels {
+		if !slices.IsSorted(got[channel]) || len(got[channel]) != 100 {
+			t.Errorf("%s events = %v, want 0..99 in order", channel, got[channel])
+		}
+	}
+}
+
+func TestDispatcher_AsyncDoesNotBlockEmit(t *testing.T) {
+	client := NewClient(&ClientOptions{Dispatch: &Dispatch{Async: true}})
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	client.On("chat", func(args ...any) {
+		<-release
+		close(done)
+	})
+
+	returned := make(chan struct{})
+	go func() {
+		client.Emit("chat", "#a")
+		close(returned)
+	}()
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("Emit waited for a slow listener")
+	}
+
+	close(release)
+	<-done
+}
+
+func TestDispatcher_Overflow(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		want   []int
+	}{
+		{OverflowDropNewest, []int{0, 1, 2}},
+		{OverflowDropOldest, []int{0, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			client := NewClient(&ClientOptions{Dispatch: &Dispatch{
+				Async: true, Workers: 1, QueueSize: 2, Overflow: tt.policy,
+			}})
+
+			release := make(chan struct{})
+			started := make(chan struct{}, 1)
+			var mu sync.Mutex
+			var got []int
+			var wg sync.WaitGroup
+			wg.Add(3)
+			client.On("chat", func(args ...any) {
+				defer wg.Done()
+				n, _ := args[1].(int)
+				if n == 0 {
+					started <- struct{}{}
+					<-release
+				}
+				mu.Lock()
+				got = append(got, n)
+				mu.Unlock()
+			})
+
+			// 0 is running, 1 and 2 fill the queue
+			client.Emit("chat", "#a", 0)
+			<-started
+			for i := 1; i <= 4; i++ {
+				client.Emit("chat", "#a", i)
+			}
+
+			s := client.DispatchStats()
+			if s.Queued != 2 || s.Dropped != 2 {
+				t.Errorf("DispatchStats() = %+v, want 2 queued and 2 dropped", s)
+			}
+
+			close(release)
+			wg.Wait()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("delivered %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcher_PanicIsolation(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		client := NewClient(&ClientOptions{Dispatch: &Dispatch{Async: async}})
+
+		errs := make(chan error, 1)
+		delivered := make(chan struct{}, 1)
+		client.On("error", func(args ...any) {
+			err, _ := args[0].(error)
+			errs <- err
+		})
+		client.On("chat", func(args ...any) { panic("boom") })
+		client.On("chat", func(args ...any) { delivered <- struct{}{} })
+
+		client.Emit("chat", "#a")
+
+		select {
+		case <-delivered:
+		case <-time.After(time.Second):
+			t.Fatalf("async=%v: listener after the panicking one did not run", async)
+		}
+		var panicErr *ListenerPanicError
+		select {
+		case err := <-errs:
+			if !errors.As(err, &panicErr) || panicErr.Event != "chat" || panicErr.Value != "boom" {
+				t.Errorf("async=%v: error event = %v, want chat listener panic", async, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("async=%v: no error event for the panic", async)
+		}
+		if n := client.DispatchStats().Panicked; n != 1 {
+			t.Errorf("async=%v: Panicked = %d, want 1", async, n)
+		}
+	}
+}
+
+func TestDispatcher_AsyncRawMessage(t *testing.T) {
+	client := NewClient(&ClientOptions{Dispatch: &Dispatch{Async: true, Workers: 4}})
+
+	// Read every tag on the workers while the read loop handles the next
+	// lines, in channels that hash to other workers than raw_message
+	channels := []string{"#a", "#b", "#c", "#d", "#e", "#f", "#g", "#h"}
+	var wg sync.WaitGroup
+	wg.Add(2 * len(channels))
+	readTags := func(message *IRCMessage) {
+		for key, value := range message.Tags {
+			_, _ = key, value
+		}
+	}
+	client.On("raw_message", func(args ...any) {
+		message, _ := args[0].(*IRCMessage)
+		readTags(message)
+		wg.Done()
+	})
+	On(client, func(e ChatEvent) {
+		readTags(e.Raw)
+		wg.Done()
+	})
+
+	for _, channel := range channels {
+		client.handleMessage(ParseMessage("@badges=subscriber/12;color=#FF0000;display-name=Viewer;mod=0;subscriber=1;tmi-sent-ts=1700000000000 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG " + channel + " :hello"))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("listeners did not run for every line")
+	}
+}
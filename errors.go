@@ -0,0 +1,117 @@
+package tmigo
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotConnected is returned when a command is issued without an open connection
+	ErrNotConnected = errors.New("not connected to server")
+
+	// ErrNoResponse is returned when Twitch does not answer a command before its deadline
+	ErrNoResponse = errors.New("no response from Twitch")
+
+	// ErrRateLimited is returned when a chat message is dropped by the rate limiter
+	ErrRateLimited = errors.New("rate limit exceeded")
+
+	// ErrJoinCanceled is returned for a queued join when Part is called for its channel
+	ErrJoinCanceled = errors.New("join canceled")
+
+	// ErrAuthFailed is returned by ConnectContext when Twitch rejects the login
+	ErrAuthFailed = errors.New("authentication failed")
+
+	// ErrConnectionClosed is returned by ConnectContext when the connection
+	// closes before the session is ready
+	ErrConnectionClosed = errors.New("connection closed")
+
+	// ErrDropped is returned for a message or command that outbound
+	// middleware dropped
+	ErrDropped = errors.New("dropped by outbound middleware")
+
+	// ErrInvalidTransition is matched by a *TransitionError
+	ErrInvalidTransition = errors.New("invalid connection state transition")
+)
+
+// ConnectError is returned by ConnectContext when the session does not become
+// ready. Err is ErrAuthFailed, ErrConnectionClosed, the context's error, the
+// dial error or, when Channel is set, the error joining that channel:
+//
+//	var connErr *tmigo.ConnectError
+//	if errors.As(err, &connErr) && errors.Is(err, tmigo.ErrAuthFailed) {
+//	    log.Fatalf("bad token: %s", connErr.Reason)
+//	}
+type ConnectError struct {
+	Channel string
+	Reason  string // Login NOTICE or disconnect reason
+	Err     error
+}
+
+// Error implements the error interface
+func (e *ConnectError) Error() string {
+	switch {
+	case e.Channel != "":
+		return fmt.Sprintf("connect: joining %s: %v", e.Channel, e.Err)
+	case e.Reason != "":
+		return fmt.Sprintf("connect: %v: %s", e.Err, e.Reason)
+	default:
+		return fmt.Sprintf("connect: %v", e.Err)
+	}
+}
+
+// Unwrap returns the underlying error
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// CommandError is returned by the context-aware command variants when Twitch
+// rejects a command with a NOTICE. MsgID holds the msg-id of that NOTICE, so
+// callers can match on the MsgID constants:
+//
+//	var cmdErr *tmigo.CommandError
+//	if errors.As(err, &cmdErr) && cmdErr.MsgID == tmigo.MsgIDNoPermission {
+//	    // ...
+//	}
+type CommandError struct {
+	Command string
+	Channel string
+	MsgID   MsgID
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	if e.Channel != "" {
+		return fmt.Sprintf("[%s] %s failed: %s", e.Channel, e.Command, e.MsgID)
+	}
+	return fmt.Sprintf("%s failed: %s", e.Command, e.MsgID)
+}
+
+// TransitionError is returned when a call is not allowed in the current
+// connection state, e.g. Connect while already connecting
+type TransitionError struct {
+	From ConnState
+	To   ConnState
+}
+
+// Error implements the error interface
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot go from %s to %s", e.From, e.To)
+}
+
+// Unwrap lets errors.Is match ErrInvalidTransition
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// ListenerPanicError is emitted as an error event when a listener panics.
+// Value is what was passed to panic.
+type ListenerPanicError struct {
+	Event string
+	Value any
+	Stack []byte
+}
+
+// Error implements the error interface
+func (e *ListenerPanicError) Error() string {
+	return fmt.Sprintf("%s listener panicked: %v", e.Event, e.Value)
+}
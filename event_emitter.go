@@ -1,20 +1,52 @@
 package tmigo
 
 import (
+	"reflect"
+	"runtime/debug"
+	"slices"
 	"sync"
+	"sync/atomic"
 )
 
 // EventEmitter provides event handling capabilities
 type EventEmitter struct {
 	mu           sync.RWMutex
-	events       map[string][]EventHandler
+	events       map[string][]*listener
 	maxListeners int
+
+	// Set by Client to recover a panicking listener, so the others still run
+	recover func(eventType string, value any, stack []byte)
+}
+
+// listener is a registered handler. Its pointer identifies it for removal,
+// since funcs cannot be compared.
+type listener struct {
+	handler EventHandler
+	typed   func(m EventMeta, args []any) // Set instead of handler by On
+	once    bool
+	fired   atomic.Bool // Set when a once listener was called
+}
+
+// Subscription is returned by On and Once to remove that listener again
+type Subscription struct {
+	emitter   *EventEmitter
+	eventType string
+	listener  *listener
+}
+
+// Unsubscribe removes the listener. It reports whether the listener was
+// still registered.
+func (s *Subscription) Unsubscribe() bool {
+	if s == nil || s.listener == nil {
+		return false
+	}
+	return s.emitter.remove(s.eventType, func(l *listener) bool { return l == s.listener })
 }
 
 // NewEventEmitter creates a new EventEmitter
 func NewEventEmitter() *EventEmitter {
 	return &EventEmitter{
-		events:       make(map[string][]EventHandler),
+		events:       make(map[string][]*listener),
 		maxListeners: 0,
 	}
 }
@@ -27,56 +59,81 @@ func (e *EventEmitter) SetMaxListeners(n int) {
 }
 
 // On registers an event listener
-func (e *EventEmitter) On(eventType string, listener EventHandler) *EventEmitter {
+func (e *EventEmitter) On(eventType string, handler EventHandler) *Subscription {
+	return e.add(eventType, &listener{handler: handler})
+}
+
+// Once registers a listener that is removed after its first call
+func (e *EventEmitter) Once(eventType string, handler EventHandler) *Subscription {
+	return e.add(eventType, &listener{handler: handler, once: true})
+}
+
+func (e *EventEmitter) add(eventType string, l *listener) *Subscription {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	if e.events[eventType] == nil {
-		e.events[eventType] = []EventHandler{}
-	}
-
 	if e.maxListeners > 0 && len(e.events[eventType]) >= e.maxListeners {
 		// In Go, we'll just log a warning instead of throwing
-		return e
+		return &Subscription{}
 	}
 
-	e.events[eventType] = append(e.events[eventType], listener)
-	return e
-}
-
-// Once registers a one-time event listener
-func (e *EventEmitter) Once(eventType string, listener EventHandler) *EventEmitter {
-	var onceListener EventHandler
-	onceListener = func(args ...any) {
-		e.RemoveListener(eventType, onceListener)
-		listener(args...)
-	}
-	return e.On(eventType, onceListener)
+	e.events[eventType] = append(e.events[eventType], l)
+	return &Subscription{emitter: e, eventType: eventType, listener: l}
 }
 
 // Emit triggers an event with the given arguments
 func (e *EventEmitter) Emit(eventType string, args ...any) bool {
+	return e.emit(eventType, newEventMeta(nil), args)
+}
+
+// emit triggers an event, handing m to the listeners registered by On
+func (e *EventEmitter) emit(eventType string, m EventMeta, args []any) bool {
 	e.mu.RLock()
-	listeners, exists := e.events[eventType]
+	// Copy to avoid issues with listeners that remove themselves
+	listeners := slices.Clone(e.events[eventType])
 	e.mu.RUnlock()
 
-	if !exists || len(listeners) == 0 {
+	if len(listeners) == 0 {
 		return false
 	}
 
-	// Create a copy to avoid issues with listeners that remove themselves
-	listenersCopy := make([]EventHandler, len(listeners))
-	copy(listenersCopy, listeners)
-
-	for _, listener := range listenersCopy {
-		listener(args...)
+	for _, l := range listeners {
+		if l.once {
+			if l.fired.Swap(true) {
+				continue
+			}
+			e.remove(eventType, func(other *listener) bool { return other == l })
+		}
+		e.call(eventType, l, m, args)
 	}
 
 	return true
 }
 
+// call runs one listener, recovering a panic when e.recover is set
+func (e *EventEmitter) call(eventType string, l *listener, m EventMeta, args []any) {
+	if e.recover != nil {
+		defer func() {
+			if v := recover(); v != nil {
+				e.recover(eventType, v, debug.Stack())
+			}
+		}()
+	}
+	if l.typed != nil {
+		l.typed(m, args)
+		return
+	}
+	l.handler(args...)
+}
+
 // Emits triggers multiple events with corresponding argument sets
 func (e *EventEmitter) Emits(types []string, values [][]any) {
+	emitEach(types, values, e.Emit)
+}
+
+// emitEach pairs each event type with its argument set, reusing the last set
+// when there are fewer sets than types
+func emitEach(types []string, values [][]any, emit func(eventType string, args ...any) bool) {
 	for i, eventType := range types {
 		var val []any
 		if i < len(values) {
@@ -84,25 +141,38 @@ func (e *EventEmitter) Emits(types []string, values [][]any) {
 		} else if len(values) > 0 {
 			val = values[len(values)-1]
 		}
-		e.Emit(eventType, val...)
+		emit(eventType, val...)
 	}
 }
 
-// RemoveListener removes a specific event listener
-func (e *EventEmitter) RemoveListener(eventType string, listener EventHandler) *EventEmitter {
+// RemoveListener removes the most recently added listener registered with
+// handler. Funcs are matched by their code, so closures created by the same
+// function literal cannot be told apart; remove those with the Subscription
+// returned by On instead.
+func (e *EventEmitter) RemoveListener(eventType string, handler EventHandler) *EventEmitter {
+	target := reflect.ValueOf(handler).Pointer()
+	e.remove(eventType, func(l *listener) bool {
+		return l.handler != nil && reflect.ValueOf(l.handler).Pointer() == target
+	})
+	return e
+}
+
+// remove deletes the most recently added listener that matches
+func (e *EventEmitter) remove(eventType string, match func(l *listener) bool) bool {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	if _, exists := e.events[eventType]; !exists {
-		return e
+	listeners := e.events[eventType]
+	for i := len(listeners) - 1; i >= 0; i-- {
+		if match(listeners[i]) {
+			e.events[eventType] = slices.Delete(listeners, i, i+1)
+			if len(e.events[eventType]) == 0 {
+				delete(e.events, eventType)
+			}
+			return true
+		}
 	}
-
-	// Since we can't directly compare functions in Go, we'll use a different approach
-	// For now, we'll clear all listeners for this event type when removing
-	// A more sophisticated approach would require listener IDs
-	delete(e.events, eventType)
-
-	return e
+	return false
 }
 
 // RemoveAllListeners removes all listeners for an event type, or all events if no type specified
@@ -111,7 +181,7 @@ func (e *EventEmitter) RemoveAllListeners(eventType ...string) *EventEmitter {
 	defer e.mu.Unlock()
 
 	if len(eventType) == 0 {
-		e.events = make(map[string][]EventHandler)
+		e.events = make(map[string][]*listener)
 	} else {
 		delete(e.events, eventType[0])
 	}
@@ -124,14 +194,13 @@ func (e *EventEmitter) Listeners(eventType string) []EventHandler {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
-	listeners, exists := e.events[eventType]
-	if !exists {
-		return []EventHandler{}
+	result := make([]EventHandler, len(e.events[eventType]))
+	for i, l := range e.events[eventType] {
+		result[i] = l.handler
+		if l.typed != nil {
+			result[i] = func(args ...any) { l.typed(newEventMeta(nil), args) }
+		}
 	}
-
-	// Return a copy
-	result := make([]EventHandler, len(listeners))
-	copy(result, listeners)
 	return result
 }
 
@@ -149,7 +218,7 @@ func (e *EventEmitter) ListenerCount(eventType string) int {
 }
 
 // AddListener is an alias for On
-func (e *EventEmitter) AddListener(eventType string, listener EventHandler) *EventEmitter {
+func (e *EventEmitter) AddListener(eventType string, listener EventHandler) *Subscription {
 	return e.On(eventType, listener)
 }
 
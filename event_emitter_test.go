@@ -1,7 +1,9 @@
 package tmigo
 
 import (
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -405,3 +407,68 @@ func TestEventEmitter_ConcurrentAddRemove(t *testing.T) {
 
 	// Test passed if no race conditions detected
 }
+
+func TestEventEmitter_Unsubscribe(t *testing.T) {
+	ee := NewEventEmitter()
+	var calls []string
+
+	// Closures of the same literal can only be told apart by their handle
+	subs := make([]*Subscription, 3)
+	for i, name := range []string{"a", "b", "c"} {
+		subs[i] = ee.On("test", func(args ...any) { calls = append(calls, name) })
+	}
+
+	if !subs[1].Unsubscribe() {
+		t.Error("Unsubscribe() = false for a registered listener")
+	}
+	if subs[1].Unsubscribe() {
+		t.Error("Unsubscribe() = true for a removed listener")
+	}
+	ee.Emit("test")
+
+	if got := strings.Join(calls, ""); got != "ac" {
+		t.Errorf("called %q after unsubscribing b, want ac", got)
+	}
+}
+
+func TestEventEmitter_OnceKeepsOtherListeners(t *testing.T) {
+	ee := NewEventEmitter()
+	var onceCalls, otherCalls atomic.Int32
+
+	ee.On("test", func(args ...any) { otherCalls.Add(1) })
+	ee.Once("test", func(args ...any) { onceCalls.Add(1) })
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() { ee.Emit("test") })
+	}
+	wg.Wait()
+
+	if n := onceCalls.Load(); n != 1 {
+		t.Errorf("Once listener called %d times, want 1", n)
+	}
+	if n := otherCalls.Load(); n != 10 {
+		t.Errorf("other listener called %d times, want 10", n)
+	}
+	if n := ee.ListenerCount("test"); n != 1 {
+		t.Errorf("ListenerCount = %d after Once fired, want 1", n)
+	}
+}
+
+func TestEventEmitter_OffKeepsOtherListeners(t *testing.T) {
+	ee := NewEventEmitter()
+	called := false
+
+	handler := func(args ...any) {}
+	ee.On("test", func(args ...any) { called = true })
+	ee.On("test", handler)
+	ee.Off("test", handler)
+	ee.Emit("test")
+
+	if !called {
+		t.Error("Off removed a different listener")
+	}
+	if n := ee.ListenerCount("test"); n != 1 {
+		t.Errorf("ListenerCount = %d after Off, want 1", n)
+	}
+}
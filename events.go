@@ -0,0 +1,891 @@
+package tmigo
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Event is an event delivered as a struct, by On, the typed On* helpers and
+// the streams of Events. Name returns the event type it was emitted as.
+type Event interface {
+	Name() string
+}
+
+// EventMeta is embedded in every event struct
+type EventMeta struct {
+	Raw      *IRCMessage // Message the event came from, nil for events the client raised itself
+	Received time.Time   // When the event was emitted
+	Sent     time.Time   // When Twitch sent Raw (tmi-sent-ts), zero when unknown
+}
+
+// Context returns the context of the message the event came from, which
+// carries the values the inbound middleware added. Events the client raised
+// itself return context.Background.
+func (m EventMeta) Context() context.Context {
+	if m.Raw == nil {
+		return context.Background()
+	}
+	return m.Raw.Context()
+}
+
+// newEventMeta describes an event emitted now, caused by raw when set
+func newEventMeta(raw *IRCMessage) EventMeta {
+	m := EventMeta{Raw: raw, Received: time.Now()}
+	if raw != nil {
+		if ts, ok := raw.Tags["tmi-sent-ts"].(string); ok {
+			if ms, err := strconv.ParseInt(ts, 10, 64); err == nil {
+				m.Sent = time.UnixMilli(ms)
+			}
+		}
+	}
+	return m
+}
+
+// eventDecoder builds an event struct from at least args arguments
+type eventDecoder struct {
+	args   int
+	decode func(m EventMeta, args []any) Event
+}
+
+// arg returns argument i as a T, or the zero value when it is missing or of
+// another type
+func arg[T any](args []any, i int) T {
+	var v T
+	if i < len(args) {
+		v, _ = args[i].(T)
+	}
+	return v
+}
+
+// On registers a handler for the event struct T:
+//
+//	tmigo.On(client, func(e tmigo.TimeoutEvent) {
+//	    log.Printf("%s timed out in %s for %ds", e.Username, e.Channel, e.Duration)
+//	})
+func On[T Event](c *Client, handler func(e T)) *Subscription {
+	var zero T
+	return c.onEvent(zero.Name(), func(e Event) {
+		handler(e.(T))
+	})
+}
+
+// onEvent registers a handler that receives the struct of an event type
+func (c *Client) onEvent(eventType string, handler func(e Event)) *Subscription {
+	decoder, ok := eventDecoders[eventType]
+	if !ok {
+		return &Subscription{}
+	}
+	return c.EventEmitter.add(eventType, &listener{typed: func(m EventMeta, args []any) {
+		if len(args) >= decoder.args {
+			handler(decoder.decode(m, args))
+		}
+	}})
+}
+
+// MessageEvent is a chat, action or whisper message. For whispers Channel
+// holds the sender.
+type MessageEvent struct {
+	EventMeta
+	Channel   string
+	Userstate ChatUserstate
+	Message   string
+	Self      bool
+}
+
+// ChatEvent is emitted for regular chat messages
+type ChatEvent struct {
+	EventMeta
+	Channel   string
+	Userstate ChatUserstate
+	Message   string
+	Self      bool
+}
+
+// ActionEvent is emitted for action messages (/me)
+type ActionEvent struct {
+	EventMeta
+	Channel   string
+	Userstate ChatUserstate
+	Message   string
+	Self      bool
+}
+
+// WhisperEvent is a whisper from another user
+type WhisperEvent struct {
+	EventMeta
+	From      string
+	Userstate ChatUserstate
+	Message   string
+	Self      bool
+}
+
+// CheerEvent is a message with bits
+type CheerEvent struct {
+	EventMeta
+	Channel   string
+	Userstate ChatUserstate
+	Message   string
+}
+
+// SubEvent is a new subscription
+type SubEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Methods   SubMethods
+	Message   string
+	Userstate SubUserstate
+}
+
+// ResubEvent is a resubscription
+type ResubEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Months    int
+	Message   string
+	Userstate SubUserstate
+	Methods   SubMethods
+}
+
+// SubGiftEvent is a subscription gifted to Recipient
+type SubGiftEvent struct {
+	EventMeta
+	Channel      string
+	Username     string
+	StreakMonths int
+	Recipient    string
+	Methods      SubMethods
+	Userstate    SubGiftUserstate
+}
+
+// SubMysteryGiftEvent is emitted for mystery gift subscription events
+type SubMysteryGiftEvent struct {
+	EventMeta
+	Channel    string
+	Username   string
+	NumbOfSubs int
+	Methods    SubMethods
+	Userstate  SubMysteryGiftUserstate
+}
+
+// AnonSubGiftEvent is emitted for anonymous gifted subscriptions
+type AnonSubGiftEvent struct {
+	EventMeta
+	Channel      string
+	StreakMonths int
+	Recipient    string
+	Methods      SubMethods
+	Userstate    AnonSubGiftUserstate
+}
+
+// AnonSubMysteryGiftEvent is emitted for anonymous mystery gift subscriptions
+type AnonSubMysteryGiftEvent struct {
+	EventMeta
+	Channel    string
+	NumbOfSubs int
+	Methods    SubMethods
+	Userstate  AnonSubMysteryGiftUserstate
+}
+
+// GiftPaidUpgradeEvent is emitted for gift subscription upgrades
+type GiftPaidUpgradeEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Sender    string
+	Userstate SubGiftUpgradeUserstate
+}
+
+// AnonGiftPaidUpgradeEvent is emitted for anonymous gift subscription upgrades
+type AnonGiftPaidUpgradeEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Userstate AnonSubGiftUpgradeUserstate
+}
+
+// PrimePaidUpgradeEvent is emitted for Prime subscription upgrades
+type PrimePaidUpgradeEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Methods   SubMethods
+	Userstate PrimeUpgradeUserstate
+}
+
+// RaidEvent is an incoming raid
+type RaidEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Viewers   int
+	Userstate RaidUserstate
+}
+
+// UnraidEvent is emitted for cancelled raids
+type UnraidEvent struct {
+	EventMeta
+	Channel   string
+	Userstate UnraidUserstate
+}
+
+// RitualEvent is emitted for ritual events (e.g. new chatters)
+type RitualEvent struct {
+	EventMeta
+	Channel    string
+	Username   string
+	RitualName string
+	Message    string
+	Userstate  RitualUserstate
+}
+
+// BitsBadgeTierEvent is emitted for bits badge tier events
+type BitsBadgeTierEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Threshold int
+	Message   string
+	Userstate BitsBadgeTierUserstate
+}
+
+// ViewerMilestoneEvent is emitted for viewer milestone events
+type ViewerMilestoneEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Category  string
+	Value     int
+	Message   string
+	Userstate ViewerMilestoneUserstate
+}
+
+// SharedChatNoticeEvent is emitted for notices relayed from a shared chat
+// session
+type SharedChatNoticeEvent struct {
+	EventMeta
+	Channel     string
+	SourceMsgID string
+	Message     string
+	Userstate   SharedChatNoticeUserstate
+}
+
+// RedeemEvent is emitted for channel point redemption events
+type RedeemEvent struct {
+	EventMeta
+	Channel    string
+	Username   string
+	RewardType string
+	Userstate  ChatUserstate
+	Message    string
+}
+
+// BanEvent is a permanent ban
+type BanEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Reason    string
+	Userstate BanUserstate
+}
+
+// TimeoutEvent is a timeout of Duration seconds
+type TimeoutEvent struct {
+	EventMeta
+	Channel   string
+	Username  string
+	Reason    string
+	Duration  int
+	Userstate TimeoutUserstate
+}
+
+// MessageDeletedEvent is a single deleted message
+type MessageDeletedEvent struct {
+	EventMeta
+	Channel        string
+	Username       string
+	DeletedMessage string
+	Userstate      DeleteUserstate
+}
+
+// JoinEvent is a user joining a channel
+type JoinEvent struct {
+	EventMeta
+	Channel  string
+	Username string
+	Self     bool
+}
+
+// PartEvent is a user leaving a channel
+type PartEvent struct {
+	EventMeta
+	Channel  string
+	Username string
+	Self     bool
+}
+
+// HostedEvent is emitted for hosted events
+type HostedEvent struct {
+	EventMeta
+	Channel  string
+	Username string
+	Viewers  int
+	Autohost bool
+}
+
+// HostingEvent is emitted for hosting events
+type HostingEvent struct {
+	EventMeta
+	Channel string
+	Target  string
+	Viewers int
+}
+
+// UnhostEvent is emitted for unhost events
+type UnhostEvent struct {
+	EventMeta
+	Channel string
+	Viewers int
+}
+
+// ModEvent is emitted for mod events
+type ModEvent struct {
+	EventMeta
+	Channel  string
+	Username string
+}
+
+// UnmodEvent is emitted for unmod events
+type UnmodEvent struct {
+	EventMeta
+	Channel  string
+	Username string
+}
+
+// ModsEvent is emitted for mods list events
+type ModsEvent struct {
+	EventMeta
+	Channel string
+	Mods    []string
+}
+
+// VipsEvent is emitted for VIPs list events
+type VipsEvent struct {
+	EventMeta
+	Channel string
+	Vips    []string
+}
+
+// NoticeEvent is a NOTICE from Twitch
+type NoticeEvent struct {
+	EventMeta
+	Channel string
+	MsgID   MsgID
+	Message string
+}
+
+// JoinProgressEvent is emitted for join scheduler progress events
+type JoinProgressEvent struct {
+	EventMeta
+	Progress JoinProgress
+}
+
+// MessageDroppedEvent is emitted for outbound messages dropped by the rate
+// limiter
+type MessageDroppedEvent struct {
+	EventMeta
+	Channel string
+	Message string
+	Reason  DropReason
+}
+
+// MsgRatelimitEvent is emitted for messages rejected for exceeding the rate
+// limit
+type MsgRatelimitEvent NoticeEvent
+
+// MsgDuplicateEvent is emitted for messages rejected as duplicates
+type MsgDuplicateEvent NoticeEvent
+
+// MsgBannedEvent is emitted for messages rejected because the client is banned
+type MsgBannedEvent NoticeEvent
+
+// MsgSubsonlyEvent is emitted for messages rejected by subscribers-only mode
+type MsgSubsonlyEvent NoticeEvent
+
+// MsgEmoteonlyEvent is emitted for messages rejected by emote-only mode
+type MsgEmoteonlyEvent NoticeEvent
+
+// ChannelSuspendedEvent is emitted for notices that a channel is suspended
+type ChannelSuspendedEvent NoticeEvent
+
+// WhisperLimitEvent is emitted for whispers rejected by the per-second or per-
+// minute limit
+type WhisperLimitEvent NoticeEvent
+
+// AutomodEvent is emitted for messages held or rejected by AutoMod
+type AutomodEvent NoticeEvent
+
+// AuthFailedEvent is emitted for rejected logins
+type AuthFailedEvent struct {
+	EventMeta
+	Reason string
+}
+
+// RoomstateEvent is emitted for roomstate events
+type RoomstateEvent struct {
+	EventMeta
+	Channel string
+	State   RoomState
+}
+
+// ClearchatEvent is emitted for clearchat events
+type ClearchatEvent struct {
+	EventMeta
+	Channel string
+}
+
+// EmoteonlyEvent is emitted for emote-only mode events
+type EmoteonlyEvent struct {
+	EventMeta
+	Channel string
+	Enabled bool
+}
+
+// FollowersonlyEvent is emitted for followers-only mode events
+type FollowersonlyEvent struct {
+	EventMeta
+	Channel string
+	Enabled bool
+	Length  int
+}
+
+// SlowmodeEvent is emitted for slow mode events
+type SlowmodeEvent struct {
+	EventMeta
+	Channel string
+	Enabled bool
+	Length  int
+}
+
+// SubscribersEvent is emitted for subscribers-only mode events
+type SubscribersEvent struct {
+	EventMeta
+	Channel string
+	Enabled bool
+}
+
+// R9kbetaEvent is emitted for R9K mode events
+type R9kbetaEvent struct {
+	EventMeta
+	Channel string
+	Enabled bool
+}
+
+// ConnectedEvent is a connection that was opened
+type ConnectedEvent struct {
+	EventMeta
+	Address string
+	Port    int
+}
+
+// ConnectingEvent is emitted for connecting events
+type ConnectingEvent struct {
+	EventMeta
+	Address string
+	Port    int
+}
+
+// DisconnectedEvent is a connection that was closed
+type DisconnectedEvent struct {
+	EventMeta
+	Reason string
+}
+
+// LogonEvent is emitted for logon events
+type LogonEvent struct {
+	EventMeta
+}
+
+// ReconnectEvent is emitted for reconnect events
+type ReconnectEvent struct {
+	EventMeta
+}
+
+// PingEvent is emitted for ping events
+type PingEvent struct {
+	EventMeta
+}
+
+// PongEvent is emitted for pong events
+type PongEvent struct {
+	EventMeta
+	Latency float64
+}
+
+// DegradedEvent is emitted for events when the keepalive latency reaches
+// Health.DegradedLatency
+type DegradedEvent struct {
+	EventMeta
+	Health HealthSnapshot
+}
+
+// RecoveredEvent is emitted for events when the keepalive latency drops below
+// Health.DegradedLatency again
+type RecoveredEvent struct {
+	EventMeta
+	Health HealthSnapshot
+}
+
+// StateChangeEvent is emitted for connection state transitions. reason is set
+// when a lost connection caused the transition.
+type StateChangeEvent struct {
+	EventMeta
+	Old    ConnState
+	New    ConnState
+	Reason DisconnectReason
+}
+
+// EmotesetsEvent is emitted for emoteset events
+type EmotesetsEvent struct {
+	EventMeta
+	Sets string
+	Obj  map[string]any
+}
+
+// RawMessageEvent is emitted for raw IRC message events
+type RawMessageEvent struct {
+	EventMeta
+	Message *IRCMessage
+}
+
+// AnnouncementEvent is emitted for announcement events
+type AnnouncementEvent struct {
+	EventMeta
+	Channel   string
+	Userstate ChatUserstate
+	Message   string
+	Self      bool
+	Color     string
+}
+
+// NamesEvent is emitted for the users listed when joining a channel
+type NamesEvent struct {
+	EventMeta
+	Channel string
+	Names   []string
+}
+
+// UsernoticeEvent is emitted for a USERNOTICE without a dedicated event
+type UsernoticeEvent struct {
+	EventMeta
+	MsgID   string
+	Channel string
+	Tags    map[string]any
+	Message string
+}
+
+// GlobalUserstateEvent is emitted for the GLOBALUSERSTATE sent after login
+type GlobalUserstateEvent struct {
+	EventMeta
+	Tags map[string]any
+}
+
+// MaxReconnectEvent is emitted when the client gives up reconnecting
+type MaxReconnectEvent struct {
+	EventMeta
+}
+
+// ErrorEvent is emitted for a listener that panicked
+type ErrorEvent struct {
+	EventMeta
+	Err error
+}
+
+func (MessageEvent) Name() string             { return "message" }
+func (ChatEvent) Name() string                { return "chat" }
+func (ActionEvent) Name() string              { return "action" }
+func (WhisperEvent) Name() string             { return "whisper" }
+func (CheerEvent) Name() string               { return "cheer" }
+func (SubEvent) Name() string                 { return "subscription" }
+func (ResubEvent) Name() string               { return "resub" }
+func (SubGiftEvent) Name() string             { return "subgift" }
+func (SubMysteryGiftEvent) Name() string      { return "submysterygift" }
+func (AnonSubGiftEvent) Name() string         { return "anonsubgift" }
+func (AnonSubMysteryGiftEvent) Name() string  { return "anonsubmysterygift" }
+func (GiftPaidUpgradeEvent) Name() string     { return "giftpaidupgrade" }
+func (AnonGiftPaidUpgradeEvent) Name() string { return "anongiftpaidupgrade" }
+func (PrimePaidUpgradeEvent) Name() string    { return "primepaidupgrade" }
+func (RaidEvent) Name() string                { return "raided" }
+func (UnraidEvent) Name() string              { return "unraid" }
+func (RitualEvent) Name() string              { return "ritual" }
+func (BitsBadgeTierEvent) Name() string       { return "bitsbadgetier" }
+func (ViewerMilestoneEvent) Name() string     { return "viewermilestone" }
+func (SharedChatNoticeEvent) Name() string    { return "sharedchatnotice" }
+func (RedeemEvent) Name() string              { return "redeem" }
+func (BanEvent) Name() string                 { return "ban" }
+func (TimeoutEvent) Name() string             { return "timeout" }
+func (MessageDeletedEvent) Name() string      { return "messagedeleted" }
+func (JoinEvent) Name() string                { return "join" }
+func (PartEvent) Name() string                { return "part" }
+func (HostedEvent) Name() string              { return "hosted" }
+func (HostingEvent) Name() string             { return "hosting" }
+func (UnhostEvent) Name() string              { return "unhost" }
+func (ModEvent) Name() string                 { return "mod" }
+func (UnmodEvent) Name() string               { return "unmod" }
+func (ModsEvent) Name() string                { return "mods" }
+func (VipsEvent) Name() string                { return "vips" }
+func (NoticeEvent) Name() string              { return "notice" }
+func (JoinProgressEvent) Name() string        { return "joinprogress" }
+func (MessageDroppedEvent) Name() string      { return "messagedropped" }
+func (MsgRatelimitEvent) Name() string        { return "msgratelimit" }
+func (MsgDuplicateEvent) Name() string        { return "msgduplicate" }
+func (MsgBannedEvent) Name() string           { return "msgbanned" }
+func (MsgSubsonlyEvent) Name() string         { return "msgsubsonly" }
+func (MsgEmoteonlyEvent) Name() string        { return "msgemoteonly" }
+func (ChannelSuspendedEvent) Name() string    { return "channelsuspended" }
+func (WhisperLimitEvent) Name() string        { return "whisperlimit" }
+func (AutomodEvent) Name() string             { return "automod" }
+func (AuthFailedEvent) Name() string          { return "authfailed" }
+func (RoomstateEvent) Name() string           { return "roomstate" }
+func (ClearchatEvent) Name() string           { return "clearchat" }
+func (EmoteonlyEvent) Name() string           { return "emoteonly" }
+func (FollowersonlyEvent) Name() string       { return "followersonly" }
+func (SlowmodeEvent) Name() string            { return "slowmode" }
+func (SubscribersEvent) Name() string         { return "subscribers" }
+func (R9kbetaEvent) Name() string             { return "r9kbeta" }
+func (ConnectedEvent) Name() string           { return "connected" }
+func (ConnectingEvent) Name() string          { return "connecting" }
+func (DisconnectedEvent) Name() string        { return "disconnected" }
+func (LogonEvent) Name() string               { return "logon" }
+func (ReconnectEvent) Name() string           { return "reconnect" }
+func (PingEvent) Name() string                { return "ping" }
+func (PongEvent) Name() string                { return "pong" }
+func (DegradedEvent) Name() string            { return "degraded" }
+func (RecoveredEvent) Name() string           { return "recovered" }
+func (StateChangeEvent) Name() string         { return "statechange" }
+func (EmotesetsEvent) Name() string           { return "emotesets" }
+func (RawMessageEvent) Name() string          { return "raw_message" }
+func (AnnouncementEvent) Name() string        { return "announcement" }
+func (NamesEvent) Name() string               { return "names" }
+func (UsernoticeEvent) Name() string          { return "usernotice" }
+func (GlobalUserstateEvent) Name() string     { return "globaluserstate" }
+func (MaxReconnectEvent) Name() string        { return "maxreconnect" }
+func (ErrorEvent) Name() string               { return "error" }
+
+// eventDecoders turns the arguments of every event type with a struct into
+// that struct. It is the one place that knows the argument order of each
+// event; On and the typed On* helpers all build on it.
+var eventDecoders = map[string]eventDecoder{
+	"message": {4, func(m EventMeta, args []any) Event {
+		return MessageEvent{m, arg[string](args, 0), arg[ChatUserstate](args, 1), arg[string](args, 2), arg[bool](args, 3)}
+	}},
+	"chat": {4, func(m EventMeta, args []any) Event {
+		return ChatEvent{m, arg[string](args, 0), arg[ChatUserstate](args, 1), arg[string](args, 2), arg[bool](args, 3)}
+	}},
+	"action": {4, func(m EventMeta, args []any) Event {
+		return ActionEvent{m, arg[string](args, 0), arg[ChatUserstate](args, 1), arg[string](args, 2), arg[bool](args, 3)}
+	}},
+	"whisper": {4, func(m EventMeta, args []any) Event {
+		return WhisperEvent{m, arg[string](args, 0), arg[ChatUserstate](args, 1), arg[string](args, 2), arg[bool](args, 3)}
+	}},
+	"cheer": {3, func(m EventMeta, args []any) Event {
+		return CheerEvent{m, arg[string](args, 0), arg[ChatUserstate](args, 1), arg[string](args, 2)}
+	}},
+	"subscription": {5, func(m EventMeta, args []any) Event {
+		return SubEvent{m, arg[string](args, 0), arg[string](args, 1), arg[SubMethods](args, 2), arg[string](args, 3), arg[SubUserstate](args, 4)}
+	}},
+	"resub": {6, func(m EventMeta, args []any) Event {
+		return ResubEvent{m, arg[string](args, 0), arg[string](args, 1), arg[int](args, 2), arg[string](args, 3), arg[SubUserstate](args, 4), arg[SubMethods](args, 5)}
+	}},
+	"subgift": {6, func(m EventMeta, args []any) Event {
+		return SubGiftEvent{m, arg[string](args, 0), arg[string](args, 1), arg[int](args, 2), arg[string](args, 3), arg[SubMethods](args, 4), arg[SubGiftUserstate](args, 5)}
+	}},
+	"submysterygift": {5, func(m EventMeta, args []any) Event {
+		return SubMysteryGiftEvent{m, arg[string](args, 0), arg[string](args, 1), arg[int](args, 2), arg[SubMethods](args, 3), arg[SubMysteryGiftUserstate](args, 4)}
+	}},
+	"anonsubgift": {5, func(m EventMeta, args []any) Event {
+		return AnonSubGiftEvent{m, arg[string](args, 0), arg[int](args, 1), arg[string](args, 2), arg[SubMethods](args, 3), arg[AnonSubGiftUserstate](args, 4)}
+	}},
+	"anonsubmysterygift": {4, func(m EventMeta, args []any) Event {
+		return AnonSubMysteryGiftEvent{m, arg[string](args, 0), arg[int](args, 1), arg[SubMethods](args, 2), arg[AnonSubMysteryGiftUserstate](args, 3)}
+	}},
+	"giftpaidupgrade": {4, func(m EventMeta, args []any) Event {
+		return GiftPaidUpgradeEvent{m, arg[string](args, 0), arg[string](args, 1), arg[string](args, 2), arg[SubGiftUpgradeUserstate](args, 3)}
+	}},
+	"anongiftpaidupgrade": {3, func(m EventMeta, args []any) Event {
+		return AnonGiftPaidUpgradeEvent{m, arg[string](args, 0), arg[string](args, 1), arg[AnonSubGiftUpgradeUserstate](args, 2)}
+	}},
+	"primepaidupgrade": {4, func(m EventMeta, args []any) Event {
+		return PrimePaidUpgradeEvent{m, arg[string](args, 0), arg[string](args, 1), arg[SubMethods](args, 2), arg[PrimeUpgradeUserstate](args, 3)}
+	}},
+	"raided": {3, func(m EventMeta, args []any) Event {
+		return RaidEvent{m, arg[string](args, 0), arg[string](args, 1), arg[int](args, 2), arg[RaidUserstate](args, 3)}
+	}},
+	"unraid": {2, func(m EventMeta, args []any) Event {
+		return UnraidEvent{m, arg[string](args, 0), arg[UnraidUserstate](args, 1)}
+	}},
+	"ritual": {5, func(m EventMeta, args []any) Event {
+		return RitualEvent{m, arg[string](args, 0), arg[string](args, 1), arg[string](args, 2), arg[string](args, 3), arg[RitualUserstate](args, 4)}
+	}},
+	"bitsbadgetier": {5, func(m EventMeta, args []any) Event {
+		return BitsBadgeTierEvent{m, arg[string](args, 0), arg[string](args, 1), arg[int](args, 2), arg[string](args, 3), arg[BitsBadgeTierUserstate](args, 4)}
+	}},
+	"viewermilestone": {6, func(m EventMeta, args []any) Event {
+		return ViewerMilestoneEvent{m, arg[string](args, 0), arg[string](args, 1), arg[string](args, 2), arg[int](args, 3), arg[string](args, 4), arg[ViewerMilestoneUserstate](args, 5)}
+	}},
+	"sharedchatnotice": {4, func(m EventMeta, args []any) Event {
+		return SharedChatNoticeEvent{m, arg[string](args, 0), arg[string](args, 1), arg[string](args, 2), arg[SharedChatNoticeUserstate](args, 3)}
+	}},
+	"redeem": {5, func(m EventMeta, args []any) Event {
+		return RedeemEvent{m, arg[string](args, 0), arg[string](args, 1), arg[string](args, 2), arg[ChatUserstate](args, 3), arg[string](args, 4)}
+	}},
+	"ban": {4, func(m EventMeta, args []any) Event {
+		return BanEvent{m, arg[string](args, 0), arg[string](args, 1), arg[string](args, 2), arg[BanUserstate](args, 3)}
+	}},
+	"timeout": {5, func(m EventMeta, args []any) Event {
+		return TimeoutEvent{m, arg[string](args, 0), arg[string](args, 1), arg[string](args, 2), arg[int](args, 3), arg[TimeoutUserstate](args, 4)}
+	}},
+	"messagedeleted": {4, func(m EventMeta, args []any) Event {
+		return MessageDeletedEvent{m, arg[string](args, 0), arg[string](args, 1), arg[string](args, 2), arg[DeleteUserstate](args, 3)}
+	}},
+	"join": {3, func(m EventMeta, args []any) Event {
+		return JoinEvent{m, arg[string](args, 0), arg[string](args, 1), arg[bool](args, 2)}
+	}},
+	"part": {3, func(m EventMeta, args []any) Event {
+		return PartEvent{m, arg[string](args, 0), arg[string](args, 1), arg[bool](args, 2)}
+	}},
+	"hosted": {4, func(m EventMeta, args []any) Event {
+		return HostedEvent{m, arg[string](args, 0), arg[string](args, 1), arg[int](args, 2), arg[bool](args, 3)}
+	}},
+	"hosting": {3, func(m EventMeta, args []any) Event {
+		return HostingEvent{m, arg[string](args, 0), arg[string](args, 1), arg[int](args, 2)}
+	}},
+	"unhost": {2, func(m EventMeta, args []any) Event {
+		return UnhostEvent{m, arg[string](args, 0), arg[int](args, 1)}
+	}},
+	"mod": {2, func(m EventMeta, args []any) Event {
+		return ModEvent{m, arg[string](args, 0), arg[string](args, 1)}
+	}},
+	"unmod": {2, func(m EventMeta, args []any) Event {
+		return UnmodEvent{m, arg[string](args, 0), arg[string](args, 1)}
+	}},
+	"mods": {2, func(m EventMeta, args []any) Event {
+		return ModsEvent{m, arg[string](args, 0), arg[[]string](args, 1)}
+	}},
+	"vips": {2, func(m EventMeta, args []any) Event {
+		return VipsEvent{m, arg[string](args, 0), arg[[]string](args, 1)}
+	}},
+	"notice": {3, func(m EventMeta, args []any) Event {
+		return NoticeEvent{m, arg[string](args, 0), arg[MsgID](args, 1), arg[string](args, 2)}
+	}},
+	"joinprogress": {1, func(m EventMeta, args []any) Event {
+		return JoinProgressEvent{m, arg[JoinProgress](args, 0)}
+	}},
+	"messagedropped": {3, func(m EventMeta, args []any) Event {
+		return MessageDroppedEvent{m, arg[string](args, 0), arg[string](args, 1), arg[DropReason](args, 2)}
+	}},
+	"msgratelimit": {3, func(m EventMeta, args []any) Event {
+		return MsgRatelimitEvent{m, arg[string](args, 0), arg[MsgID](args, 1), arg[string](args, 2)}
+	}},
+	"msgduplicate": {3, func(m EventMeta, args []any) Event {
+		return MsgDuplicateEvent{m, arg[string](args, 0), arg[MsgID](args, 1), arg[string](args, 2)}
+	}},
+	"msgbanned": {3, func(m EventMeta, args []any) Event {
+		return MsgBannedEvent{m, arg[string](args, 0), arg[MsgID](args, 1), arg[string](args, 2)}
+	}},
+	"msgsubsonly": {3, func(m EventMeta, args []any) Event {
+		return MsgSubsonlyEvent{m, arg[string](args, 0), arg[MsgID](args, 1), arg[string](args, 2)}
+	}},
+	"msgemoteonly": {3, func(m EventMeta, args []any) Event {
+		return MsgEmoteonlyEvent{m, arg[string](args, 0), arg[MsgID](args, 1), arg[string](args, 2)}
+	}},
+	"channelsuspended": {3, func(m EventMeta, args []any) Event {
+		return ChannelSuspendedEvent{m, arg[string](args, 0), arg[MsgID](args, 1), arg[string](args, 2)}
+	}},
+	"whisperlimit": {3, func(m EventMeta, args []any) Event {
+		return WhisperLimitEvent{m, arg[string](args, 0), arg[MsgID](args, 1), arg[string](args, 2)}
+	}},
+	"automod": {3, func(m EventMeta, args []any) Event {
+		return AutomodEvent{m, arg[string](args, 0), arg[MsgID](args, 1), arg[string](args, 2)}
+	}},
+	"authfailed": {1, func(m EventMeta, args []any) Event {
+		return AuthFailedEvent{m, arg[string](args, 0)}
+	}},
+	"roomstate": {2, func(m EventMeta, args []any) Event {
+		return RoomstateEvent{m, arg[string](args, 0), arg[RoomState](args, 1)}
+	}},
+	"clearchat": {1, func(m EventMeta, args []any) Event {
+		return ClearchatEvent{m, arg[string](args, 0)}
+	}},
+	"emoteonly": {2, func(m EventMeta, args []any) Event {
+		return EmoteonlyEvent{m, arg[string](args, 0), arg[bool](args, 1)}
+	}},
+	"followersonly": {3, func(m EventMeta, args []any) Event {
+		return FollowersonlyEvent{m, arg[string](args, 0), arg[bool](args, 1), arg[int](args, 2)}
+	}},
+	"slowmode": {3, func(m EventMeta, args []any) Event {
+		return SlowmodeEvent{m, arg[string](args, 0), arg[bool](args, 1), arg[int](args, 2)}
+	}},
+	"subscribers": {2, func(m EventMeta, args []any) Event {
+		return SubscribersEvent{m, arg[string](args, 0), arg[bool](args, 1)}
+	}},
+	"r9kbeta": {2, func(m EventMeta, args []any) Event {
+		return R9kbetaEvent{m, arg[string](args, 0), arg[bool](args, 1)}
+	}},
+	"connected": {2, func(m EventMeta, args []any) Event {
+		return ConnectedEvent{m, arg[string](args, 0), arg[int](args, 1)}
+	}},
+	"connecting": {2, func(m EventMeta, args []any) Event {
+		return ConnectingEvent{m, arg[string](args, 0), arg[int](args, 1)}
+	}},
+	"disconnected": {1, func(m EventMeta, args []any) Event {
+		return DisconnectedEvent{m, arg[string](args, 0)}
+	}},
+	"logon": {0, func(m EventMeta, args []any) Event {
+		return LogonEvent{m}
+	}},
+	"reconnect": {0, func(m EventMeta, args []any) Event {
+		return ReconnectEvent{m}
+	}},
+	"ping": {0, func(m EventMeta, args []any) Event {
+		return PingEvent{m}
+	}},
+	"pong": {1, func(m EventMeta, args []any) Event {
+		return PongEvent{m, arg[float64](args, 0)}
+	}},
+	"degraded": {1, func(m EventMeta, args []any) Event {
+		return DegradedEvent{m, arg[HealthSnapshot](args, 0)}
+	}},
+	"recovered": {1, func(m EventMeta, args []any) Event {
+		return RecoveredEvent{m, arg[HealthSnapshot](args, 0)}
+	}},
+	"statechange": {3, func(m EventMeta, args []any) Event {
+		return StateChangeEvent{m, arg[ConnState](args, 0), arg[ConnState](args, 1), arg[DisconnectReason](args, 2)}
+	}},
+	"emotesets": {2, func(m EventMeta, args []any) Event {
+		return EmotesetsEvent{m, arg[string](args, 0), arg[map[string]any](args, 1)}
+	}},
+	"raw_message": {1, func(m EventMeta, args []any) Event {
+		return RawMessageEvent{m, arg[*IRCMessage](args, 0)}
+	}},
+	"announcement": {5, func(m EventMeta, args []any) Event {
+		return AnnouncementEvent{m, arg[string](args, 0), arg[ChatUserstate](args, 1), arg[string](args, 2), arg[bool](args, 3), arg[string](args, 4)}
+	}},
+	"names": {2, func(m EventMeta, args []any) Event {
+		return NamesEvent{m, arg[string](args, 0), arg[[]string](args, 1)}
+	}},
+	"usernotice": {4, func(m EventMeta, args []any) Event {
+		return UsernoticeEvent{m, arg[string](args, 0), arg[string](args, 1), arg[map[string]any](args, 2), arg[string](args, 3)}
+	}},
+	"globaluserstate": {1, func(m EventMeta, args []any) Event {
+		return GlobalUserstateEvent{m, arg[map[string]any](args, 0)}
+	}},
+	"maxreconnect": {0, func(m EventMeta, args []any) Event {
+		return MaxReconnectEvent{m}
+	}},
+	"error": {1, func(m EventMeta, args []any) Event {
+		return ErrorEvent{m, arg[error](args, 0)}
+	}},
+}
@@ -0,0 +1,69 @@
+package tmigo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOn_TypedEventFromMessage(t *testing.T) {
+	client := NewClient(nil)
+
+	var got TimeoutEvent
+	calls := 0
+	On(client, func(e TimeoutEvent) {
+		got = e
+		calls++
+	})
+
+	client.handleMessage(ParseMessage("@ban-duration=600;tmi-sent-ts=1700000000000 :tmi.twitch.tv CLEARCHAT #test :spammer"))
+
+	if calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+	if got.Channel != "#test" || got.Username != "spammer" || got.Duration != 600 {
+		t.Errorf("event = %+v, want spammer timed out in #test for 600s", got)
+	}
+	if got.Raw == nil || got.Raw.Command != "CLEARCHAT" {
+		t.Errorf("Raw = %+v, want the CLEARCHAT message", got.Raw)
+	}
+	if want := time.UnixMilli(1700000000000); !got.Sent.Equal(want) {
+		t.Errorf("Sent = %v, want %v", got.Sent, want)
+	}
+	if got.Received.IsZero() {
+		t.Error("Received is zero")
+	}
+}
+
+func TestOn_TypedEventFromEmit(t *testing.T) {
+	client := NewClient(nil)
+
+	var got MessageEvent
+	sub := On(client, func(e MessageEvent) { got = e })
+	client.Emit("message", "#a", ChatUserstate{Username: "viewer"}, "hello", true)
+
+	if got.Channel != "#a" || got.Userstate.Username != "viewer" || got.Message != "hello" || !got.Self {
+		t.Errorf("event = %+v, want hello from viewer in #a", got)
+	}
+	if got.Raw != nil || !got.Sent.IsZero() {
+		t.Errorf("Raw = %v, Sent = %v for an event without a message, want nil and zero", got.Raw, got.Sent)
+	}
+
+	// Too few arguments do not reach the handler
+	got = MessageEvent{}
+	client.Emit("message", "#a")
+	if got.Channel != "" {
+		t.Error("handler called with 1 of 4 arguments")
+	}
+
+	if !sub.Unsubscribe() {
+		t.Error("Unsubscribe() = false for a typed handler")
+	}
+}
+
+func TestEventDecoders_Names(t *testing.T) {
+	for eventType, decoder := range eventDecoders {
+		if name := decoder.decode(EventMeta{}, nil).Name(); name != eventType {
+			t.Errorf("decoder for %s builds an event named %s", eventType, name)
+		}
+	}
+}
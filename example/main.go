@@ -72,10 +72,10 @@ func setupEventHandlers(client *tmigo.Client) {
 	})
 
 	// Message event
-	// Note: tags is map[string]any, but for typed access you can convert to ChatUserstate
+	// Note: the tags are a ChatUserstate; tmigo.On(client, func(e tmigo.MessageEvent) {...}) avoids the assertions
 	client.On("message", func(args ...any) {
 		channel := args[0].(string)
-		tags := args[1].(map[string]any)
+		tags := args[1].(tmigo.ChatUserstate)
 		message := args[2].(string)
 		self := args[3].(bool)
 
@@ -84,18 +84,15 @@ func setupEventHandlers(client *tmigo.Client) {
 			return
 		}
 
-		username := ""
-		if val, ok := tags["username"].(string); ok {
-			username = val
-		}
+		username := tags.Username
 
 		// You can also check for specific fields from ChatUserstate:
-		// - tags["display-name"] for display name
-		// - tags["color"] for username color
-		// - tags["badges"] for user badges (map[string]string)
-		// - tags["subscriber"] for subscriber status (bool)
-		// - tags["mod"] for moderator status (bool)
-		// - tags["bits"] for bit amount in cheers (string)
+		// - tags.DisplayName for display name
+		// - tags.Color for username color
+		// - tags.Badges for user badges (map[string]string)
+		// - tags.Subscriber for subscriber status (bool)
+		// - tags.Mod for moderator status (bool)
+		// - tags.Bits for bit amount in cheers (string)
 
 		log.Printf("[%s] %s: %s", channel, username, message)
 
@@ -106,7 +103,7 @@ func setupEventHandlers(client *tmigo.Client) {
 	// Chat event (regular messages only)
 	client.On("chat", func(args ...any) {
 		channel := args[0].(string)
-		tags := args[1].(map[string]any)
+		tags := args[1].(tmigo.ChatUserstate)
 		message := args[2].(string)
 		self := args[3].(bool)
 
@@ -114,10 +111,7 @@ func setupEventHandlers(client *tmigo.Client) {
 			return
 		}
 
-		username := ""
-		if val, ok := tags["username"].(string); ok {
-			username = val
-		}
+		username := tags.Username
 
 		log.Printf("[CHAT] [%s] %s: %s", channel, username, message)
 	})
@@ -125,13 +119,10 @@ func setupEventHandlers(client *tmigo.Client) {
 	// Action event (/me messages)
 	client.On("action", func(args ...any) {
 		channel := args[0].(string)
-		tags := args[1].(map[string]any)
+		tags := args[1].(tmigo.ChatUserstate)
 		message := args[2].(string)
 
-		username := ""
-		if val, ok := tags["username"].(string); ok {
-			username = val
-		}
+		username := tags.Username
 
 		log.Printf("[ACTION] [%s] * %s %s", channel, username, message)
 	})
@@ -168,18 +159,12 @@ func setupEventHandlers(client *tmigo.Client) {
 	// Cheer event (bits)
 	client.On("cheer", func(args ...any) {
 		channel := args[0].(string)
-		tags := args[1].(map[string]any)
+		tags := args[1].(tmigo.ChatUserstate)
 		message := args[2].(string)
 
-		username := ""
-		if val, ok := tags["username"].(string); ok {
-			username = val
-		}
+		username := tags.Username
 
-		bits := ""
-		if val, ok := tags["bits"].(string); ok {
-			bits = val
-		}
+		bits := tags.Bits
 
 		log.Printf("[CHEER] %s cheered %s bits in %s: %s", username, bits, channel, message)
 	})
@@ -1,6 +1,7 @@
 package tmigo
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strconv"
@@ -8,14 +9,26 @@ import (
 	"time"
 )
 
-// handleMessage processes a parsed IRC message
+// handleMessage passes a parsed IRC message through the inbound middleware
+// and processes it
 func (c *Client) handleMessage(message *IRCMessage) {
 	if message == nil {
 		return
 	}
+	c.inbound(c.connContext(), message)
+}
+
+// processMessage processes a parsed IRC message that passed the inbound
+// middleware. Events caused by the message carry ctx.
+func (c *Client) processMessage(ctx context.Context, message *IRCMessage) {
+	if message == nil {
+		return
+	}
+	message.ctx = ctx
 
-	// Emit raw message event if anyone is listening
-	c.Emit("raw_message", message)
+	// Emit raw message event if anyone is listening. Async listeners get a
+	// copy, so the tag parsing below does not change it under them.
+	c.emitFrom(message, "raw_message", message)
 
 	channel := ""
 	if len(message.Params) > 0 {
@@ -75,19 +88,24 @@ func (c *Client) handleMessage(message *IRCMessage) {
 func (c *Client) handleNoPrefixMessage(message *IRCMessage) {
 	switch message.Command {
 	case "PING":
-		c.Emit("ping")
+		c.emitFrom(message, "ping")
 		if c.isConnected() {
-			c.state.ws.WriteMessage(1, []byte("PONG"))
+			c.write("PONG")
 		}
 
 	case "PONG":
+		c.mu.Lock()
 		c.state.currentLatency = time.Since(c.state.latency)
-		c.Emits([]string{"pong", "_promisePing"}, [][]any{
-			{c.state.currentLatency.Seconds()},
-		})
+		latency := c.state.currentLatency
 		if c.state.pingTimeout != nil {
 			c.state.pingTimeout.Stop()
 		}
+		c.mu.Unlock()
+
+		c.emitsFrom(message, []string{"pong", "_promisePing"}, [][]any{
+			{latency.Seconds()},
+		})
+		c.recordLatency(latency)
 	}
 }
 
@@ -96,79 +114,60 @@ func (c *Client) handleTwitchMessage(message *IRCMessage, channel, msg, msgid st
 	switch message.Command {
 	case "001":
 		if len(message.Params) > 0 {
+			c.mu.Lock()
 			c.state.username = message.Params[0]
+			c.mu.Unlock()
 		}
 
 	case "376":
 		// Connected to server
 		c.state.log.Info("Connected to server.")
-		c.state.userState[c.state.globalDefaultChannel] = UserState{}
-		c.Emits([]string{"connected", "_promiseConnect"}, [][]any{
-			{c.state.server, c.state.port},
-			{nil},
-		})
-		c.state.reconnections = 0
-		c.state.reconnectTimer = c.state.reconnectInterval
-
-		// Start ping loop
-		c.state.pingLoop = time.NewTicker(60 * time.Second)
-		go func() {
-			for range c.state.pingLoop.C {
-				if c.isConnected() {
-					c.state.ws.WriteMessage(1, []byte("PING"))
-				}
-				c.state.latency = time.Now()
-				c.state.pingTimeout = time.AfterFunc(c.state.opts.Connection.Timeout, func() {
-					if c.state.ws != nil {
-						c.state.wasCloseCalled = false
-						c.state.log.Error("Ping timeout.")
-						c.state.ws.Close()
-						if c.state.pingLoop != nil {
-							c.state.pingLoop.Stop()
-						}
-					}
-				})
-			}
-		}()
 
-		// Join channels
-		joinInterval := time.Duration(c.state.opts.Options.JoinInterval) * time.Millisecond
-		joinInterval = max(joinInterval, 300*time.Millisecond)
+		c.mu.Lock()
+		c.state.userState[c.state.globalDefaultChannel] = UserState{}
+		c.state.loggedOnAt = time.Now()
+		c.state.tokenRetry = false
+		change, _ := c.transition(StateConnected, "")
+		connCtx := c.state.connCtx
 
-		queue := NewQueue(joinInterval)
 		joinChannels := append([]string{}, c.state.opts.Channels...)
 		joinChannels = append(joinChannels, c.state.channels...)
 		c.state.channels = []string{}
+		unsent := c.state.unsent
+		c.state.unsent = nil
+		c.endReconnect()
+		c.mu.Unlock()
 
-		// Deduplicate channels
-		seen := make(map[string]bool)
-		uniqueChannels := []string{}
-		for _, ch := range joinChannels {
-			if !seen[ch] {
-				seen[ch] = true
-				uniqueChannels = append(uniqueChannels, ch)
-			}
+		c.announceState(change)
+		c.emitsFrom(message, []string{"connected", "_promiseConnect"}, [][]any{
+			{c.state.server, c.state.port},
+			{nil},
+		})
+
+		// Start the keepalive, which ends with the connection
+		if connCtx != nil {
+			go c.keepalive(connCtx.Done())
 		}
 
-		for _, ch := range uniqueChannels {
-			channel := ch
-			queue.Add(func() {
-				if c.isConnected() {
-					c.Join(channel)
-				}
-			})
+		// Send what was lost with the previous connection
+		if len(unsent) > 0 {
+			go c.flushUnsent(unsent)
 		}
 
-		queue.Next()
+		// Join channels
+		for _, channel := range joinChannels {
+			c.joins.add(channel)
+		}
+		c.joins.resume()
 
 	case "NOTICE":
-		c.handleNotice(channel, msgid, msg)
+		c.handleNotice(message, channel, msgid, msg)
 
 	case "USERNOTICE":
 		c.handleUserNotice(message, channel, msg, msgid)
 
 	case "HOSTTARGET":
-		c.handleHostTarget(channel, msg)
+		c.handleHostTarget(message, channel, msg)
 
 	case "CLEARCHAT":
 		c.handleClearChat(message, channel, msg)
@@ -182,19 +181,21 @@ func (c *Client) handleTwitchMessage(message *IRCMessage, channel, msg, msgid st
 			message.Tags["message-type"] = "messagedeleted"
 			c.state.log.Info(fmt.Sprintf("[%s] %s's message has been deleted.", channel, username))
 			userstate := convertToDeleteUserstate(message.Tags)
-			c.Emit("messagedeleted", channel, username, msg, userstate)
+			c.emitFrom(message, "messagedeleted", channel, username, msg, userstate)
 		}
 
 	case "RECONNECT":
 		c.state.log.Info("Received RECONNECT request from Twitch..")
-		c.state.log.Info(fmt.Sprintf("Disconnecting and reconnecting in %v..", c.state.reconnectTimer))
-		c.Disconnect()
-		time.AfterFunc(c.state.reconnectTimer, func() {
-			c.Connect()
-		})
+		if c.state.opts.Connection.SeamlessReconnect {
+			go c.handover()
+			return
+		}
+		c.dropConnection("Twitch requested a reconnect.")
 
 	case "USERSTATE":
-		message.Tags["username"] = c.state.username
+		c.mu.Lock()
+		username := c.state.username
+		message.Tags["username"] = username
 
 		// Add client to moderators if mod
 		if userType, ok := message.Tags["user-type"].(string); ok && userType == "mod" {
@@ -202,49 +203,70 @@ func (c *Client) handleTwitchMessage(message *IRCMessage, channel, msg, msgid st
 				c.state.moderators[channel] = []string{}
 			}
 
-			if !slices.Contains(c.state.moderators[channel], c.state.username) {
-				c.state.moderators[channel] = append(c.state.moderators[channel], c.state.username)
+			if !slices.Contains(c.state.moderators[channel], username) {
+				c.state.moderators[channel] = append(c.state.moderators[channel], username)
 			}
 		}
 
 		// Check if this is a join
-		if _, exists := c.state.userState[channel]; !exists && !IsJustinfan(c.GetUsername()) {
-			userstate := convertToUserState(message.Tags)
-			c.state.userState[channel] = userstate
+		_, exists := c.state.userState[channel]
+		joined := !exists && !IsJustinfan(username)
+		if joined {
 			c.state.lastJoined = channel
 			c.state.channels = append(c.state.channels, channel)
-			c.state.log.Info(fmt.Sprintf("Joined %s", channel))
-			c.Emit("join", channel, Username(c.GetUsername()), true)
 		}
 
 		// Check if emote-sets changed
-		if emoteSets, ok := message.Tags["emote-sets"].(string); ok && emoteSets != c.state.emotes {
+		emoteSets, ok := message.Tags["emote-sets"].(string)
+		emotesChanged := ok && emoteSets != c.state.emotes
+		if emotesChanged {
 			c.state.emotes = emoteSets
-			c.Emit("emotesets", c.state.emotes, nil)
 		}
 
-		userstate := convertToUserState(message.Tags)
-		c.state.userState[channel] = userstate
+		c.state.userState[channel] = convertToUserState(message.Tags)
+		c.mu.Unlock()
+
+		if joined {
+			c.state.log.Info(fmt.Sprintf("Joined %s", channel))
+			c.emitFrom(message, "join", channel, Username(username), true)
+		}
+		if emotesChanged {
+			c.emitFrom(message, "emotesets", emoteSets, nil)
+		}
 
 	case "GLOBALUSERSTATE":
+		c.mu.Lock()
 		c.state.globalUserState = convertToGlobalUserState(message.Tags)
-		c.Emit("globaluserstate", message.Tags)
-
-		if emoteSets, ok := message.Tags["emote-sets"].(string); ok && emoteSets != c.state.emotes {
+		emoteSets, ok := message.Tags["emote-sets"].(string)
+		emotesChanged := ok && emoteSets != c.state.emotes
+		if emotesChanged {
 			c.state.emotes = emoteSets
-			c.Emit("emotesets", c.state.emotes, nil)
 		}
+		c.mu.Unlock()
 
-	case "ROOMSTATE":
-		if Channel(c.state.lastJoined) == channel {
-			c.Emit("_promiseJoin", nil, channel)
+		c.emitFrom(message, "globaluserstate", message.Tags)
+		if emotesChanged {
+			c.emitFrom(message, "emotesets", emoteSets, nil)
 		}
 
+	case "ROOMSTATE":
 		message.Tags["channel"] = channel
-		roomstate := convertToRoomState(message.Tags)
-		c.Emit("roomstate", channel, roomstate)
+		normalizeRoomStateTags(message.Tags)
+
+		c.mu.Lock()
+		lastJoined := c.state.lastJoined
+		previous, known := c.state.roomStates[channel]
+		roomstate := mergeRoomState(previous, message.Tags)
+		c.state.roomStates[channel] = roomstate
+		c.mu.Unlock()
+
+		if Channel(lastJoined) == channel {
+			c.emitFrom(message, "_promiseJoin", nil, channel)
+		}
+
+		c.emitFrom(message, "roomstate", channel, roomstate)
 
-		c.handleRoomState(message, channel)
+		c.handleRoomState(message, channel, previous, roomstate, known)
 	}
 }
 
@@ -298,6 +320,7 @@ func (c *Client) handleJTVMessage(message *IRCMessage, channel, msg string) {
 
 		if msg == "+o" {
 			// Add to moderators
+			c.mu.Lock()
 			if c.state.moderators[channel] == nil {
 				c.state.moderators[channel] = []string{}
 			}
@@ -311,9 +334,11 @@ func (c *Client) handleJTVMessage(message *IRCMessage, channel, msg string) {
 			if !found {
 				c.state.moderators[channel] = append(c.state.moderators[channel], username)
 			}
-			c.Emit("mod", channel, username)
+			c.mu.Unlock()
+			c.emitFrom(message, "mod", channel, username)
 		} else if msg == "-o" {
 			// Remove from moderators
+			c.mu.Lock()
 			if c.state.moderators[channel] != nil {
 				newMods := []string{}
 				for _, mod := range c.state.moderators[channel] {
@@ -323,7 +348,8 @@ func (c *Client) handleJTVMessage(message *IRCMessage, channel, msg string) {
 				}
 				c.state.moderators[channel] = newMods
 			}
-			c.Emit("unmod", channel, username)
+			c.mu.Unlock()
+			c.emitFrom(message, "unmod", channel, username)
 		}
 	}
 }
@@ -337,16 +363,21 @@ func (c *Client) handleUserMessage(message *IRCMessage, channel, msg string) {
 			return
 		}
 		nick := parts[0]
-		matchesUsername := c.state.username == nick
-		isSelfAnon := matchesUsername && IsJustinfan(c.GetUsername())
 
+		c.mu.Lock()
+		matchesUsername := c.state.username == nick
+		isSelfAnon := matchesUsername && IsJustinfan(c.state.username)
 		if isSelfAnon {
 			c.state.lastJoined = channel
 			c.state.channels = append(c.state.channels, channel)
+		}
+		c.mu.Unlock()
+
+		if isSelfAnon {
 			c.state.log.Info(fmt.Sprintf("Joined %s", channel))
-			c.Emit("join", channel, nick, true)
+			c.emitFrom(message, "join", channel, nick, true)
 		} else if !matchesUsername {
-			c.Emit("join", channel, nick, false)
+			c.emitFrom(message, "join", channel, nick, false)
 		}
 
 	case "PART":
@@ -355,10 +386,12 @@ func (c *Client) handleUserMessage(message *IRCMessage, channel, msg string) {
 			return
 		}
 		nick := parts[0]
-		isSelf := c.state.username == nick
 
+		c.mu.Lock()
+		isSelf := c.state.username == nick
 		if isSelf {
 			delete(c.state.userState, channel)
+			delete(c.state.roomStates, channel)
 
 			// Remove from channels
 			newChannels := []string{}
@@ -377,12 +410,15 @@ func (c *Client) handleUserMessage(message *IRCMessage, channel, msg string) {
 				}
 			}
 			c.state.opts.Channels = newOptsChannels
+		}
+		c.mu.Unlock()
 
+		if isSelf {
 			c.state.log.Info(fmt.Sprintf("Left %s", channel))
-			c.Emit("_promisePart", nil)
+			c.emitFrom(message, "_promisePart", nil, channel)
 		}
 
-		c.Emit("part", channel, nick, isSelf)
+		c.emitFrom(message, "part", channel, nick, isSelf)
 
 	case "WHISPER":
 		parts := strings.Split(message.Prefix, "!")
@@ -397,7 +433,7 @@ func (c *Client) handleUserMessage(message *IRCMessage, channel, msg string) {
 
 		from := Channel(nick)
 		userstate := convertToChatUserstate(message.Tags)
-		c.Emits([]string{"whisper", "message"}, [][]any{
+		c.emitsFrom(message, []string{"whisper", "message"}, [][]any{
 			{from, userstate, msg, false},
 		})
 
@@ -414,7 +450,7 @@ func (c *Client) handleUserMessage(message *IRCMessage, channel, msg string) {
 			message.Tags["message-type"] = "action"
 			c.state.log.Info(fmt.Sprintf("[%s] *<%s>: %s", channel, message.Tags["username"], actionMsg))
 			userstate := convertToChatUserstate(message.Tags)
-			c.Emits([]string{"action", "message"}, [][]any{
+			c.emitsFrom(message, []string{"action", "message"}, [][]any{
 				{channel, userstate, actionMsg, false},
 			})
 		} else {
@@ -423,22 +459,22 @@ func (c *Client) handleUserMessage(message *IRCMessage, channel, msg string) {
 			// Check for bits
 			if _, hasBits := message.Tags["bits"]; hasBits {
 				userstate := convertToChatUserstate(message.Tags)
-				c.Emit("cheer", channel, userstate, msg)
+				c.emitFrom(message, "cheer", channel, userstate, msg)
 			} else {
 				// Check for channel point redemptions
 				if msgID, ok := message.Tags["msg-id"].(string); ok {
 					if msgID == "highlighted-message" || msgID == "skip-subs-mode-message" {
 						userstate := convertToChatUserstate(message.Tags)
-						c.Emit("redeem", channel, message.Tags["username"], msgID, userstate, msg)
+						c.emitFrom(message, "redeem", channel, message.Tags["username"], msgID, userstate, msg)
 					}
 				} else if rewardID, ok := message.Tags["custom-reward-id"].(string); ok {
 					userstate := convertToChatUserstate(message.Tags)
-					c.Emit("redeem", channel, message.Tags["username"], rewardID, userstate, msg)
+					c.emitFrom(message, "redeem", channel, message.Tags["username"], rewardID, userstate, msg)
 				}
 
 				c.state.log.Info(fmt.Sprintf("[%s] <%s>: %s", channel, message.Tags["username"], msg))
 				userstate := convertToChatUserstate(message.Tags)
-				c.Emits([]string{"chat", "message"}, [][]any{
+				c.emitsFrom(message, []string{"chat", "message"}, [][]any{
 					{channel, userstate, msg, false},
 				})
 			}
@@ -447,62 +483,191 @@ func (c *Client) handleUserMessage(message *IRCMessage, channel, msg string) {
 	case "353": // Names list
 		if len(message.Params) >= 4 {
 			names := strings.Split(message.Params[3], " ")
-			c.Emit("names", message.Params[2], names)
+			c.emitFrom(message, "names", message.Params[2], names)
 		}
 	}
 }
 
-// handleRoomState processes ROOMSTATE changes
-func (c *Client) handleRoomState(message *IRCMessage, channel string) {
-	// Check for slow mode
-	if slow, ok := message.Tags["slow"]; ok {
-		if slowBool, isBool := slow.(bool); isBool && !slowBool {
-			c.state.log.Info(fmt.Sprintf("[%s] This room is no longer in slow mode.", channel))
-			c.Emits([]string{"slow", "slowmode", "_promiseSlowoff"}, [][]any{
-				{channel, false, 0},
-				{channel, false, 0},
-				{nil},
-			})
-		} else if slowStr, isStr := slow.(string); isStr {
-			seconds := ParseInt(slowStr)
+// handleRoomState resolves pending mode commands and emits an event for
+// every mode that differs from the last known state. The initial ROOMSTATE
+// after joining is only delivered through the roomstate event.
+func (c *Client) handleRoomState(message *IRCMessage, channel string, previous, current RoomState, known bool) {
+	slow, seconds := current.slowMode()
+	if _, ok := message.Tags["slow"]; ok {
+		if slow {
+			c.emitFrom(message, "_promiseSlow", nil, channel)
+		} else {
+			c.emitFrom(message, "_promiseSlowoff", nil, channel)
+		}
+	}
+
+	followers, minutes := current.followersMode()
+	if _, ok := message.Tags["followers-only"]; ok {
+		if followers {
+			c.emitFrom(message, "_promiseFollowers", nil, channel)
+		} else {
+			c.emitFrom(message, "_promiseFollowersoff", nil, channel)
+		}
+	}
+
+	if !known {
+		return
+	}
+
+	if current.EmoteOnly != previous.EmoteOnly {
+		if current.EmoteOnly {
+			c.state.log.Info(fmt.Sprintf("[%s] This room is now in emote-only mode.", channel))
+		} else {
+			c.state.log.Info(fmt.Sprintf("[%s] This room is no longer in emote-only mode.", channel))
+		}
+		c.emitFrom(message, "emoteonly", channel, current.EmoteOnly)
+	}
+
+	if current.SubsOnly != previous.SubsOnly {
+		if current.SubsOnly {
+			c.state.log.Info(fmt.Sprintf("[%s] This room is now in subscribers-only mode.", channel))
+		} else {
+			c.state.log.Info(fmt.Sprintf("[%s] This room is no longer in subscribers-only mode.", channel))
+		}
+		c.emitsFrom(message, []string{"subscriber", "subscribers"}, [][]any{{channel, current.SubsOnly}})
+	}
+
+	if current.R9K != previous.R9K {
+		if current.R9K {
+			c.state.log.Info(fmt.Sprintf("[%s] This room is now in r9k mode.", channel))
+		} else {
+			c.state.log.Info(fmt.Sprintf("[%s] This room is no longer in r9k mode.", channel))
+		}
+		c.emitsFrom(message, []string{"r9kmode", "r9kbeta"}, [][]any{{channel, current.R9K}})
+	}
+
+	if current.Slow != previous.Slow {
+		if slow {
 			c.state.log.Info(fmt.Sprintf("[%s] This room is now in slow mode.", channel))
-			c.Emits([]string{"slow", "slowmode", "_promiseSlow"}, [][]any{
-				{channel, true, seconds},
-				{channel, true, seconds},
-				{nil},
-			})
+		} else {
+			c.state.log.Info(fmt.Sprintf("[%s] This room is no longer in slow mode.", channel))
 		}
+		c.emitsFrom(message, []string{"slow", "slowmode"}, [][]any{{channel, slow, seconds}})
 	}
 
-	// Check for followers-only mode
-	if followers, ok := message.Tags["followers-only"]; ok {
-		if followersStr, isStr := followers.(string); isStr {
-			if followersStr == "-1" {
-				c.state.log.Info(fmt.Sprintf("[%s] This room is no longer in followers-only mode.", channel))
-				c.Emits([]string{"followersonly", "followersmode", "_promiseFollowersoff"}, [][]any{
-					{channel, false, 0},
-					{channel, false, 0},
-					{nil},
-				})
-			} else {
-				minutes := ParseInt(followersStr)
-				c.state.log.Info(fmt.Sprintf("[%s] This room is now in follower-only mode.", channel))
-				c.Emits([]string{"followersonly", "followersmode", "_promiseFollowers"}, [][]any{
-					{channel, true, minutes},
-					{channel, true, minutes},
-					{nil},
-				})
-			}
+	if current.FollowersOnly != previous.FollowersOnly {
+		if followers {
+			c.state.log.Info(fmt.Sprintf("[%s] This room is now in follower-only mode.", channel))
+		} else {
+			c.state.log.Info(fmt.Sprintf("[%s] This room is no longer in followers-only mode.", channel))
 		}
+		c.emitsFrom(message, []string{"followersonly", "followersmode"}, [][]any{{channel, followers, minutes}})
 	}
 }
 
-// handleNotice processes NOTICE messages (stub - would need full implementation)
-func (c *Client) handleNotice(channel, msgid, msg string) {
-	// This would contain all the notice handling from the original
-	// For brevity, I'm including just a few key ones
+// handleNotice processes NOTICE messages
+func (c *Client) handleNotice(message *IRCMessage, channel, msgid, msg string) {
+	id := MsgID(msgid)
+
+	c.resolveNoticePromises(channel, id, msg)
+
+	switch id {
+	// Room mode changes are reported by ROOMSTATE, which also carries the
+	// slow and followers-only delays. HOSTTARGET already covers hosting.
+	case MsgIDSubsOn, MsgIDSubsOff, MsgIDEmoteOnlyOn, MsgIDEmoteOnlyOff, MsgIDR9kOn, MsgIDR9kOff,
+		MsgIDSlowOn, MsgIDSlowOff, MsgIDFollowersOn, MsgIDFollowersOnZero, MsgIDFollowersOff, MsgIDHostOn, MsgIDHostOff:
+
+	// Mods and VIPs lists
+	case MsgIDRoomMods, MsgIDNoMods:
+		mods := []string{}
+		if id == MsgIDRoomMods {
+			mods = parseNoticeList(msg)
+		}
+		c.mu.Lock()
+		c.state.moderators[channel] = mods
+		c.mu.Unlock()
+		c.emitFrom(message, "mods", channel, mods)
+	case MsgIDVipsSuccess, MsgIDNoVips:
+		vips := []string{}
+		if id == MsgIDVipsSuccess {
+			vips = parseNoticeList(msg)
+		}
+		c.emitFrom(message, "vips", channel, vips)
+
+	// Rejected chat messages
+	case MsgIDMsgRatelimit:
+		c.emitNotice(message, channel, id, msg, "msgratelimit")
+	case MsgIDMsgDuplicate:
+		c.emitNotice(message, channel, id, msg, "msgduplicate")
+	case MsgIDMsgBanned:
+		c.emitNotice(message, channel, id, msg, "msgbanned")
+	case MsgIDMsgSubsonly:
+		c.emitNotice(message, channel, id, msg, "msgsubsonly")
+	case MsgIDMsgEmoteonly:
+		c.emitNotice(message, channel, id, msg, "msgemoteonly")
+	case MsgIDMsgChannelSuspended:
+		c.emitNotice(message, channel, id, msg, "channelsuspended")
+	case MsgIDWhisperLimitPerMin, MsgIDWhisperLimitPerSec:
+		c.emitNotice(message, channel, id, msg, "whisperlimit")
+	case MsgIDMsgRejected, MsgIDMsgRejectedMandatory:
+		c.emitNotice(message, channel, id, msg, "automod")
+
+	case "":
+		// Authentication failures come without a msg-id
+		if isAuthFailure(msg) {
+			c.handleAuthFailure(message, msg)
+			return
+		}
+		c.state.log.Warn(fmt.Sprintf("Could not parse NOTICE from tmi.twitch.tv: %s", msg))
+		c.emitNotice(message, channel, id, msg)
+
+	default:
+		c.emitNotice(message, channel, id, msg)
+	}
+}
+
+// emitNotice logs a NOTICE and emits it as "notice" plus any dedicated events
+func (c *Client) emitNotice(message *IRCMessage, channel string, msgid MsgID, msg string, events ...string) {
 	c.state.log.Info(fmt.Sprintf("[%s] %s", channel, msg))
-	c.Emit("notice", channel, msgid, msg)
+
+	types := append([]string{"notice"}, events...)
+	c.emitsFrom(message, types, [][]any{{channel, msgid, msg}})
+}
+
+// isAuthFailure reports whether a NOTICE rejects the login
+func isAuthFailure(msg string) bool {
+	for _, text := range []string{
+		"Login unsuccessful",
+		"Login authentication failed",
+		"Error logging in",
+		"Improperly formatted auth",
+		"Invalid NICK",
+	} {
+		if strings.Contains(msg, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAuthFailure stops reconnecting and closes the connection, since
+// retrying with the same credentials would fail the same way. With a
+// TokenProvider the token is refreshed first and the client reconnects once.
+func (c *Client) handleAuthFailure(message *IRCMessage, msg string) {
+	c.state.log.Error(msg)
+	retry := c.refreshToken()
+
+	c.mu.Lock()
+	if !retry {
+		c.state.reconnect = false
+	}
+	c.state.closeReason = DisconnectAuthFailed
+	c.state.closeMessage = msg
+	conn := c.state.conn
+	c.mu.Unlock()
+
+	if !retry {
+		c.emitFrom(message, "authfailed", msg)
+	}
+
+	if conn != nil {
+		conn.Close()
+	}
 }
 
 // handleUserNotice processes USERNOTICE messages for subs, raids, etc.
@@ -520,40 +685,107 @@ func (c *Client) handleUserNotice(message *IRCMessage, channel, msg, msgid strin
 	case "sub":
 		methods := convertToSubMethods(message.Tags)
 		userstate := convertToSubUserstate(message.Tags)
-		c.Emits([]string{"subscription", "sub"}, [][]any{
+		c.emitsFrom(message, []string{"subscription", "sub"}, [][]any{
 			{channel, username, methods, msg, userstate},
 		})
 
 	case "resub":
-		streakMonths := 0
-		if val, ok := message.Tags["msg-param-streak-months"].(string); ok {
-			streakMonths = ParseInt(val)
-		}
+		streakMonths := tagInt(message.Tags, "msg-param-streak-months")
 		methods := convertToSubMethods(message.Tags)
 		userstate := convertToSubUserstate(message.Tags)
-		c.Emits([]string{"resub", "subanniversary"}, [][]any{
+		c.emitsFrom(message, []string{"resub", "subanniversary"}, [][]any{
 			{channel, username, streakMonths, msg, userstate, methods},
 		})
 
-	case "subgift":
-		streakMonths := 0
-		if val, ok := message.Tags["msg-param-months"].(string); ok {
-			streakMonths = ParseInt(val)
-		}
+	case "subgift", "anonsubgift":
+		streakMonths := tagInt(message.Tags, "msg-param-months")
 		recipient := ""
 		if val, ok := message.Tags["msg-param-recipient-display-name"].(string); ok {
 			recipient = val
+		} else if val, ok := message.Tags["msg-param-recipient-user-name"].(string); ok {
+			recipient = val
+		}
+		methods := convertToSubMethods(message.Tags)
+
+		if msgid == "anonsubgift" || isAnonymousGifter(message.Tags) {
+			message.Tags["message-type"] = "anonsubgift"
+			userstate := convertToAnonSubGiftUserstate(message.Tags)
+			c.emitFrom(message, "anonsubgift", channel, streakMonths, recipient, methods, userstate)
+		} else {
+			userstate := convertToSubGiftUserstate(message.Tags)
+			c.emitFrom(message, "subgift", channel, username, streakMonths, recipient, methods, userstate)
 		}
+
+	case "submysterygift", "anonsubmysterygift":
+		numbOfSubs := tagInt(message.Tags, "msg-param-mass-gift-count")
+		methods := convertToSubMethods(message.Tags)
+
+		if msgid == "anonsubmysterygift" || isAnonymousGifter(message.Tags) {
+			message.Tags["message-type"] = "anonsubmysterygift"
+			userstate := convertToAnonSubMysteryGiftUserstate(message.Tags)
+			c.emitFrom(message, "anonsubmysterygift", channel, numbOfSubs, methods, userstate)
+		} else {
+			userstate := convertToSubMysteryGiftUserstate(message.Tags)
+			c.emitFrom(message, "submysterygift", channel, username, numbOfSubs, methods, userstate)
+		}
+
+	case "giftpaidupgrade":
+		sender := ""
+		if val, ok := message.Tags["msg-param-sender-name"].(string); ok {
+			sender = val
+		} else if val, ok := message.Tags["msg-param-sender-login"].(string); ok {
+			sender = val
+		}
+		userstate := convertToSubGiftUpgradeUserstate(message.Tags)
+		c.emitFrom(message, "giftpaidupgrade", channel, username, sender, userstate)
+
+	case "anongiftpaidupgrade":
+		userstate := convertToAnonSubGiftUpgradeUserstate(message.Tags)
+		c.emitFrom(message, "anongiftpaidupgrade", channel, username, userstate)
+
+	case "primepaidupgrade":
 		methods := convertToSubMethods(message.Tags)
-		userstate := convertToSubGiftUserstate(message.Tags)
-		c.Emit("subgift", channel, username, streakMonths, recipient, methods, userstate)
+		userstate := convertToPrimeUpgradeUserstate(message.Tags)
+		c.emitFrom(message, "primepaidupgrade", channel, username, methods, userstate)
 
 	case "raid":
-		viewers := 0
-		if val, ok := message.Tags["msg-param-viewerCount"].(string); ok {
-			viewers = ParseInt(val)
+		viewers := tagInt(message.Tags, "msg-param-viewerCount")
+		userstate := convertToRaidUserstate(message.Tags)
+		c.emitFrom(message, "raided", channel, username, viewers, userstate)
+
+	case "unraid":
+		userstate := convertToUnraidUserstate(message.Tags)
+		c.emitFrom(message, "unraid", channel, userstate)
+
+	case "ritual":
+		ritualName := ""
+		if val, ok := message.Tags["msg-param-ritual-name"].(string); ok {
+			ritualName = val
 		}
-		c.Emit("raided", channel, username, viewers)
+		userstate := convertToRitualUserstate(message.Tags)
+		c.emitFrom(message, "ritual", channel, username, ritualName, msg, userstate)
+
+	case "bitsbadgetier":
+		threshold := tagInt(message.Tags, "msg-param-threshold")
+		userstate := convertToBitsBadgeTierUserstate(message.Tags)
+		c.emitFrom(message, "bitsbadgetier", channel, username, threshold, msg, userstate)
+
+	case "viewermilestone":
+		category := ""
+		if val, ok := message.Tags["msg-param-category"].(string); ok {
+			category = val
+		}
+		value := tagInt(message.Tags, "msg-param-value")
+		userstate := convertToViewerMilestoneUserstate(message.Tags)
+		c.emitFrom(message, "viewermilestone", channel, username, category, value, msg, userstate)
+
+	case "sharedchatnotice":
+		sourceMsgID := ""
+		if val, ok := message.Tags["source-msg-id"].(string); ok {
+			sourceMsgID = val
+		}
+		userstate := convertToSharedChatNoticeUserstate(message.Tags)
+		c.emitFrom(message, "sharedchatnotice", channel, sourceMsgID, msg, userstate)
 
 	case "announcement":
 		color := ""
@@ -561,15 +793,29 @@ func (c *Client) handleUserNotice(message *IRCMessage, channel, msg, msgid strin
 			color = val
 		}
 		userstate := convertToChatUserstate(message.Tags)
-		c.Emit("announcement", channel, userstate, msg, false, color)
+		c.emitFrom(message, "announcement", channel, userstate, msg, false, color)
 
 	default:
-		c.Emit("usernotice", msgid, channel, message.Tags, msg)
+		c.emitFrom(message, "usernotice", msgid, channel, message.Tags, msg)
 	}
 }
 
+// isAnonymousGifter reports whether a gift was sent anonymously. Twitch
+// reports those as regular gifts from the "ananonymousgifter" account.
+func isAnonymousGifter(tags map[string]any) bool {
+	login, _ := tags["login"].(string)
+	displayName, _ := tags["display-name"].(string)
+	return strings.EqualFold(login, "ananonymousgifter") || strings.EqualFold(displayName, "AnAnonymousGifter")
+}
+
+// tagInt reads a numeric tag
+func tagInt(tags map[string]any, key string) int {
+	v, _ := tagCount(tags, key)
+	return ParseInt(v)
+}
+
 // handleHostTarget processes host/unhost messages
-func (c *Client) handleHostTarget(channel, msg string) {
+func (c *Client) handleHostTarget(message *IRCMessage, channel, msg string) {
 	parts := strings.Split(msg, " ")
 	if len(parts) < 1 {
 		return
@@ -582,13 +828,13 @@ func (c *Client) handleHostTarget(channel, msg string) {
 
 	if parts[0] == "-" {
 		c.state.log.Info(fmt.Sprintf("[%s] Exited host mode.", channel))
-		c.Emits([]string{"unhost", "_promiseUnhost"}, [][]any{
+		c.emitsFrom(message, []string{"unhost", "_promiseUnhost"}, [][]any{
 			{channel, viewers},
-			{nil},
+			{nil, channel},
 		})
 	} else {
 		c.state.log.Info(fmt.Sprintf("[%s] Now hosting %s for %d viewer(s).", channel, parts[0], viewers))
-		c.Emit("hosting", channel, parts[0], viewers)
+		c.emitFrom(message, "hosting", channel, parts[0], viewers)
 	}
 }
 
@@ -604,19 +850,19 @@ func (c *Client) handleClearChat(message *IRCMessage, channel, msg string) {
 		if duration == "" {
 			c.state.log.Info(fmt.Sprintf("[%s] %s has been banned.", channel, msg))
 			userstate := convertToBanUserstate(message.Tags)
-			c.Emit("ban", channel, msg, "", userstate)
+			c.emitFrom(message, "ban", channel, msg, "", userstate)
 		} else {
 			durationInt, _ := strconv.Atoi(duration)
 			c.state.log.Info(fmt.Sprintf("[%s] %s has been timed out for %d seconds.", channel, msg, durationInt))
 			userstate := convertToTimeoutUserstate(message.Tags)
-			c.Emit("timeout", channel, msg, "", durationInt, userstate)
+			c.emitFrom(message, "timeout", channel, msg, "", durationInt, userstate)
 		}
 	} else {
 		// Chat cleared
 		c.state.log.Info(fmt.Sprintf("[%s] Chat was cleared by a moderator.", channel))
-		c.Emits([]string{"clearchat", "_promiseClear"}, [][]any{
+		c.emitsFrom(message, []string{"clearchat", "_promiseClear"}, [][]any{
 			{channel},
-			{nil},
+			{nil, channel},
 		})
 	}
 }
@@ -0,0 +1,155 @@
+package tmigo
+
+import "testing"
+
+func TestHandleUserNotice_AnonymousSubGift(t *testing.T) {
+	client := NewClient(nil)
+
+	subgiftCalled := false
+	client.OnSubGift(func(channel string, username string, streakMonths int, recipient string, methods SubMethods, userstate SubGiftUserstate) {
+		subgiftCalled = true
+	})
+
+	var gotRecipient string
+	var gotMonths int
+	client.OnAnonSubGift(func(channel string, streakMonths int, recipient string, methods SubMethods, userstate AnonSubGiftUserstate) {
+		gotRecipient = recipient
+		gotMonths = streakMonths
+	})
+
+	client.handleMessage(ParseMessage("@msg-id=subgift;login=ananonymousgifter;display-name=AnAnonymousGifter;msg-param-months=1;msg-param-recipient-display-name=Viewer;msg-param-sub-plan=1000 :tmi.twitch.tv USERNOTICE #test"))
+
+	if subgiftCalled {
+		t.Error("subgift should not fire for an anonymous gifter")
+	}
+	if gotRecipient != "Viewer" {
+		t.Errorf("recipient = %q, want Viewer", gotRecipient)
+	}
+	if gotMonths != 1 {
+		t.Errorf("streakMonths = %d, want 1", gotMonths)
+	}
+}
+
+func TestHandleUserNotice_SubMysteryGift(t *testing.T) {
+	client := NewClient(nil)
+
+	var gotUser string
+	var gotCount int
+	var gotState SubMysteryGiftUserstate
+	client.OnSubMysteryGift(func(channel string, username string, numbOfSubs int, methods SubMethods, userstate SubMysteryGiftUserstate) {
+		gotUser = username
+		gotCount = numbOfSubs
+		gotState = userstate
+	})
+
+	client.handleMessage(ParseMessage("@msg-id=submysterygift;login=gifter;display-name=Gifter;msg-param-mass-gift-count=5;msg-param-sub-plan=1000 :tmi.twitch.tv USERNOTICE #test"))
+
+	if gotUser != "Gifter" || gotCount != 5 {
+		t.Errorf("submysterygift = (%q, %d), want (Gifter, 5)", gotUser, gotCount)
+	}
+	if gotState.MsgParamMassGiftCount != "5" {
+		t.Errorf("MsgParamMassGiftCount = %q, want 5", gotState.MsgParamMassGiftCount)
+	}
+}
+
+func TestHandleUserNotice_CountOfOne(t *testing.T) {
+	client := NewClient(nil)
+
+	// A tag holding "1" is turned into true before the userstates are built
+	var mystery SubMysteryGiftUserstate
+	client.OnSubMysteryGift(func(channel string, username string, numbOfSubs int, methods SubMethods, userstate SubMysteryGiftUserstate) {
+		mystery = userstate
+	})
+	var milestone ViewerMilestoneUserstate
+	client.OnViewerMilestone(func(channel string, username string, category string, value int, message string, userstate ViewerMilestoneUserstate) {
+		milestone = userstate
+	})
+
+	client.handleMessage(ParseMessage("@msg-id=submysterygift;login=gifter;display-name=Gifter;msg-param-mass-gift-count=1;msg-param-sender-count=1;msg-param-sub-plan=1000 :tmi.twitch.tv USERNOTICE #test"))
+	client.handleMessage(ParseMessage("@msg-id=viewermilestone;login=viewer;display-name=Viewer;msg-param-category=watch-streak;msg-param-value=1 :tmi.twitch.tv USERNOTICE #test"))
+
+	if mystery.MsgParamMassGiftCount != "1" || mystery.MsgParamSenderCount != "1" {
+		t.Errorf("counts = (%q, %q), want (1, 1)", mystery.MsgParamMassGiftCount, mystery.MsgParamSenderCount)
+	}
+	if milestone.MsgParamValue != "1" {
+		t.Errorf("MsgParamValue = %q, want 1", milestone.MsgParamValue)
+	}
+}
+
+func TestHandleUserNotice_Variants(t *testing.T) {
+	tests := []struct {
+		msgid string
+		event string
+	}{
+		{"giftpaidupgrade", "giftpaidupgrade"},
+		{"anongiftpaidupgrade", "anongiftpaidupgrade"},
+		{"primepaidupgrade", "primepaidupgrade"},
+		{"ritual", "ritual"},
+		{"bitsbadgetier", "bitsbadgetier"},
+		{"unraid", "unraid"},
+		{"viewermilestone", "viewermilestone"},
+		{"sharedchatnotice", "sharedchatnotice"},
+		{"somethingnew", "usernotice"},
+	}
+
+	for _, tt := range tests {
+		client := NewClient(nil)
+
+		called := false
+		client.On(tt.event, func(args ...any) {
+			called = true
+		})
+
+		client.handleMessage(ParseMessage("@msg-id=" + tt.msgid + ";login=user :tmi.twitch.tv USERNOTICE #test :hello"))
+
+		if !called {
+			t.Errorf("msg-id %s did not emit %s", tt.msgid, tt.event)
+		}
+	}
+}
+
+func TestHandleUserNotice_ViewerMilestone(t *testing.T) {
+	client := NewClient(nil)
+
+	var gotCategory string
+	var gotValue int
+	client.OnViewerMilestone(func(channel string, username string, category string, value int, message string, userstate ViewerMilestoneUserstate) {
+		gotCategory = category
+		gotValue = value
+	})
+
+	client.handleMessage(ParseMessage("@msg-id=viewermilestone;login=user;msg-param-category=watch-streak;msg-param-value=7 :tmi.twitch.tv USERNOTICE #test"))
+
+	if gotCategory != "watch-streak" || gotValue != 7 {
+		t.Errorf("viewermilestone = (%q, %d), want (watch-streak, 7)", gotCategory, gotValue)
+	}
+}
+
+func TestHandleMessage_RawMessageTags(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		client := NewClient(&ClientOptions{Dispatch: &Dispatch{Async: async}})
+
+		// raw_message fires before the tags are parsed, with the values
+		// as received
+		got := make(chan any, 1)
+		client.On("raw_message", func(args ...any) {
+			got <- args[0].(*IRCMessage).Tags["subscriber"]
+		})
+		client.handleMessage(ParseMessage("@subscriber=1 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #a :hello"))
+
+		if value := <-got; value != "1" {
+			t.Errorf("async=%v: raw_message tag = %#v, want \"1\"", async, value)
+		}
+	}
+}
+
+func TestTagInt(t *testing.T) {
+	tags := map[string]any{"a": "12", "b": true, "c": false, "d": "x"}
+
+	tests := map[string]int{"a": 12, "b": 1, "c": 0, "d": 0, "missing": 0}
+	for key, want := range tests {
+		if got := tagInt(tags, key); got != want {
+			t.Errorf("tagInt(%q) = %d, want %d", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package tmigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// handoverState tracks a seamless reconnect. While the new connection joins,
+// the old one keeps delivering lines and records them in seen; lines that
+// the new connection received in that time are only delivered if the old
+// one did not deliver them already.
+type handoverState struct {
+	old     Conn
+	mu      sync.Mutex
+	seen    map[string]struct{}
+	swapped bool
+}
+
+// deliver reports whether a line read from the old connection should still
+// be handled, and records it so that the new connection does not repeat it
+func (h *handoverState) deliver(key string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.swapped {
+		return false
+	}
+	h.seen[key] = struct{}{}
+	return true
+}
+
+// swap stops delivery from the old connection and returns the lines it
+// delivered during the handover
+func (h *handoverState) swap() map[string]struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.swapped = true
+	return h.seen
+}
+
+// active reports whether the old connection is still in use
+func (h *handoverState) active() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.swapped
+}
+
+// lineKey identifies a line across connections: by its message id when it
+// has one, otherwise by its raw text
+func lineKey(raw string, message *IRCMessage) string {
+	if id, ok := message.Tags["id"].(string); ok && id != "" {
+		return "id:" + id
+	}
+	return raw
+}
+
+// pendingLine is a line read from the new connection during a handover
+type pendingLine struct {
+	raw     string
+	message *IRCMessage
+}
+
+// handover replaces the connection after a RECONNECT without a gap in chat.
+// The new connection logs in and joins every channel while the old one is
+// still open, then takes over. If the new connection cannot be set up, the
+// client falls back to a normal reconnect.
+func (c *Client) handover() {
+	c.mu.Lock()
+	old := c.state.conn
+	if h := c.handoff.Load(); old == nil || (h != nil && h.active()) {
+		c.mu.Unlock()
+		return
+	}
+	h := &handoverState{old: old, seen: make(map[string]struct{})}
+	c.handoff.Store(h)
+	channels := slices.Clone(c.state.channels)
+	c.mu.Unlock()
+
+	c.state.log.Info("Opening a new connection before closing the old one..")
+	conn, writer, lines, err := c.openStandby(channels)
+	if err != nil {
+		c.handoff.CompareAndSwap(h, nil)
+		c.state.log.Error(fmt.Sprintf("Seamless reconnect failed: %v", err))
+		c.dropConnection("Twitch requested a reconnect.")
+		return
+	}
+
+	c.mu.Lock()
+	if c.state.conn != old {
+		// The old connection was lost or closed in the meantime
+		c.mu.Unlock()
+		c.handoff.CompareAndSwap(h, nil)
+		writer.close()
+		conn.Close()
+		return
+	}
+
+	oldWriter := c.state.writer
+	oldCancel := c.state.connCancel
+	if c.state.pingTimeout != nil {
+		c.state.pingTimeout.Stop()
+		c.state.pingTimeout = nil
+	}
+	c.state.conn = conn
+	c.state.writer = writer
+	c.state.connCtx, c.state.connCancel = context.WithCancel(context.Background())
+	connCtx := c.state.connCtx
+	seen := h.swap()
+	unsent := c.state.unsent
+	c.state.unsent = nil
+	c.mu.Unlock()
+
+	oldCancel()
+	oldWriter.close()
+	old.Close()
+	c.state.log.Info("Switched to the new connection.")
+
+	if len(unsent) > 0 {
+		go c.flushUnsent(unsent)
+	}
+
+	writer.reading.Store(true)
+	for _, line := range lines {
+		if _, ok := seen[lineKey(line.raw, line.message)]; !ok {
+			c.handleMessage(line.message)
+		}
+	}
+	writer.reading.Store(false)
+
+	go c.keepalive(connCtx.Done())
+	c.handleMessages(conn, writer)
+}
+
+// openStandby opens a second connection, logs in and joins channels on it
+// without touching the client state. It returns the connection with its
+// writer and the lines that arrived after the login, which have not been
+// handled yet.
+func (c *Client) openStandby(channels []string) (Conn, *outboundWriter, []pendingLine, error) {
+	opts := c.state.opts.Connection
+
+	conn, err := c.dialStandby()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	writer := newOutboundWriter(conn, opts.WriteQueueSize, opts.WriteTimeout)
+
+	fail := func(err error) (Conn, *outboundWriter, []pendingLine, error) {
+		writer.close()
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	// Closing the connection ends a read that takes too long
+	watchdog := time.AfterFunc(opts.Timeout, func() { conn.Close() })
+	defer watchdog.Stop()
+
+	write := func(line string) error { return writer.write([]byte(line)) }
+	if err := c.sendLogin(write); err != nil {
+		return fail(err)
+	}
+
+	waiting := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		waiting[channel] = true
+	}
+
+	username := c.GetUsername()
+	loggedOn := false
+	var lines []pendingLine
+	for !loggedOn || len(waiting) > 0 {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			if !watchdog.Stop() {
+				err = errors.New("timed out")
+			}
+			return fail(err)
+		}
+
+		for raw := range strings.SplitSeq(strings.TrimSpace(string(data)), "\r\n") {
+			message := ParseMessage(raw)
+			if message == nil {
+				continue
+			}
+
+			channel := ""
+			if len(message.Params) > 0 {
+				channel = Channel(message.Params[0])
+			}
+
+			switch {
+			case message.Command == "PING":
+				write("PONG :tmi.twitch.tv")
+
+			case message.Command == "NOTICE" && !loggedOn:
+				return fail(fmt.Errorf("login rejected: %s", strings.Join(message.Params[1:], " ")))
+
+			case message.Command == "376":
+				loggedOn = true
+				watchdog.Reset(opts.Timeout)
+				go c.joinStandby(channels, write)
+
+			case !loggedOn:
+				// Login replies carry nothing the client does not know yet
+
+			case message.Command == "ROOMSTATE" && waiting[channel],
+				message.Command == "NOTICE" && waiting[channel]:
+				// A ROOMSTATE confirms the join, a NOTICE rejects it
+				delete(waiting, channel)
+				watchdog.Reset(opts.Timeout)
+
+			case message.Command == "USERSTATE" && waiting[channel],
+				message.Command == "JOIN" && strings.HasPrefix(message.Prefix, username+"!"),
+				message.Command == "353", message.Command == "366":
+				// Join replies for state the client already has
+
+			default:
+				lines = append(lines, pendingLine{raw: raw, message: message})
+			}
+		}
+	}
+
+	if !watchdog.Stop() {
+		return fail(errors.New("timed out"))
+	}
+	return conn, writer, lines, nil
+}
+
+// dialStandby dials the connection for a handover
+func (c *Client) dialStandby() (Conn, error) {
+	ctx := c.connContext()
+	if timeout := c.state.opts.Connection.HandshakeTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return c.state.opts.Connection.Transport.Dial(ctx, c.state.opts.Connection)
+}
+
+// joinStandby sends the JOINs for a handover within the join budget
+func (c *Client) joinStandby(channels []string, write func(line string) error) {
+	for _, channel := range channels {
+		if wait := c.joins.reserve(); wait > 0 {
+			time.Sleep(wait)
+		}
+		if err := write(fmt.Sprintf("JOIN %s", channel)); err != nil {
+			return
+		}
+	}
+}
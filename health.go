@@ -0,0 +1,157 @@
+package tmigo
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthSnapshot describes the connection's health at one moment. The
+// latency statistics cover the last Health.Window keepalive round trips,
+// across reconnects.
+type HealthSnapshot struct {
+	Connected bool
+	Degraded  bool          // The last round trip reached Health.DegradedLatency
+	Latency   time.Duration // Last round trip
+	Min       time.Duration
+	Avg       time.Duration
+	P95       time.Duration
+	Max       time.Duration
+	Samples   int       // Round trips the statistics are based on
+	LastPong  time.Time // Zero before the first PONG
+	Timeouts  int       // PINGs the server did not answer in time
+}
+
+// healthMonitor keeps the latency samples and the degraded state
+type healthMonitor struct {
+	opts *Health
+
+	mu       sync.Mutex
+	samples  []time.Duration // Ring buffer of the last Window round trips
+	next     int
+	latency  time.Duration
+	lastPong time.Time
+	timeouts int
+	degraded bool
+
+	loops atomic.Int32 // Running keepalive loops
+}
+
+// newHealthMonitor creates a monitor and applies the defaults of the health
+// options
+func newHealthMonitor(opts *ClientOptions) *healthMonitor {
+	health := opts.Health
+	health.PingInterval = cmp.Or(health.PingInterval, 60*time.Second)
+	health.PingTimeout = cmp.Or(health.PingTimeout, opts.Connection.Timeout)
+	health.Window = cmp.Or(health.Window, 100)
+
+	return &healthMonitor{
+		opts:    health,
+		samples: make([]time.Duration, 0, health.Window),
+	}
+}
+
+// record adds a round trip and reports whether the degraded state changed
+func (h *healthMonitor) record(latency time.Duration, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) < h.opts.Window {
+		h.samples = append(h.samples, latency)
+	} else {
+		h.samples[h.next] = latency
+	}
+	h.next = (h.next + 1) % h.opts.Window
+	h.latency = latency
+	h.lastPong = now
+
+	degraded := h.opts.DegradedLatency > 0 && latency >= h.opts.DegradedLatency
+	changed := degraded != h.degraded
+	h.degraded = degraded
+	return changed
+}
+
+// timedOut counts a PING that was not answered in time
+func (h *healthMonitor) timedOut() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timeouts++
+}
+
+// snapshot returns the statistics without the connection state
+func (h *healthMonitor) snapshot() HealthSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := HealthSnapshot{
+		Degraded: h.degraded,
+		Latency:  h.latency,
+		Samples:  len(h.samples),
+		LastPong: h.lastPong,
+		Timeouts: h.timeouts,
+	}
+	if len(h.samples) == 0 {
+		return s
+	}
+
+	sorted := slices.Clone(h.samples)
+	slices.Sort(sorted)
+	var total time.Duration
+	for _, sample := range sorted {
+		total += sample
+	}
+	s.Min = sorted[0]
+	s.Max = sorted[len(sorted)-1]
+	s.Avg = total / time.Duration(len(sorted))
+	s.P95 = sorted[(len(sorted)*95+99)/100-1]
+	return s
+}
+
+// Health returns a snapshot of the connection's health
+func (c *Client) Health() HealthSnapshot {
+	s := c.health.snapshot()
+	s.Connected = c.isConnected()
+	return s
+}
+
+// keepalive pings the server every Health.PingInterval until done is closed.
+// There is one loop per connection; it ends when the connection is closed
+// or replaced.
+func (c *Client) keepalive(done <-chan struct{}) {
+	c.health.loops.Add(1)
+	defer c.health.loops.Add(-1)
+
+	ticker := time.NewTicker(c.health.opts.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Armed first, so a fast PONG finds the send time and stops it
+			c.startPingTimeout()
+			c.write("PING")
+		case <-done:
+			return
+		}
+	}
+}
+
+// recordLatency adds a round trip to the statistics and emits degraded or
+// recovered when the latency crossed Health.DegradedLatency
+func (c *Client) recordLatency(latency time.Duration) {
+	if !c.health.record(latency, time.Now()) {
+		return
+	}
+
+	snapshot := c.Health()
+	if snapshot.Degraded {
+		c.state.log.Warn(fmt.Sprintf("Connection degraded: latency %v.", latency))
+		c.Emit("degraded", snapshot)
+	} else {
+		c.state.log.Info(fmt.Sprintf("Connection recovered: latency %v.", latency))
+		c.Emit("recovered", snapshot)
+	}
+}
@@ -0,0 +1,106 @@
+package tmigo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthMonitor_Stats(t *testing.T) {
+	h := newHealthMonitor(&ClientOptions{
+		Connection: &Connection{Timeout: time.Second},
+		Health:     &Health{Window: 20},
+	})
+
+	// 25 samples overflow the window: 6ms..25ms remain
+	for i := 1; i <= 25; i++ {
+		h.record(time.Duration(i)*time.Millisecond, time.Now())
+	}
+
+	s := h.snapshot()
+	want := HealthSnapshot{
+		Latency: 25 * time.Millisecond,
+		Min:     6 * time.Millisecond,
+		Avg:     15500 * time.Microsecond,
+		P95:     24 * time.Millisecond,
+		Max:     25 * time.Millisecond,
+		Samples: 20,
+	}
+	s.LastPong = time.Time{}
+	if s != want {
+		t.Errorf("snapshot() = %+v, want %+v", s, want)
+	}
+}
+
+func TestHealthMonitor_Degraded(t *testing.T) {
+	h := newHealthMonitor(&ClientOptions{
+		Connection: &Connection{Timeout: time.Second},
+		Health:     &Health{DegradedLatency: 100 * time.Millisecond},
+	})
+
+	steps := []struct {
+		latency  time.Duration
+		changed  bool
+		degraded bool
+	}{
+		{50 * time.Millisecond, false, false},
+		{100 * time.Millisecond, true, true},
+		{300 * time.Millisecond, false, true},
+		{99 * time.Millisecond, true, false},
+	}
+	for _, step := range steps {
+		if changed := h.record(step.latency, time.Now()); changed != step.changed {
+			t.Errorf("record(%v) = %v, want %v", step.latency, changed, step.changed)
+		}
+		if degraded := h.snapshot().Degraded; degraded != step.degraded {
+			t.Errorf("Degraded = %v after %v, want %v", degraded, step.latency, step.degraded)
+		}
+	}
+}
+
+func TestClient_Keepalive(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	opts.Health = &Health{PingInterval: 20 * time.Millisecond, DegradedLatency: time.Nanosecond}
+	client := NewClient(opts)
+
+	waitPongs := waitFor(t, client, "pong", 3)
+	waitDegraded := waitFor(t, client, "degraded", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Disconnect()
+	waitPongs()
+	waitDegraded()
+
+	health := client.Health()
+	if !health.Connected || !health.Degraded || health.Samples < 3 || health.LastPong.IsZero() {
+		t.Errorf("Health() = %+v, want connected and degraded with at least 3 samples", health)
+	}
+	if health.Min > health.Avg || health.Avg > health.Max || health.P95 > health.Max {
+		t.Errorf("Health() = %+v, statistics out of order", health)
+	}
+	if n := countLines(server, "PING"); n < 3 {
+		t.Errorf("client sent %d PINGs, want at least 3", n)
+	}
+}
+
+func TestClient_KeepaliveAcrossReconnects(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	opts.Health = &Health{PingInterval: 10 * time.Millisecond}
+	client := connectTest(t, opts)
+
+	for range 3 {
+		wait := waitFor(t, client, "roomstate", 1)
+		server.Drop()
+		wait()
+	}
+
+	if n := client.health.loops.Load(); n != 1 {
+		t.Errorf("%d keepalive loops running after 3 reconnects, want 1", n)
+	}
+
+	client.Disconnect()
+	time.Sleep(20 * time.Millisecond)
+	if n := client.health.loops.Load(); n != 0 {
+		t.Errorf("%d keepalive loops running after Disconnect, want 0", n)
+	}
+}
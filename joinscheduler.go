@@ -0,0 +1,197 @@
+package tmigo
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// JoinStatus is the stage a channel reached in the join scheduler
+type JoinStatus string
+
+const (
+	JoinQueued   JoinStatus = "queued"
+	JoinJoined   JoinStatus = "joined"
+	JoinFailed   JoinStatus = "failed"
+	JoinCanceled JoinStatus = "canceled"
+)
+
+// JoinProgress is emitted with the joinprogress event whenever a channel is
+// queued, joined, fails to join or is canceled by Part
+type JoinProgress struct {
+	Channel string
+	Status  JoinStatus
+	Err     error // Set when Status is JoinFailed or JoinCanceled
+	Pending int   // Channels still waiting to be sent
+}
+
+// joinScheduler sends JOINs for the whole client under Twitch's join budget.
+// Channels are joined in the order they were queued; every channel waits in
+// the queue until its JOIN is sent and in flight until finish is called.
+type joinScheduler struct {
+	mu       sync.Mutex
+	bucket   *tokenBucket
+	interval time.Duration
+	pending  []string
+	waiting  map[string][]chan error
+	last     time.Time
+	timer    *time.Timer
+
+	send     func(channel string)
+	ready    func() bool
+	progress func(JoinProgress)
+	now      func() time.Time
+}
+
+// newJoinScheduler creates a scheduler for the join budget in the options.
+// send must eventually call finish for the channel it was given.
+func newJoinScheduler(opts *ClientOptions, send func(string), ready func() bool, progress func(JoinProgress)) *joinScheduler {
+	limits := opts.RateLimit
+	if limits.JoinTier.Messages == 0 || limits.JoinTier.Per == 0 {
+		limits.JoinTier = RateLimitTier{Messages: 20, Per: 10 * time.Second}
+	}
+	if limits.VerifiedJoinTier.Messages == 0 || limits.VerifiedJoinTier.Per == 0 {
+		limits.VerifiedJoinTier = RateLimitTier{Messages: 2000, Per: 10 * time.Second}
+	}
+
+	tier := limits.JoinTier
+	if limits.Verified {
+		tier = limits.VerifiedJoinTier
+	}
+
+	return &joinScheduler{
+		bucket:   newTokenBucket(tier, time.Now()),
+		interval: time.Duration(opts.Options.JoinInterval) * time.Millisecond,
+		waiting:  make(map[string][]chan error),
+		send:     send,
+		ready:    ready,
+		progress: progress,
+		now:      time.Now,
+	}
+}
+
+// add queues a channel unless it is already queued or in flight. The
+// returned channel receives the outcome of the join.
+func (s *joinScheduler) add(channel string) <-chan error {
+	result := make(chan error, 1)
+
+	s.mu.Lock()
+	_, known := s.waiting[channel]
+	s.waiting[channel] = append(s.waiting[channel], result)
+	if !known {
+		s.pending = append(s.pending, channel)
+	}
+	pending := len(s.pending)
+	s.schedule()
+	s.mu.Unlock()
+
+	if !known {
+		s.progress(JoinProgress{Channel: channel, Status: JoinQueued, Pending: pending})
+	}
+	return result
+}
+
+// cancel removes a channel whose JOIN has not been sent yet. It reports
+// whether the channel was pending.
+func (s *joinScheduler) cancel(channel string) bool {
+	s.mu.Lock()
+	i := slices.Index(s.pending, channel)
+	if i == -1 {
+		s.mu.Unlock()
+		return false
+	}
+	s.pending = slices.Delete(s.pending, i, i+1)
+	results := s.waiting[channel]
+	delete(s.waiting, channel)
+	pending := len(s.pending)
+	s.mu.Unlock()
+
+	s.progress(JoinProgress{Channel: channel, Status: JoinCanceled, Err: ErrJoinCanceled, Pending: pending})
+	for _, result := range results {
+		result <- ErrJoinCanceled
+	}
+	return true
+}
+
+// finish reports the outcome of a JOIN that was sent
+func (s *joinScheduler) finish(channel string, err error) {
+	s.mu.Lock()
+	results := s.waiting[channel]
+	delete(s.waiting, channel)
+	pending := len(s.pending)
+	s.mu.Unlock()
+
+	status := JoinJoined
+	if err != nil {
+		status = JoinFailed
+	}
+	s.progress(JoinProgress{Channel: channel, Status: status, Err: err, Pending: pending})
+
+	for _, result := range results {
+		result <- err
+	}
+}
+
+// requeue puts a channel whose JOIN was lost with its connection back at
+// the front of the queue. Callers waiting on the channel keep waiting.
+func (s *joinScheduler) requeue(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.waiting[channel]; !ok || slices.Contains(s.pending, channel) {
+		return
+	}
+	s.pending = slices.Insert(s.pending, 0, channel)
+	s.schedule()
+}
+
+// reserve takes a JOIN sent outside the queue from the budget and returns
+// how long to wait before sending it
+func (s *joinScheduler) reserve() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bucket.take(s.now())
+}
+
+// resume continues sending after the connection is ready again
+func (s *joinScheduler) resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedule()
+}
+
+// schedule arms the timer for the next pending channel. The token for that
+// JOIN is taken now and returned if the timer finds nothing to send.
+func (s *joinScheduler) schedule() {
+	if s.timer != nil || len(s.pending) == 0 {
+		return
+	}
+
+	now := s.now()
+	wait := s.bucket.take(now)
+	if s.interval > 0 && !s.last.IsZero() {
+		wait = max(wait, s.last.Add(s.interval).Sub(now))
+	}
+
+	s.timer = time.AfterFunc(wait, s.run)
+}
+
+// run sends the next pending channel. It stops while the connection is not
+// ready; resume starts it again.
+func (s *joinScheduler) run() {
+	s.mu.Lock()
+	s.timer = nil
+	if len(s.pending) == 0 || !s.ready() {
+		s.bucket.put()
+		s.mu.Unlock()
+		return
+	}
+
+	channel := s.pending[0]
+	s.pending = s.pending[1:]
+	s.last = s.now()
+	s.schedule()
+	s.mu.Unlock()
+
+	s.send(channel)
+}
@@ -0,0 +1,159 @@
+package tmigo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type joinRecorder struct {
+	mu       sync.Mutex
+	sent     []string
+	progress []JoinProgress
+}
+
+func (r *joinRecorder) sentChannels() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.sent...)
+}
+
+func newTestJoinScheduler(tier RateLimitTier, interval int, ready func() bool) (*joinScheduler, *joinRecorder) {
+	r := &joinRecorder{}
+	opts := &ClientOptions{
+		Options:   &Options{JoinInterval: interval},
+		RateLimit: &RateLimit{JoinTier: tier},
+	}
+
+	var s *joinScheduler
+	s = newJoinScheduler(opts, func(channel string) {
+		r.mu.Lock()
+		r.sent = append(r.sent, channel)
+		r.mu.Unlock()
+		s.finish(channel, nil)
+	}, ready, func(progress JoinProgress) {
+		r.mu.Lock()
+		r.progress = append(r.progress, progress)
+		r.mu.Unlock()
+	})
+	return s, r
+}
+
+func alwaysReady() bool { return true }
+
+func TestJoinScheduler_Budget(t *testing.T) {
+	s, r := newTestJoinScheduler(RateLimitTier{Messages: 2, Per: time.Second}, 0, alwaysReady)
+
+	for _, channel := range []string{"#a", "#b", "#c"} {
+		s.add(channel)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if sent := r.sentChannels(); len(sent) != 2 {
+		t.Fatalf("sent = %v before refill, want 2 channels", sent)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	sent := r.sentChannels()
+	if len(sent) != 3 || sent[2] != "#c" {
+		t.Errorf("sent = %v after refill, want [#a #b #c]", sent)
+	}
+}
+
+func TestJoinScheduler_Deduplicates(t *testing.T) {
+	s, r := newTestJoinScheduler(RateLimitTier{Messages: 1, Per: time.Hour}, 0, alwaysReady)
+
+	s.add("#a")
+	s.add("#b")
+	s.add("#b")
+
+	time.Sleep(50 * time.Millisecond)
+
+	s.mu.Lock()
+	pending := len(s.pending)
+	s.mu.Unlock()
+	if pending != 1 {
+		t.Errorf("pending = %d, want 1", pending)
+	}
+	if sent := r.sentChannels(); len(sent) != 1 {
+		t.Errorf("sent = %v, want [#a]", sent)
+	}
+}
+
+func TestJoinScheduler_CancelPending(t *testing.T) {
+	s, r := newTestJoinScheduler(RateLimitTier{Messages: 1, Per: time.Hour}, 0, alwaysReady)
+
+	s.add("#a")
+	result := s.add("#b")
+	time.Sleep(50 * time.Millisecond)
+
+	if !s.cancel("#b") {
+		t.Fatal("cancel(#b) = false for a pending channel")
+	}
+	if s.cancel("#a") {
+		t.Error("cancel(#a) = true for a channel that was already sent")
+	}
+	if err := <-result; !errors.Is(err, ErrJoinCanceled) {
+		t.Errorf("result = %v, want ErrJoinCanceled", err)
+	}
+
+	r.mu.Lock()
+	last := r.progress[len(r.progress)-1]
+	r.mu.Unlock()
+	if last.Channel != "#b" || last.Status != JoinCanceled {
+		t.Errorf("last progress = %+v, want #b canceled", last)
+	}
+}
+
+func TestJoinScheduler_WaitsUntilReady(t *testing.T) {
+	var mu sync.Mutex
+	ready := false
+	isReady := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return ready
+	}
+
+	s, r := newTestJoinScheduler(RateLimitTier{Messages: 5, Per: time.Second}, 0, isReady)
+	result := s.add("#a")
+
+	time.Sleep(50 * time.Millisecond)
+	if sent := r.sentChannels(); len(sent) != 0 {
+		t.Fatalf("sent = %v while not ready", sent)
+	}
+
+	mu.Lock()
+	ready = true
+	mu.Unlock()
+	s.resume()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("result = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("join not sent after resume")
+	}
+}
+
+func TestJoinScheduler_Progress(t *testing.T) {
+	s, r := newTestJoinScheduler(RateLimitTier{Messages: 5, Per: time.Second}, 0, alwaysReady)
+
+	<-s.add("#a")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.progress) != 2 || r.progress[0].Status != JoinQueued || r.progress[1].Status != JoinJoined {
+		t.Errorf("progress = %+v, want queued then joined", r.progress)
+	}
+}
+
+func TestJoin_NotConnected(t *testing.T) {
+	client := NewClient(nil)
+
+	if err := client.Join("test"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Join() error = %v, want ErrNotConnected", err)
+	}
+}
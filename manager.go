@@ -0,0 +1,369 @@
+package tmigo
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ManagerOptions configures a Manager
+type ManagerOptions struct {
+	DedupWindow time.Duration // How long a delivered event suppresses copies from other accounts (default 1m)
+}
+
+// Manager runs several accounts side by side, each on its own Client keyed
+// by its username. Commands for a channel go out on the account routed to it,
+// and the events of every account are merged into one stream: handlers on the
+// manager receive the receiving account's username before the usual
+// arguments. When several accounts sit in the same channel, a message with
+// a Twitch message id, or a channel broadcast like a ban or a room state
+// change, is only delivered by the first account that receives it. Copies
+// from the other accounts are suppressed; the same line repeated on that
+// account is a new event and delivered again.
+type Manager struct {
+	*EventEmitter
+
+	opts ManagerOptions
+
+	mu       sync.RWMutex
+	clients  map[string]*Client
+	accounts []string          // Usernames in the order they were added
+	routes   map[string]string // Channel to the account that speaks in it
+	fallback string            // Account used when nothing else applies
+
+	seenMu    sync.Mutex
+	seen      map[string]seenMessage // Message keys to the account that delivered them
+	lastPrune time.Time
+}
+
+// seenMessage records which account delivered a message and until when
+// copies from other accounts are suppressed
+type seenMessage struct {
+	account string
+	until   time.Time
+}
+
+// NewManager creates a manager without accounts
+func NewManager(opts *ManagerOptions) *Manager {
+	if opts == nil {
+		opts = &ManagerOptions{}
+	}
+
+	m := &Manager{
+		EventEmitter: NewEventEmitter(),
+		opts:         *opts,
+		clients:      make(map[string]*Client),
+		routes:       make(map[string]string),
+		seen:         make(map[string]seenMessage),
+	}
+	m.opts.DedupWindow = cmp.Or(m.opts.DedupWindow, time.Minute)
+
+	return m
+}
+
+// Add creates a client for an account. The first account added is the
+// default account. The client is not connected.
+func (m *Manager) Add(opts *ClientOptions) (*Client, error) {
+	if opts == nil || opts.Identity == nil || opts.Identity.Username == "" {
+		return nil, errors.New("manager accounts need Identity.Username")
+	}
+	account := Username(opts.Identity.Username)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.clients[account]; ok {
+		return nil, fmt.Errorf("account %s already added", account)
+	}
+
+	client := NewClient(opts)
+	client.forward = func(meta EventMeta, eventType string, args []any) {
+		m.deliver(account, meta, eventType, args)
+	}
+	m.clients[account] = client
+	m.accounts = append(m.accounts, account)
+	if m.fallback == "" {
+		m.fallback = account
+	}
+
+	return client, nil
+}
+
+// Remove disconnects an account and forgets it along with its routes
+func (m *Manager) Remove(account string) error {
+	account = Username(account)
+
+	m.mu.Lock()
+	client, ok := m.clients[account]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("unknown account %s", account)
+	}
+	delete(m.clients, account)
+	m.accounts = slices.DeleteFunc(m.accounts, func(a string) bool { return a == account })
+	for channel, routed := range m.routes {
+		if routed == account {
+			delete(m.routes, channel)
+		}
+	}
+	if m.fallback == account {
+		m.fallback = ""
+		if len(m.accounts) > 0 {
+			m.fallback = m.accounts[0]
+		}
+	}
+	m.mu.Unlock()
+
+	if err := client.Disconnect(); err != nil && !errors.Is(err, ErrNotConnected) {
+		return err
+	}
+	return nil
+}
+
+// Client returns the client of an account
+func (m *Manager) Client(account string) (*Client, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[Username(account)]
+	return client, ok
+}
+
+// Accounts returns the usernames of the accounts in the order they were
+// added
+func (m *Manager) Accounts() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.accounts)
+}
+
+// Connect connects every account
+func (m *Manager) Connect() error {
+	var errs []error
+	for _, client := range m.all() {
+		if err := client.Connect(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Disconnect disconnects every connected account
+func (m *Manager) Disconnect() error {
+	var errs []error
+	for _, client := range m.all() {
+		if err := client.Disconnect(); err != nil && !errors.Is(err, ErrNotConnected) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetDefault makes an account the one used for channels without a route and
+// for whispers
+func (m *Manager) SetDefault(account string) error {
+	account = Username(account)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.clients[account]; !ok {
+		return fmt.Errorf("unknown account %s", account)
+	}
+	m.fallback = account
+	return nil
+}
+
+// Route makes an account speak in a channel. An empty account removes the
+// route.
+func (m *Manager) Route(channel, account string) error {
+	channel = Channel(channel)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if account == "" {
+		delete(m.routes, channel)
+		return nil
+	}
+	account = Username(account)
+	if _, ok := m.clients[account]; !ok {
+		return fmt.Errorf("unknown account %s", account)
+	}
+	m.routes[channel] = account
+	return nil
+}
+
+// For returns the account that speaks in a channel: the routed account, else
+// the first account that joined the channel, else the default account
+func (m *Manager) For(channel string) (*Client, error) {
+	channel = Channel(channel)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if account, ok := m.routes[channel]; ok {
+		return m.clients[account], nil
+	}
+	for _, account := range m.accounts {
+		if slices.Contains(m.clients[account].GetChannels(), channel) {
+			return m.clients[account], nil
+		}
+	}
+	if m.fallback == "" {
+		return nil, ErrNotConnected
+	}
+	return m.clients[m.fallback], nil
+}
+
+// Say sends a message on the account that speaks in the channel
+func (m *Manager) Say(channel, message string, tags ...map[string]string) error {
+	client, err := m.For(channel)
+	if err != nil {
+		return err
+	}
+	return client.Say(channel, message, tags...)
+}
+
+// Action sends an action message on the account that speaks in the channel
+func (m *Manager) Action(channel, message string, tags ...map[string]string) error {
+	client, err := m.For(channel)
+	if err != nil {
+		return err
+	}
+	return client.Action(channel, message, tags...)
+}
+
+// Reply replies to a message on the account that speaks in the channel
+func (m *Manager) Reply(channel, message, replyParentMsgID string, tags ...map[string]string) error {
+	client, err := m.For(channel)
+	if err != nil {
+		return err
+	}
+	return client.Reply(channel, message, replyParentMsgID, tags...)
+}
+
+// Whisper sends a whisper from the default account. Use Client to whisper
+// from another account.
+func (m *Manager) Whisper(username, message string) error {
+	m.mu.RLock()
+	client := m.clients[m.fallback]
+	m.mu.RUnlock()
+
+	if client == nil {
+		return ErrNotConnected
+	}
+	return client.Whisper(username, message)
+}
+
+// OnMessage registers a type-safe handler for message events of every
+// account
+func (m *Manager) OnMessage(handler func(account, channel string, userstate ChatUserstate, message string, self bool)) *Subscription {
+	return m.On("message", func(args ...any) {
+		if len(args) >= 5 {
+			account, _ := args[0].(string)
+			channel, _ := args[1].(string)
+			userstate, _ := args[2].(ChatUserstate)
+			message, _ := args[3].(string)
+			self, _ := args[4].(bool)
+			handler(account, channel, userstate, message, self)
+		}
+	})
+}
+
+// all returns the clients in the order they were added
+func (m *Manager) all() []*Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	clients := make([]*Client, len(m.accounts))
+	for i, account := range m.accounts {
+		clients[i] = m.clients[account]
+	}
+	return clients
+}
+
+// deliver emits an account's event on the manager unless another account
+// already delivered the same message
+func (m *Manager) deliver(account string, meta EventMeta, eventType string, args []any) {
+	// Internal events only concern the account's own commands
+	if strings.HasPrefix(eventType, "_") {
+		return
+	}
+	if key, ok := messageKey(eventType, meta.Raw, args); ok && !m.first(account, key) {
+		return
+	}
+	m.Emit(eventType, append([]any{account}, args...)...)
+}
+
+// first records a message key for an account and reports whether it is not
+// a copy of what another account delivered within the dedup window
+func (m *Manager) first(account, key string) bool {
+	m.seenMu.Lock()
+	defer m.seenMu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastPrune) >= m.opts.DedupWindow {
+		for k, seen := range m.seen {
+			if now.After(seen.until) {
+				delete(m.seen, k)
+			}
+		}
+		m.lastPrune = now
+	}
+
+	if seen, ok := m.seen[key]; ok && seen.account != account && now.Before(seen.until) {
+		return false
+	}
+	m.seen[key] = seenMessage{account: account, until: now.Add(m.opts.DedupWindow)}
+	return true
+}
+
+// messageID is implemented by the userstates of messages that carry a
+// Twitch message id
+type messageID interface {
+	messageID() string
+}
+
+func (u CommonUserstate) messageID() string {
+	return u.ID
+}
+
+// channelBroadcasts are the channel lines without a message id that every
+// account in the channel receives
+var channelBroadcasts = map[string]bool{
+	"CLEARCHAT":  true,
+	"CLEARMSG":   true,
+	"ROOMSTATE":  true,
+	"JOIN":       true,
+	"PART":       true,
+	"HOSTTARGET": true,
+}
+
+// messageKey identifies an event caused by a specific Twitch message: by its
+// message id, or for channel broadcasts like bans, room state and joins by
+// the text of the line, which includes tmi-sent-ts where Twitch sends it.
+// Other events, like connection events and notices, have no key and are
+// delivered by every account.
+func messageKey(eventType string, raw *IRCMessage, args []any) (string, bool) {
+	for _, arg := range args {
+		var id string
+		switch v := arg.(type) {
+		case messageID:
+			id = v.messageID()
+		case map[string]any:
+			id, _ = v["id"].(string)
+		}
+		if id != "" {
+			return eventType + " " + id, true
+		}
+	}
+
+	if raw == nil || !channelBroadcasts[raw.Command] || len(raw.Params) == 0 || !strings.HasPrefix(raw.Params[0], "#") {
+		return "", false
+	}
+	return eventType + " " + raw.Raw, true
+}
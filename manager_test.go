@@ -0,0 +1,267 @@
+package tmigo
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testManager adds an account per username, each joined to channels, on one
+// test server and connects them
+func testManager(t *testing.T, accounts map[string][]string) (*Manager, func(channel, username, message, id string)) {
+	t.Helper()
+
+	server, _ := testOptions(t)
+	m := NewManager(nil)
+
+	// The manager delivers a room state shared by several accounts once
+	var waits []func()
+	for _, account := range slices.Sorted(maps.Keys(accounts)) {
+		client, err := m.Add(&ClientOptions{
+			Connection: &Connection{Server: server.Host(), Port: server.Port()},
+			Identity:   &Identity{Username: account, Password: "oauth:token"},
+			Channels:   accounts[account],
+		})
+		if err != nil {
+			t.Fatalf("Add(%s) error = %v", account, err)
+		}
+		waits = append(waits, waitFor(t, client, "roomstate", len(accounts[account])))
+	}
+
+	if err := m.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { m.Disconnect() })
+	for _, wait := range waits {
+		wait()
+	}
+
+	return m, func(channel, username, message, id string) {
+		server.PrivMsg(channel, username, message, map[string]string{"id": id})
+	}
+}
+
+func waitForManager(t *testing.T, m *Manager, event string, count int) func() {
+	t.Helper()
+
+	fired := make(chan struct{}, count)
+	m.On(event, func(args ...any) {
+		select {
+		case fired <- struct{}{}:
+		default:
+		}
+	})
+
+	return func() {
+		t.Helper()
+		for i := range count {
+			select {
+			case <-fired:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s fired %d times, want %d", event, i, count)
+			}
+		}
+	}
+}
+
+// recordMessages collects "account channel message" for every merged
+// message event
+func recordMessages(m *Manager) func() []string {
+	var mu sync.Mutex
+	var messages []string
+	m.OnMessage(func(account, channel string, userstate ChatUserstate, message string, self bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, account+" "+channel+" "+message)
+	})
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(messages)
+	}
+}
+
+func TestManager_Dedup(t *testing.T) {
+	m, privMsg := testManager(t, map[string][]string{"main": {"#a"}, "mod": {"#a"}})
+	messages := recordMessages(m)
+
+	mod, _ := m.Client("mod")
+	waitMod := waitFor(t, mod, "message", 2)
+	privMsg("#a", "viewer", "hello", "b5b8c3d0")
+	privMsg("#a", "viewer", "hello again", "c2a1f7e4")
+	waitMod()
+	time.Sleep(20 * time.Millisecond)
+
+	got := messages()
+	if len(got) != 2 {
+		t.Fatalf("merged messages = %v, want each message once", got)
+	}
+	for i, want := range []string{"#a hello", "#a hello again"} {
+		if account, rest, _ := strings.Cut(got[i], " "); rest != want || (account != "main" && account != "mod") {
+			t.Errorf("message %d = %q, want %q from main or mod", i, got[i], want)
+		}
+	}
+}
+
+func TestManager_DedupChannelEvents(t *testing.T) {
+	server, _ := testOptions(t)
+	m := NewManager(nil)
+
+	var clients []*Client
+	for _, account := range []string{"main", "mod"} {
+		client, err := m.Add(&ClientOptions{
+			Connection: &Connection{Server: server.Host(), Port: server.Port()},
+			Identity:   &Identity{Username: account, Password: "oauth:token"},
+			Channels:   []string{"#a"},
+		})
+		if err != nil {
+			t.Fatalf("Add(%s) error = %v", account, err)
+		}
+		clients = append(clients, client)
+	}
+
+	var mu sync.Mutex
+	var timeouts, roomstates int
+	m.On("timeout", func(args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		timeouts++
+	})
+	m.On("roomstate", func(args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		roomstates++
+	})
+
+	waitJoined := []func(){waitFor(t, clients[0], "roomstate", 1), waitFor(t, clients[1], "roomstate", 1)}
+	if err := m.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { m.Disconnect() })
+	for _, wait := range waitJoined {
+		wait()
+	}
+
+	waitTimeouts := []func(){waitFor(t, clients[0], "timeout", 2), waitFor(t, clients[1], "timeout", 2)}
+	server.ClearChat("#a", "spammer", map[string]string{"ban-duration": "600", "tmi-sent-ts": "1700000000000"})
+	server.ClearChat("#a", "spammer", map[string]string{"ban-duration": "600", "tmi-sent-ts": "1700000060000"})
+	for _, wait := range waitTimeouts {
+		wait()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if timeouts != 2 {
+		t.Errorf("timeout fired %d times on the manager, want once per CLEARCHAT", timeouts)
+	}
+	if roomstates != 1 {
+		t.Errorf("roomstate fired %d times on the manager, want 1", roomstates)
+	}
+}
+
+func TestManager_RepeatedChannelEvents(t *testing.T) {
+	server, _ := testOptions(t)
+	m := NewManager(nil)
+	client, err := m.Add(&ClientOptions{
+		Connection: &Connection{Server: server.Host(), Port: server.Port()},
+		Identity:   &Identity{Username: "main", Password: "oauth:token"},
+		Channels:   []string{"#a"},
+	})
+	if err != nil {
+		t.Fatalf("Add(main) error = %v", err)
+	}
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := m.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { m.Disconnect() })
+	wait()
+
+	// The same line again on one account is a new event, not a copy
+	waitJoins := waitForManager(t, m, "join", 2)
+	waitEmoteOnly := waitForManager(t, m, "emoteonly", 3)
+	waitParts := waitFor(t, client, "part", 1)
+	waitClient := waitFor(t, client, "emoteonly", 3)
+	server.Send(
+		":viewer!viewer@viewer.tmi.twitch.tv JOIN #a",
+		":viewer!viewer@viewer.tmi.twitch.tv PART #a",
+		":viewer!viewer@viewer.tmi.twitch.tv JOIN #a",
+		"@emote-only=1 :tmi.twitch.tv ROOMSTATE #a",
+		"@emote-only=0 :tmi.twitch.tv ROOMSTATE #a",
+		"@emote-only=1 :tmi.twitch.tv ROOMSTATE #a",
+	)
+	waitParts()
+	waitClient()
+	waitJoins()
+	waitEmoteOnly()
+}
+
+func TestManager_DeliversAccountEvents(t *testing.T) {
+	m, _ := testManager(t, map[string][]string{"main": nil, "mod": nil})
+
+	var mu sync.Mutex
+	var accounts []string
+	wait := waitForManager(t, m, "pong", 2)
+	m.On("pong", func(args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		accounts = append(accounts, args[0].(string))
+	})
+	for _, account := range m.Accounts() {
+		client, _ := m.Client(account)
+		if err := client.Ping(); err != nil {
+			t.Fatalf("Ping() error = %v", err)
+		}
+	}
+	wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(accounts)
+	if !slices.Equal(accounts, []string{"main", "mod"}) {
+		t.Errorf("pong accounts = %v, want main and mod", accounts)
+	}
+}
+
+func TestManager_Route(t *testing.T) {
+	m, _ := testManager(t, map[string][]string{"main": {"#a"}, "mod": {"#b"}})
+
+	tests := []struct {
+		route   string // Account routed to #a
+		channel string
+		want    string
+	}{
+		{"", "#a", "main"},   // Joined
+		{"", "#b", "mod"},    // Joined
+		{"", "#c", "main"},   // Default
+		{"mod", "#a", "mod"}, // Routed
+	}
+	for _, tt := range tests {
+		if err := m.Route("#a", tt.route); err != nil {
+			t.Fatalf("Route() error = %v", err)
+		}
+		client, err := m.For(tt.channel)
+		if err != nil {
+			t.Fatalf("For(%s) error = %v", tt.channel, err)
+		}
+		if got := client.GetUsername(); got != tt.want {
+			t.Errorf("For(%s) with route %q = %s, want %s", tt.channel, tt.route, got, tt.want)
+		}
+	}
+
+	if err := m.Route("#a", "nobody"); err == nil {
+		t.Error("Route() to an unknown account succeeded")
+	}
+	if err := m.SetDefault("mod"); err != nil {
+		t.Fatalf("SetDefault() error = %v", err)
+	}
+	if client, _ := m.For("#c"); client.GetUsername() != "mod" {
+		t.Errorf("For(#c) = %s after SetDefault, want mod", client.GetUsername())
+	}
+}
@@ -0,0 +1,115 @@
+package tmigo
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// InboundHandler handles a message received from Twitch
+type InboundHandler func(ctx context.Context, message *IRCMessage)
+
+// InboundMiddleware wraps the handling of received messages. It can change
+// the message before passing it to next, drop it by not calling next, or
+// annotate it with values on ctx. The ctx passed on by the last middleware
+// reaches listeners through IRCMessage.Context and EventMeta.Context.
+type InboundMiddleware func(next InboundHandler) InboundHandler
+
+// Context returns the context the inbound middleware handed on with the
+// message. It is done once the connection the message arrived on is closed.
+// Messages that did not pass the middleware return context.Background.
+func (m *IRCMessage) Context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// OutboundMessage is a line on its way to Twitch
+type OutboundMessage struct {
+	Tags    map[string]string // Tags sent in front of the line, never nil
+	Channel string            // Channel of a PRIVMSG, empty for a raw command
+	Text    string            // Message or chat command, or the whole line of a raw command
+}
+
+// String formats the line written to the connection
+func (m *OutboundMessage) String() string {
+	tagStr := FormTags(m.Tags)
+	if tagStr != "" {
+		tagStr += " "
+	}
+	if m.Channel == "" {
+		return tagStr + m.Text
+	}
+	return fmt.Sprintf("%sPRIVMSG %s :%s", tagStr, m.Channel, m.Text)
+}
+
+// OutboundHandler sends a line to Twitch
+type OutboundHandler func(ctx context.Context, message *OutboundMessage) error
+
+// OutboundMiddleware wraps the sending of messages and commands. It can
+// change the message before passing it to next, drop it by returning without
+// calling next, or fail the send by returning an error. The command of a
+// dropped message returns ErrDropped. Login lines and keepalive pings bypass
+// it.
+type OutboundMiddleware func(next OutboundHandler) OutboundHandler
+
+// chainInbound wraps final in middleware, the first one running first
+func chainInbound(middleware []InboundMiddleware, final InboundHandler) InboundHandler {
+	h := final
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// chainOutbound wraps final in middleware, the first one running first
+func chainOutbound(middleware []OutboundMiddleware, final OutboundHandler) OutboundHandler {
+	h := final
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// send passes a message through the outbound middleware, then waits for the
+// rate limiter when it goes to a channel and writes it
+func (c *Client) send(channel, text string, tags ...map[string]string) error {
+	m := &OutboundMessage{Tags: make(map[string]string), Channel: channel, Text: text}
+	if len(tags) > 0 && tags[0] != nil {
+		maps.Copy(m.Tags, tags[0])
+	}
+
+	written := new(bool)
+	ctx := context.WithValue(c.connContext(), writtenKey{}, written)
+	if err := c.outbound(ctx, m); err != nil {
+		return err
+	}
+	if !*written {
+		// Commands waiting for a response would wait for nothing
+		return ErrDropped
+	}
+	return nil
+}
+
+// writtenKey marks on the context of a send whether the message reached the
+// end of the outbound chain
+type writtenKey struct{}
+
+// writeOutbound ends the outbound middleware chain
+func (c *Client) writeOutbound(ctx context.Context, m *OutboundMessage) error {
+	if written, ok := ctx.Value(writtenKey{}).(*bool); ok {
+		*written = true
+	}
+	if m.Channel != "" {
+		if err := c.throttle(m.Channel, m.Text); err != nil {
+			return err
+		}
+	}
+	if m.Channel == "" && strings.HasPrefix(m.Text, "JOIN ") {
+		// The join scheduler sends lost JOINs again itself
+		return c.write(m.String())
+	}
+	return c.writeUnsent(m.String())
+}
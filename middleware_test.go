@@ -0,0 +1,135 @@
+package tmigo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type traceKey struct{}
+
+func TestMiddleware_Inbound(t *testing.T) {
+	var order []string
+	trace := func(name string) InboundMiddleware {
+		return func(next InboundHandler) InboundHandler {
+			return func(ctx context.Context, message *IRCMessage) {
+				order = append(order, name)
+				next(context.WithValue(ctx, traceKey{}, name), message)
+			}
+		}
+	}
+	mute := func(next InboundHandler) InboundHandler {
+		return func(ctx context.Context, message *IRCMessage) {
+			if ctx.Value(traceKey{}) != "second" {
+				t.Errorf("trace = %v, want the value of the middleware before", ctx.Value(traceKey{}))
+			}
+			switch {
+			case message.Command == "PRIVMSG" && message.Params[1] == "drop me":
+				return
+			case message.Command == "PRIVMSG":
+				message.Params[1] = strings.ToUpper(message.Params[1])
+				message.Tags["audit-id"] = "42"
+			}
+			next(ctx, message)
+		}
+	}
+
+	client := NewClient(&ClientOptions{Middleware: &Middleware{
+		Inbound: []InboundMiddleware{trace("first"), trace("second"), mute},
+	}})
+
+	var got []MessageEvent
+	On(client, func(e MessageEvent) { got = append(got, e) })
+
+	client.handleMessage(ParseMessage(":viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #a :drop me"))
+	client.handleMessage(ParseMessage(":viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #a :hello"))
+
+	if want := []string{"first", "second", "first", "second"}; !slices.Equal(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if len(got) != 1 {
+		t.Fatalf("message fired %d times, want 1", len(got))
+	}
+	if got[0].Message != "HELLO" {
+		t.Errorf("Message = %q, want HELLO", got[0].Message)
+	}
+	if got[0].Raw.Tags["audit-id"] != "42" {
+		t.Errorf("Raw.Tags = %v, want the audit-id tag", got[0].Raw.Tags)
+	}
+	if trace := got[0].Context().Value(traceKey{}); trace != "second" {
+		t.Errorf("Context() value = %v, want the value of the last middleware", trace)
+	}
+	if trace := (EventMeta{}).Context().Value(traceKey{}); trace != nil {
+		t.Errorf("Context() value = %v for an event without a message", trace)
+	}
+}
+
+func TestMiddleware_Outbound(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	filter := func(next OutboundHandler) OutboundHandler {
+		return func(ctx context.Context, message *OutboundMessage) error {
+			switch message.Text {
+			case "drop me", "/slow 30":
+				return nil
+			case "block me":
+				return errBlocked
+			}
+			message.Text = strings.ReplaceAll(message.Text, "darn", "****")
+			return next(ctx, message)
+		}
+	}
+	nonce := func(next OutboundHandler) OutboundHandler {
+		return func(ctx context.Context, message *OutboundMessage) error {
+			if message.Channel != "" {
+				message.Tags["client-nonce"] = "abc"
+			}
+			return next(ctx, message)
+		}
+	}
+
+	server, opts := testOptions(t, "#a")
+	opts.RateLimit = &RateLimit{Disabled: true}
+	opts.Middleware = &Middleware{Outbound: []OutboundMiddleware{filter, nonce}}
+	client := connectTest(t, opts)
+
+	if err := client.Say("#a", "drop me"); !errors.Is(err, ErrDropped) {
+		t.Errorf("Say() error = %v, want ErrDropped", err)
+	}
+	if err := client.Say("#a", "block me"); !errors.Is(err, errBlocked) {
+		t.Errorf("Say() error = %v, want %v", err, errBlocked)
+	}
+	tags := map[string]string{"reply-parent-msg-id": "1"}
+	if err := client.Say("#a", "darn it", tags); err != nil {
+		t.Fatalf("Say() error = %v", err)
+	}
+	if len(tags) != 1 {
+		t.Errorf("caller's tags changed to %v", tags)
+	}
+
+	// A command waiting for a response fails right away
+	start := time.Now()
+	if err := client.SlowContext(context.Background(), "#a", 30); !errors.Is(err, ErrDropped) {
+		t.Errorf("SlowContext() error = %v, want ErrDropped", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SlowContext() took %v for a dropped command", elapsed)
+	}
+
+	line, ok := server.WaitForLine(time.Second, func(line string) bool {
+		return strings.Contains(line, "PRIVMSG #a :")
+	})
+	if !ok {
+		t.Fatal("server did not receive the message")
+	}
+	if !strings.HasSuffix(line, "PRIVMSG #a :**** it") || !strings.Contains(line, "client-nonce=abc") {
+		t.Errorf("line = %q, want the filtered message with a client-nonce", line)
+	}
+	for _, line := range server.Lines() {
+		if strings.Contains(line, "drop me") || strings.Contains(line, "block me") {
+			t.Errorf("server received %q", line)
+		}
+	}
+}
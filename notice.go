@@ -0,0 +1,194 @@
+package tmigo
+
+import "strings"
+
+// Internal _promise* events are emitted with (msgid, channel, extra...),
+// where msgid is nil when the command succeeded.
+
+// noticePromise describes how a NOTICE msg-id settles pending commands
+type noticePromise struct {
+	events  []string
+	success bool
+}
+
+// allPromiseEvents are settled by NOTICEs that reject any command in a channel
+var allPromiseEvents = []string{
+	"_promiseBan", "_promiseClear", "_promiseUnban", "_promiseTimeout", "_promiseDeletemessage",
+	"_promiseMods", "_promiseMod", "_promiseUnmod", "_promiseVips", "_promiseVip", "_promiseUnvip",
+	"_promiseCommercial", "_promiseHost", "_promiseUnhost", "_promiseJoin", "_promisePart",
+	"_promiseR9kbeta", "_promiseR9kbetaoff", "_promiseSlow", "_promiseSlowoff",
+	"_promiseFollowers", "_promiseFollowersoff", "_promiseSubscribers", "_promiseSubscribersoff",
+	"_promiseEmoteonly", "_promiseEmoteonlyoff", "_promiseWhisper",
+}
+
+// noticePromises maps NOTICE msg-ids onto the commands they settle
+var noticePromises = map[MsgID]noticePromise{
+	// Ban
+	MsgIDBanSuccess:            {[]string{"_promiseBan"}, true},
+	MsgIDAlreadyBanned:         {[]string{"_promiseBan"}, false},
+	MsgIDBadBanAdmin:           {[]string{"_promiseBan"}, false},
+	MsgIDBadBanAnon:            {[]string{"_promiseBan"}, false},
+	MsgIDBadBanBroadcaster:     {[]string{"_promiseBan"}, false},
+	MsgIDBadBanGlobalMod:       {[]string{"_promiseBan"}, false},
+	MsgIDBadBanMod:             {[]string{"_promiseBan"}, false},
+	MsgIDBadBanSelf:            {[]string{"_promiseBan"}, false},
+	MsgIDBadBanStaff:           {[]string{"_promiseBan"}, false},
+	MsgIDUsageBan:              {[]string{"_promiseBan"}, false},
+	MsgIDUsageClear:            {[]string{"_promiseClear"}, false},
+	MsgIDUnbanSuccess:          {[]string{"_promiseUnban"}, true},
+	MsgIDUntimeoutSuccess:      {[]string{"_promiseUnban"}, true},
+	MsgIDUsageUnban:            {[]string{"_promiseUnban"}, false},
+	MsgIDBadUnbanNoBan:         {[]string{"_promiseUnban"}, false},
+	MsgIDTimeoutSuccess:        {[]string{"_promiseTimeout"}, true},
+	MsgIDUsageTimeout:          {[]string{"_promiseTimeout"}, false},
+	MsgIDBadTimeoutAdmin:       {[]string{"_promiseTimeout"}, false},
+	MsgIDBadTimeoutAnon:        {[]string{"_promiseTimeout"}, false},
+	MsgIDBadTimeoutBroadcaster: {[]string{"_promiseTimeout"}, false},
+	MsgIDBadTimeoutDuration:    {[]string{"_promiseTimeout"}, false},
+	MsgIDBadTimeoutGlobalMod:   {[]string{"_promiseTimeout"}, false},
+	MsgIDBadTimeoutMod:         {[]string{"_promiseTimeout"}, false},
+	MsgIDBadTimeoutSelf:        {[]string{"_promiseTimeout"}, false},
+	MsgIDBadTimeoutStaff:       {[]string{"_promiseTimeout"}, false},
+
+	// Messages
+	MsgIDDeleteMessageSuccess:        {[]string{"_promiseDeletemessage"}, true},
+	MsgIDUsageDelete:                 {[]string{"_promiseDeletemessage"}, false},
+	MsgIDBadDeleteMessageError:       {[]string{"_promiseDeletemessage"}, false},
+	MsgIDBadDeleteMessageBroadcaster: {[]string{"_promiseDeletemessage"}, false},
+	MsgIDBadDeleteMessageMod:         {[]string{"_promiseDeletemessage"}, false},
+
+	// Mods and VIPs
+	MsgIDModSuccess:                  {[]string{"_promiseMod"}, true},
+	MsgIDUsageMod:                    {[]string{"_promiseMod"}, false},
+	MsgIDBadModBanned:                {[]string{"_promiseMod"}, false},
+	MsgIDBadModMod:                   {[]string{"_promiseMod"}, false},
+	MsgIDUnmodSuccess:                {[]string{"_promiseUnmod"}, true},
+	MsgIDUsageUnmod:                  {[]string{"_promiseUnmod"}, false},
+	MsgIDBadUnmodMod:                 {[]string{"_promiseUnmod"}, false},
+	MsgIDUsageMods:                   {[]string{"_promiseMods"}, false},
+	MsgIDVipSuccess:                  {[]string{"_promiseVip"}, true},
+	MsgIDUsageVip:                    {[]string{"_promiseVip"}, false},
+	MsgIDBadVipGranteeBanned:         {[]string{"_promiseVip"}, false},
+	MsgIDBadVipGranteeAlreadyVip:     {[]string{"_promiseVip"}, false},
+	MsgIDBadVipMaxVipsReached:        {[]string{"_promiseVip"}, false},
+	MsgIDBadVipAchievementIncomplete: {[]string{"_promiseVip"}, false},
+	MsgIDUnvipSuccess:                {[]string{"_promiseUnvip"}, true},
+	MsgIDUsageUnvip:                  {[]string{"_promiseUnvip"}, false},
+	MsgIDBadUnvipGranteeNotVip:       {[]string{"_promiseUnvip"}, false},
+	MsgIDUsageVips:                   {[]string{"_promiseVips"}, false},
+
+	// Color and commercials
+	MsgIDColorChanged:       {[]string{"_promiseColor"}, true},
+	MsgIDUsageColor:         {[]string{"_promiseColor"}, false},
+	MsgIDTurboOnlyColor:     {[]string{"_promiseColor"}, false},
+	MsgIDCommercialSuccess:  {[]string{"_promiseCommercial"}, true},
+	MsgIDUsageCommercial:    {[]string{"_promiseCommercial"}, false},
+	MsgIDBadCommercialError: {[]string{"_promiseCommercial"}, false},
+
+	// Hosting
+	MsgIDBadHostHosting:      {[]string{"_promiseHost"}, false},
+	MsgIDBadHostRateExceeded: {[]string{"_promiseHost"}, false},
+	MsgIDBadHostError:        {[]string{"_promiseHost"}, false},
+	MsgIDUsageHost:           {[]string{"_promiseHost"}, false},
+	MsgIDUsageUnhost:         {[]string{"_promiseUnhost"}, false},
+	MsgIDNotHosting:          {[]string{"_promiseUnhost"}, false},
+
+	// Room modes. Slow and followers-only are settled by ROOMSTATE instead,
+	// because only ROOMSTATE carries the delay.
+	MsgIDSubsOn:              {[]string{"_promiseSubscribers"}, true},
+	MsgIDAlreadySubsOn:       {[]string{"_promiseSubscribers"}, false},
+	MsgIDUsageSubsOn:         {[]string{"_promiseSubscribers"}, false},
+	MsgIDSubsOff:             {[]string{"_promiseSubscribersoff"}, true},
+	MsgIDAlreadySubsOff:      {[]string{"_promiseSubscribersoff"}, false},
+	MsgIDUsageSubsOff:        {[]string{"_promiseSubscribersoff"}, false},
+	MsgIDEmoteOnlyOn:         {[]string{"_promiseEmoteonly"}, true},
+	MsgIDAlreadyEmoteOnlyOn:  {[]string{"_promiseEmoteonly"}, false},
+	MsgIDUsageEmoteOnlyOn:    {[]string{"_promiseEmoteonly"}, false},
+	MsgIDEmoteOnlyOff:        {[]string{"_promiseEmoteonlyoff"}, true},
+	MsgIDAlreadyEmoteOnlyOff: {[]string{"_promiseEmoteonlyoff"}, false},
+	MsgIDUsageEmoteOnlyOff:   {[]string{"_promiseEmoteonlyoff"}, false},
+	MsgIDR9kOn:               {[]string{"_promiseR9kbeta"}, true},
+	MsgIDAlreadyR9kOn:        {[]string{"_promiseR9kbeta"}, false},
+	MsgIDUsageR9kOn:          {[]string{"_promiseR9kbeta"}, false},
+	MsgIDR9kOff:              {[]string{"_promiseR9kbetaoff"}, true},
+	MsgIDAlreadyR9kOff:       {[]string{"_promiseR9kbetaoff"}, false},
+	MsgIDUsageR9kOff:         {[]string{"_promiseR9kbetaoff"}, false},
+	MsgIDUsageSlowOn:         {[]string{"_promiseSlow"}, false},
+	MsgIDUsageSlowOff:        {[]string{"_promiseSlowoff"}, false},
+	MsgIDUsageFollowersOn:    {[]string{"_promiseFollowers"}, false},
+	MsgIDUsageFollowersOff:   {[]string{"_promiseFollowersoff"}, false},
+
+	// Whispers
+	MsgIDWhisperInvalidLogin:    {[]string{"_promiseWhisper"}, false},
+	MsgIDWhisperInvalidSelf:     {[]string{"_promiseWhisper"}, false},
+	MsgIDWhisperLimitPerMin:     {[]string{"_promiseWhisper"}, false},
+	MsgIDWhisperLimitPerSec:     {[]string{"_promiseWhisper"}, false},
+	MsgIDWhisperRestricted:      {[]string{"_promiseWhisper"}, false},
+	MsgIDWhisperRestrictedRecip: {[]string{"_promiseWhisper"}, false},
+
+	// Permission errors reject whatever was pending in the channel
+	MsgIDNoPermission:        {allPromiseEvents, false},
+	MsgIDMsgBanned:           {allPromiseEvents, false},
+	MsgIDMsgRoomNotFound:     {allPromiseEvents, false},
+	MsgIDMsgChannelSuspended: {allPromiseEvents, false},
+	MsgIDTosBan:              {allPromiseEvents, false},
+	MsgIDInvalidUser:         {allPromiseEvents, false},
+}
+
+// resolveNoticePromises settles the commands waiting on a NOTICE msg-id
+func (c *Client) resolveNoticePromises(channel string, msgid MsgID, msg string) {
+	switch msgid {
+	case MsgIDRoomMods:
+		c.Emit("_promiseMods", nil, channel, parseNoticeList(msg))
+		return
+	case MsgIDNoMods:
+		c.Emit("_promiseMods", nil, channel, []string{})
+		return
+	case MsgIDVipsSuccess:
+		c.Emit("_promiseVips", nil, channel, parseNoticeList(msg))
+		return
+	case MsgIDNoVips:
+		c.Emit("_promiseVips", nil, channel, []string{})
+		return
+	case MsgIDHostsRemaining:
+		remaining := 0
+		if len(msg) > 0 && IsInteger(msg[:1]) {
+			remaining = ParseInt(msg[:1])
+		}
+		c.Emit("_promiseHost", nil, channel, remaining)
+		return
+	}
+
+	promise, exists := noticePromises[msgid]
+	if !exists {
+		return
+	}
+
+	var result any
+	if !promise.success {
+		result = msgid
+	}
+
+	for _, event := range promise.events {
+		c.Emit(event, result, channel)
+	}
+}
+
+// parseNoticeList extracts the usernames from a NOTICE such as
+// "The moderators of this channel are: user1, user2"
+func parseNoticeList(msg string) []string {
+	msg = strings.TrimSuffix(msg, ".")
+
+	parts := strings.SplitN(msg, ": ", 2)
+	if len(parts) < 2 {
+		return []string{}
+	}
+
+	list := []string{}
+	for name := range strings.SplitSeq(strings.ToLower(parts[1]), ", ") {
+		if name != "" {
+			list = append(list, name)
+		}
+	}
+	return list
+}
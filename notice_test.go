@@ -0,0 +1,82 @@
+package tmigo
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHandleNotice_DedicatedEvents(t *testing.T) {
+	tests := []struct {
+		msgid MsgID
+		event string
+	}{
+		{MsgIDMsgRatelimit, "msgratelimit"},
+		{MsgIDMsgDuplicate, "msgduplicate"},
+		{MsgIDMsgBanned, "msgbanned"},
+		{MsgIDMsgSubsonly, "msgsubsonly"},
+		{MsgIDMsgEmoteonly, "msgemoteonly"},
+		{MsgIDMsgChannelSuspended, "channelsuspended"},
+		{MsgIDWhisperLimitPerMin, "whisperlimit"},
+		{MsgIDWhisperLimitPerSec, "whisperlimit"},
+		{MsgIDMsgRejected, "automod"},
+	}
+
+	for _, tt := range tests {
+		client := NewClient(nil)
+
+		var gotNotice MsgID
+		client.OnNotice(func(channel string, msgid MsgID, message string) {
+			gotNotice = msgid
+		})
+
+		var gotChannel string
+		client.On(tt.event, func(args ...any) {
+			gotChannel, _ = args[0].(string)
+		})
+
+		client.handleMessage(ParseMessage("@msg-id=" + string(tt.msgid) + " :tmi.twitch.tv NOTICE #test :Some notice"))
+
+		if gotNotice != tt.msgid {
+			t.Errorf("%s: notice msgid = %q, want %q", tt.msgid, gotNotice, tt.msgid)
+		}
+		if gotChannel != "#test" {
+			t.Errorf("%s: %s channel = %q, want #test", tt.msgid, tt.event, gotChannel)
+		}
+	}
+}
+
+func TestHandleNotice_ModsList(t *testing.T) {
+	client := NewClient(nil)
+
+	var gotMods []string
+	client.OnMods(func(channel string, mods []string) {
+		gotMods = mods
+	})
+
+	client.handleMessage(ParseMessage("@msg-id=room_mods :tmi.twitch.tv NOTICE #test :The moderators of this channel are: alice, bob"))
+
+	if !slices.Equal(gotMods, []string{"alice", "bob"}) {
+		t.Errorf("mods = %v, want [alice bob]", gotMods)
+	}
+	if !client.IsMod("test", "bob") {
+		t.Error("IsMod(test, bob) = false after room_mods")
+	}
+}
+
+func TestHandleNotice_AuthFailure(t *testing.T) {
+	client := NewClient(nil)
+
+	var gotReason string
+	client.OnAuthFailed(func(reason string) {
+		gotReason = reason
+	})
+
+	client.handleMessage(ParseMessage(":tmi.twitch.tv NOTICE * :Login authentication failed"))
+
+	if gotReason != "Login authentication failed" {
+		t.Errorf("reason = %q, want %q", gotReason, "Login authentication failed")
+	}
+	if client.state.reconnect {
+		t.Error("reconnect should be disabled after an authentication failure")
+	}
+}
@@ -0,0 +1,171 @@
+package tmigo
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// responseWaiter is a command waiting for one of the internal _promise* events.
+type responseWaiter struct {
+	match func(args []any) bool
+	ch    chan []any
+}
+
+// responseWaiters keeps track of the commands that are waiting on a response
+type responseWaiters struct {
+	mu      sync.Mutex
+	pending map[string][]*responseWaiter
+}
+
+func newResponseWaiters() *responseWaiters {
+	return &responseWaiters{
+		pending: make(map[string][]*responseWaiter),
+	}
+}
+
+// add registers a waiter for eventType. A nil match accepts any arguments.
+func (r *responseWaiters) add(eventType string, match func(args []any) bool) *responseWaiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := &responseWaiter{match: match, ch: make(chan []any, 1)}
+	r.pending[eventType] = append(r.pending[eventType], w)
+	return w
+}
+
+// remove unregisters a waiter that is no longer interested in a response
+func (r *responseWaiters) remove(eventType string, w *responseWaiter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending[eventType] = slices.DeleteFunc(r.pending[eventType], func(p *responseWaiter) bool {
+		return p == w
+	})
+	if len(r.pending[eventType]) == 0 {
+		delete(r.pending, eventType)
+	}
+}
+
+// resolve hands args to every waiter on eventType that accepts them
+func (r *responseWaiters) resolve(eventType string, args []any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	waiters, exists := r.pending[eventType]
+	if !exists {
+		return
+	}
+
+	remaining := waiters[:0]
+	for _, w := range waiters {
+		if w.match != nil && !w.match(args) {
+			remaining = append(remaining, w)
+			continue
+		}
+		w.ch <- args
+	}
+
+	if len(remaining) == 0 {
+		delete(r.pending, eventType)
+	} else {
+		r.pending[eventType] = remaining
+	}
+}
+
+// Emit resolves commands waiting on eventType and then triggers the event,
+// right away or on a dispatch worker when Dispatch.Async is set
+func (c *Client) Emit(eventType string, args ...any) bool {
+	return c.emit(nil, eventType, args)
+}
+
+// Emits triggers multiple events with corresponding argument sets
+func (c *Client) Emits(types []string, values [][]any) {
+	emitEach(types, values, c.Emit)
+}
+
+// emitFrom emits an event caused by raw, which event structs expose
+func (c *Client) emitFrom(raw *IRCMessage, eventType string, args ...any) bool {
+	return c.emit(raw, eventType, args)
+}
+
+// emitsFrom emits multiple events caused by raw
+func (c *Client) emitsFrom(raw *IRCMessage, types []string, values [][]any) {
+	emitEach(types, values, func(eventType string, args ...any) bool {
+		return c.emit(raw, eventType, args)
+	})
+}
+
+func (c *Client) emit(raw *IRCMessage, eventType string, args []any) bool {
+	return c.emitMeta(newEventMeta(raw), eventType, args)
+}
+
+// emitMeta resolves commands waiting on eventType and dispatches an event
+// whose metadata is already known, like one forwarded from a shard
+func (c *Client) emitMeta(m EventMeta, eventType string, args []any) bool {
+	c.waiters.resolve(eventType, args)
+	handled := c.ListenerCount(eventType) > 0
+	if m.Raw != nil && c.dispatch.opts.Async {
+		// The read loop goes on updating the message while workers run
+		m.Raw, args = snapshotMessage(m.Raw, args)
+	}
+	c.dispatch.dispatch(dispatchKey(args), eventType, func() {
+		c.emitListeners(m, eventType, args)
+	})
+	return handled
+}
+
+// emitListeners calls the listeners of an event and forwards it
+func (c *Client) emitListeners(m EventMeta, eventType string, args []any) {
+	c.EventEmitter.emit(eventType, m, args)
+	if c.forward != nil {
+		c.forward(m, eventType, args)
+	}
+}
+
+// awaitResponse blocks until w is resolved, the timeout fires or ctx is done.
+// The returned MsgID is set when Twitch answered with a failure msg-id.
+func (c *Client) awaitResponse(ctx context.Context, eventType string, w *responseWaiter, timeout time.Duration) ([]any, MsgID, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case args := <-w.ch:
+		return args, responseMsgID(args), nil
+	case <-timer.C:
+		c.waiters.remove(eventType, w)
+		return nil, "", ErrNoResponse
+	case <-ctx.Done():
+		c.waiters.remove(eventType, w)
+		return nil, "", ctx.Err()
+	}
+}
+
+// responseMsgID extracts the failure msg-id from the first argument of a
+// _promise* event. Successful responses carry nil there.
+func responseMsgID(args []any) MsgID {
+	if len(args) == 0 {
+		return ""
+	}
+
+	switch v := args[0].(type) {
+	case MsgID:
+		return v
+	case string:
+		return MsgID(v)
+	}
+
+	return ""
+}
+
+// matchChannel accepts _promise* events whose second argument is channel
+func matchChannel(channel string) func(args []any) bool {
+	return func(args []any) bool {
+		if len(args) < 2 {
+			return false
+		}
+		ch, ok := args[1].(string)
+		return ok && Channel(ch) == channel
+	}
+}
@@ -0,0 +1,134 @@
+package tmigo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAwaitResponse_Success(t *testing.T) {
+	client := NewClient(nil)
+	w := client.waiters.add("_promiseSlow", matchChannel("#test"))
+
+	go client.Emit("_promiseSlow", nil, "#test")
+
+	_, msgid, err := client.awaitResponse(context.Background(), "_promiseSlow", w, time.Second)
+	if err != nil {
+		t.Fatalf("awaitResponse() error = %v", err)
+	}
+	if msgid != "" {
+		t.Errorf("msgid = %q, want empty", msgid)
+	}
+}
+
+func TestAwaitResponse_Failure(t *testing.T) {
+	client := NewClient(nil)
+	w := client.waiters.add("_promiseBan", matchChannel("#test"))
+
+	go client.resolveNoticePromises("#test", MsgIDBadBanMod, "You cannot ban moderator x.")
+
+	_, msgid, err := client.awaitResponse(context.Background(), "_promiseBan", w, time.Second)
+	if err != nil {
+		t.Fatalf("awaitResponse() error = %v", err)
+	}
+	if msgid != MsgIDBadBanMod {
+		t.Errorf("msgid = %q, want %q", msgid, MsgIDBadBanMod)
+	}
+}
+
+func TestAwaitResponse_IgnoresOtherChannels(t *testing.T) {
+	client := NewClient(nil)
+	w := client.waiters.add("_promiseClear", matchChannel("#mine"))
+
+	client.Emit("_promiseClear", nil, "#other")
+
+	_, _, err := client.awaitResponse(context.Background(), "_promiseClear", w, 50*time.Millisecond)
+	if !errors.Is(err, ErrNoResponse) {
+		t.Errorf("error = %v, want ErrNoResponse", err)
+	}
+	if len(client.waiters.pending) != 0 {
+		t.Errorf("pending waiters = %d, want 0 after timeout", len(client.waiters.pending))
+	}
+}
+
+func TestAwaitResponse_ContextCanceled(t *testing.T) {
+	client := NewClient(nil)
+	w := client.waiters.add("_promiseMods", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := client.awaitResponse(ctx, "_promiseMods", w, time.Second)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+func TestResolveNoticePromises_ModsList(t *testing.T) {
+	client := NewClient(nil)
+	w := client.waiters.add("_promiseMods", matchChannel("#test"))
+
+	client.resolveNoticePromises("#test", MsgIDRoomMods, "The moderators of this channel are: Alice, bob")
+
+	args := <-w.ch
+	mods, _ := args[2].([]string)
+	if !slices.Equal(mods, []string{"alice", "bob"}) {
+		t.Errorf("mods = %v, want [alice bob]", mods)
+	}
+}
+
+func TestSendCommandWithResponse_NotConnected(t *testing.T) {
+	client := NewClient(nil)
+
+	err := client.SlowContext(context.Background(), "test", 30)
+	if !errors.Is(err, ErrNotConnected) {
+		t.Errorf("error = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestPingContext_NotConnected(t *testing.T) {
+	client := NewClient(nil)
+	latency := client.state.latency
+
+	if _, err := client.PingContext(context.Background()); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("error = %v, want ErrNotConnected", err)
+	}
+	if client.state.pingTimeout != nil || !client.state.latency.Equal(latency) {
+		t.Error("PingContext() armed the ping timeout without a connection")
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	var err error = &CommandError{Command: "/ban x", Channel: "#test", MsgID: MsgIDBadBanMod}
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatal("errors.As failed for *CommandError")
+	}
+	if cmdErr.MsgID != MsgIDBadBanMod {
+		t.Errorf("MsgID = %q, want %q", cmdErr.MsgID, MsgIDBadBanMod)
+	}
+	if err.Error() != "[#test] /ban x failed: bad_ban_mod" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+func TestParseNoticeList(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want []string
+	}{
+		{"The moderators of this channel are: user1, User2", []string{"user1", "user2"}},
+		{"The VIPs of this channel are: vip1, vip2.", []string{"vip1", "vip2"}},
+		{"There are no moderators of this channel.", []string{}},
+	}
+
+	for _, tt := range tests {
+		got := parseNoticeList(tt.msg)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseNoticeList(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package tmigo
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// proxyFromEnvironment is replaced by tests, as http.ProxyFromEnvironment
+// reads the environment only once
+var proxyFromEnvironment = http.ProxyFromEnvironment
+
+// environmentProxy returns the proxy the environment sets for a websocket
+// connection to addr, or nil when there is none
+func environmentProxy(secure bool, addr string) (*url.URL, error) {
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+	return proxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: scheme, Host: addr}})
+}
+
+// dialTCP opens a TCP connection to addr, through Connection.Proxy when one
+// is configured
+func dialTCP(ctx context.Context, opts *Connection, addr string) (net.Conn, error) {
+	dialer := opts.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	proxy := opts.Proxy
+	if proxy == nil {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	switch proxy.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr(proxy))
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+
+	// Bound the proxy handshake by the dial context
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	tunnel := conn
+	if proxy.Scheme == "http" {
+		tunnel, err = httpConnect(conn, proxy, addr)
+	} else {
+		err = socksConnect(conn, proxy, addr)
+	}
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("proxy: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+
+	if !stop() {
+		conn.Close()
+		return nil, fmt.Errorf("proxy: %w", ctx.Err())
+	}
+	conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// proxyAddr returns the proxy's host:port, applying the scheme's default port
+func proxyAddr(proxy *url.URL) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+	port := "1080"
+	if proxy.Scheme == "http" {
+		port = "80"
+	}
+	return net.JoinHostPort(proxy.Hostname(), port)
+}
+
+// bufferedConn returns bytes read ahead while parsing a proxy response before
+// reading from the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// httpConnect opens a tunnel to addr with an HTTP CONNECT request
+func httpConnect(conn net.Conn, proxy *url.URL, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxy.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CONNECT %s: %s", addr, resp.Status)
+	}
+
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// SOCKS5 protocol values from RFC 1928 and RFC 1929
+const (
+	socks5Version      = 0x05
+	socks5NoAuth       = 0x00
+	socks5PasswordAuth = 0x02
+	socks5NoAcceptable = 0xff
+	socks5CmdConnect   = 0x01
+	socks5IPv4         = 0x01
+	socks5Domain       = 0x03
+	socks5IPv6         = 0x04
+)
+
+// socksConnect asks a SOCKS5 proxy to connect to addr. Host names are
+// resolved by the proxy.
+func socksConnect(conn net.Conn, proxy *url.URL, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+
+	methods := []byte{socks5NoAuth}
+	if proxy.User != nil {
+		methods = append(methods, socks5PasswordAuth)
+	}
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS version %d", reply[0])
+	}
+
+	switch reply[1] {
+	case socks5NoAuth:
+	case socks5PasswordAuth:
+		if proxy.User == nil {
+			return errors.New("SOCKS5 proxy requires authentication")
+		}
+		if err := socks5Authenticate(conn, proxy.User); err != nil {
+			return err
+		}
+	case socks5NoAcceptable:
+		return errors.New("SOCKS5 proxy accepted no authentication method")
+	default:
+		return fmt.Errorf("unsupported SOCKS5 authentication method %d", reply[1])
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(append(req, socks5IPv4), ip4...)
+		} else {
+			req = append(append(req, socks5IPv6), ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %s", host)
+		}
+		req = append(append(req, socks5Domain, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		return fmt.Errorf("SOCKS5 connect to %s failed with code %d", addr, header[1])
+	}
+
+	// Skip the bound address and port
+	var skip int
+	switch header[3] {
+	case socks5IPv4:
+		skip = net.IPv4len + 2
+	case socks5IPv6:
+		skip = net.IPv6len + 2
+	case socks5Domain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return err
+		}
+		skip = int(size[0]) + 2
+	default:
+		return fmt.Errorf("unknown SOCKS5 address type %d", header[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip))
+	return err
+}
+
+// socks5Authenticate performs username/password authentication
+func socks5Authenticate(conn net.Conn, user *url.Userinfo) error {
+	username := user.Username()
+	password, _ := user.Password()
+	if len(username) > 255 || len(password) > 255 {
+		return errors.New("SOCKS5 credentials too long")
+	}
+
+	req := []byte{0x01, byte(len(username))}
+	req = append(req, username...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0x00 {
+		return errors.New("SOCKS5 authentication failed")
+	}
+	return nil
+}
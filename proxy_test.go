@@ -0,0 +1,375 @@
+package tmigo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ktnuity/tmigo/tmigotest"
+)
+
+// testProxy is a local proxy stand-in that counts the tunnels it opened
+type testProxy struct {
+	listener net.Listener
+	mu       sync.Mutex
+	tunnels  int
+	targets  []string
+}
+
+func newTestProxy(t *testing.T, handshake func(conn net.Conn, reader *bufio.Reader) (string, bool)) *testProxy {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	p := &testProxy{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(t, conn, handshake)
+		}
+	}()
+
+	return p
+}
+
+func (p *testProxy) serve(t *testing.T, conn net.Conn, handshake func(net.Conn, *bufio.Reader) (string, bool)) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	target, ok := handshake(conn, reader)
+	if !ok {
+		return
+	}
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	p.mu.Lock()
+	p.tunnels++
+	p.targets = append(p.targets, target)
+	p.mu.Unlock()
+
+	go io.Copy(upstream, reader)
+	io.Copy(conn, upstream)
+}
+
+func (p *testProxy) url(scheme string, user *url.Userinfo) *url.URL {
+	return &url.URL{Scheme: scheme, Host: p.listener.Addr().String(), User: user}
+}
+
+func (p *testProxy) tunnelCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tunnels
+}
+
+// httpProxyHandshake accepts CONNECT requests with the given basic auth
+func httpProxyHandshake(username, password string) func(net.Conn, *bufio.Reader) (string, bool) {
+	return func(conn net.Conn, reader *bufio.Reader) (string, bool) {
+		req, err := http.ReadRequest(reader)
+		if err != nil || req.Method != http.MethodConnect {
+			return "", false
+		}
+
+		auth := (&http.Request{Header: http.Header{"Authorization": req.Header["Proxy-Authorization"]}})
+		user, pass, _ := auth.BasicAuth()
+		if user != username || pass != password {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return "", false
+		}
+
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		return req.Host, true
+	}
+}
+
+// socks5ProxyHandshake accepts SOCKS5 CONNECT requests with the given
+// username and password
+func socks5ProxyHandshake(username, password string) func(net.Conn, *bufio.Reader) (string, bool) {
+	return func(conn net.Conn, reader *bufio.Reader) (string, bool) {
+		greeting := make([]byte, 2)
+		if _, err := io.ReadFull(reader, greeting); err != nil {
+			return "", false
+		}
+		if _, err := io.ReadFull(reader, make([]byte, greeting[1])); err != nil {
+			return "", false
+		}
+		conn.Write([]byte{5, 2})
+
+		version, _ := reader.ReadByte()
+		userLen, _ := reader.ReadByte()
+		user := make([]byte, userLen)
+		io.ReadFull(reader, user)
+		passLen, _ := reader.ReadByte()
+		pass := make([]byte, passLen)
+		io.ReadFull(reader, pass)
+		if version != 1 || string(user) != username || string(pass) != password {
+			conn.Write([]byte{1, 1})
+			return "", false
+		}
+		conn.Write([]byte{1, 0})
+
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return "", false
+		}
+		var host string
+		switch header[3] {
+		case 1:
+			ip := make([]byte, 4)
+			io.ReadFull(reader, ip)
+			host = net.IP(ip).String()
+		case 3:
+			size, _ := reader.ReadByte()
+			name := make([]byte, size)
+			io.ReadFull(reader, name)
+			host = string(name)
+		default:
+			return "", false
+		}
+		port := make([]byte, 2)
+		io.ReadFull(reader, port)
+
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), true
+	}
+}
+
+// connectThrough connects a client to srv with extra connection options,
+// waits for the first join and returns the client
+func connectThrough(t *testing.T, srv *tmigotest.Server, configure func(*Connection)) *Client {
+	t.Helper()
+
+	connection := &Connection{
+		Server:            srv.Host(),
+		Port:              srv.Port(),
+		ReconnectInterval: 10 * time.Millisecond,
+	}
+	configure(connection)
+	client := NewClient(&ClientOptions{
+		Connection: connection,
+		Identity:   &Identity{Username: "bot", Password: "oauth:token"},
+		Channels:   []string{"#a"},
+	})
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	wait()
+
+	return client
+}
+
+func TestClient_Proxy(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheme    string
+		handshake func(net.Conn, *bufio.Reader) (string, bool)
+		transport Transport
+	}{
+		{"http websocket", "http", httpProxyHandshake("user", "pass"), WebSocketTransport{}},
+		{"http tcp", "http", httpProxyHandshake("user", "pass"), TCPTransport{}},
+		{"socks5 websocket", "socks5", socks5ProxyHandshake("user", "pass"), WebSocketTransport{}},
+		{"socks5 tcp", "socks5h", socks5ProxyHandshake("user", "pass"), TCPTransport{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var addr string
+			var srv *tmigotest.Server
+			if _, ok := tt.transport.(TCPTransport); ok {
+				addr = newTCPIRCServer(t, nil).String()
+			} else {
+				srv = tmigotest.NewServer()
+				t.Cleanup(srv.Close)
+				addr = net.JoinHostPort(srv.Host(), strconv.Itoa(srv.Port()))
+			}
+			host, portStr, _ := net.SplitHostPort(addr)
+			port, _ := strconv.Atoi(portStr)
+
+			proxy := newTestProxy(t, tt.handshake)
+			client := NewClient(&ClientOptions{
+				Connection: &Connection{
+					Server:            host,
+					Port:              port,
+					Transport:         tt.transport,
+					Proxy:             proxy.url(tt.scheme, url.UserPassword("user", "pass")),
+					ReconnectInterval: 10 * time.Millisecond,
+				},
+				Identity: &Identity{Username: "bot", Password: "oauth:token"},
+				Channels: []string{"#a"},
+			})
+
+			wait := waitFor(t, client, "roomstate", 1)
+			if err := client.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer client.Disconnect()
+			wait()
+
+			if n := proxy.tunnelCount(); n != 1 {
+				t.Errorf("proxy opened %d tunnels, want 1", n)
+			}
+			if got := proxy.targets[0]; got != addr {
+				t.Errorf("proxy target = %s, want %s", got, addr)
+			}
+		})
+	}
+}
+
+func TestClient_ProxyOnReconnect(t *testing.T) {
+	srv := tmigotest.NewServer()
+	t.Cleanup(srv.Close)
+	proxy := newTestProxy(t, httpProxyHandshake("", ""))
+
+	client := connectThrough(t, srv, func(c *Connection) {
+		c.Proxy = proxy.url("http", nil)
+	})
+
+	wait := waitFor(t, client, "roomstate", 1)
+	srv.Drop()
+	wait()
+
+	if n := proxy.tunnelCount(); n != 2 {
+		t.Errorf("proxy opened %d tunnels, want 2", n)
+	}
+}
+
+func TestClient_ProxyFromEnvironment(t *testing.T) {
+	srv := tmigotest.NewServer()
+	t.Cleanup(srv.Close)
+	proxy := newTestProxy(t, httpProxyHandshake("", ""))
+
+	var requested *url.URL
+	proxyFromEnvironment = func(req *http.Request) (*url.URL, error) {
+		requested = req.URL
+		return proxy.url("http", nil), nil
+	}
+	t.Cleanup(func() { proxyFromEnvironment = http.ProxyFromEnvironment })
+
+	connectThrough(t, srv, func(c *Connection) {})
+
+	if n := proxy.tunnelCount(); n != 1 {
+		t.Errorf("proxy opened %d tunnels, want 1", n)
+	}
+	if want := net.JoinHostPort(srv.Host(), strconv.Itoa(srv.Port())); requested == nil || requested.Scheme != "http" || requested.Host != want {
+		t.Errorf("proxy looked up for %v, want http://%s", requested, want)
+	}
+}
+
+func TestClient_ProxyRejected(t *testing.T) {
+	srv := tmigotest.NewServer()
+	t.Cleanup(srv.Close)
+	proxy := newTestProxy(t, httpProxyHandshake("user", "pass"))
+
+	client := NewClient(&ClientOptions{
+		Connection: &Connection{
+			Server: srv.Host(),
+			Port:   srv.Port(),
+			Proxy:  proxy.url("http", url.UserPassword("user", "wrong")),
+		},
+	})
+
+	if err := client.Connect(); err == nil {
+		client.Disconnect()
+		t.Fatal("Connect() error = nil through a proxy that rejects the credentials")
+	}
+}
+
+func TestDialTCP_UnsupportedProxy(t *testing.T) {
+	opts := &Connection{Proxy: &url.URL{Scheme: "ftp", Host: "127.0.0.1:21"}}
+
+	if _, err := dialTCP(t.Context(), opts, "127.0.0.1:80"); err == nil {
+		t.Error("dialTCP() error = nil for an ftp proxy")
+	}
+}
+
+func TestClient_TLSConfigAndHeader(t *testing.T) {
+	srv := tmigotest.NewTLSServer()
+	t.Cleanup(srv.Close)
+
+	connectThrough(t, srv, func(c *Connection) {
+		c.Secure = true
+		c.TLSConfig = srv.TLSConfig()
+		c.Header = http.Header{"X-Bot": []string{"tmigo"}}
+	})
+
+	if got := srv.RequestHeader().Get("X-Bot"); got != "tmigo" {
+		t.Errorf("handshake header X-Bot = %q, want tmigo", got)
+	}
+}
+
+func TestClient_TLSUntrusted(t *testing.T) {
+	srv := tmigotest.NewTLSServer()
+	t.Cleanup(srv.Close)
+
+	client := NewClient(&ClientOptions{
+		Connection: &Connection{Server: srv.Host(), Port: srv.Port(), Secure: true},
+	})
+
+	if err := client.Connect(); err == nil {
+		client.Disconnect()
+		t.Fatal("Connect() error = nil for a server with an untrusted certificate")
+	}
+}
+
+func TestClient_HandshakeTimeout(t *testing.T) {
+	// Accept connections but never answer the handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	client := NewClient(&ClientOptions{
+		Connection: &Connection{
+			Server:           addr.IP.String(),
+			Port:             addr.Port,
+			HandshakeTimeout: 50 * time.Millisecond,
+		},
+	})
+
+	start := time.Now()
+	err = client.Connect()
+	if err == nil {
+		client.Disconnect()
+		t.Fatal("Connect() error = nil for a server that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Connect() took %v, want about 50ms", elapsed)
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Connect() error = %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,211 @@
+package tmigo
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// tokenBucket holds up to one window of messages and refills continuously.
+// Tokens go negative for reservations that have to wait. The shards of a
+// ShardedClient share their buckets, so a bucket locks itself.
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	interval time.Duration // time to refill one token
+	last     time.Time
+}
+
+func newTokenBucket(tier RateLimitTier, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(tier.Messages),
+		tokens:   float64(tier.Messages),
+		interval: tier.Per / time.Duration(tier.Messages),
+		last:     now,
+	}
+}
+
+// take removes a token and returns how long until it is covered
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.capacity, b.tokens+float64(elapsed)/float64(b.interval))
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens * float64(b.interval))
+}
+
+// put returns a token taken by a reservation that was given up
+func (b *tokenBucket) put() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+}
+
+// rateLimiter paces outbound chat messages. Every message takes a token from
+// the shared bucket; messages to channels where the bot is not a moderator
+// also take one from the normal bucket. Verified bots only use the shared
+// bucket.
+type rateLimiter struct {
+	mu       sync.Mutex
+	policy   RateLimitPolicy
+	maxQueue int
+	shared   *tokenBucket
+	normal   *tokenBucket
+	queued   int
+	done     chan struct{}
+	now      func() time.Time
+}
+
+// newRateLimiter creates a limiter from the options, or returns nil when rate
+// limiting is disabled
+func newRateLimiter(opts *RateLimit) *rateLimiter {
+	if opts.Disabled {
+		return nil
+	}
+
+	if opts.Policy == "" {
+		opts.Policy = RateLimitQueue
+	}
+	if opts.MaxQueue == 0 {
+		opts.MaxQueue = 100
+	}
+	if opts.NormalTier.Messages == 0 || opts.NormalTier.Per == 0 {
+		opts.NormalTier = RateLimitTier{Messages: 20, Per: 30 * time.Second}
+	}
+	if opts.ModeratorTier.Messages == 0 || opts.ModeratorTier.Per == 0 {
+		opts.ModeratorTier = RateLimitTier{Messages: 100, Per: 30 * time.Second}
+	}
+	if opts.VerifiedTier.Messages == 0 || opts.VerifiedTier.Per == 0 {
+		opts.VerifiedTier = RateLimitTier{Messages: 7500, Per: 30 * time.Second}
+	}
+
+	now := time.Now()
+	l := &rateLimiter{
+		policy:   opts.Policy,
+		maxQueue: opts.MaxQueue,
+		done:     make(chan struct{}),
+		now:      time.Now,
+	}
+
+	if opts.Verified {
+		l.shared = newTokenBucket(opts.VerifiedTier, now)
+	} else {
+		l.shared = newTokenBucket(opts.ModeratorTier, now)
+		l.normal = newTokenBucket(opts.NormalTier, now)
+	}
+
+	return l
+}
+
+// reserve takes the tokens for one message and returns how long the caller
+// has to wait before sending it. A non-empty DropReason means the message
+// must not be sent. Callers that wait must call dequeue afterwards.
+func (l *rateLimiter) reserve(moderator bool) (time.Duration, <-chan struct{}, DropReason) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	wait := l.shared.take(now)
+	useNormal := l.normal != nil && !moderator
+	if useNormal {
+		wait = max(wait, l.normal.take(now))
+	}
+
+	if wait <= 0 {
+		return 0, nil, ""
+	}
+
+	var reason DropReason
+	if l.policy == RateLimitReject {
+		reason = DropReasonRateLimited
+	} else if l.queued >= l.maxQueue {
+		reason = DropReasonQueueFull
+	}
+
+	if reason != "" {
+		l.shared.put()
+		if useNormal {
+			l.normal.put()
+		}
+		return 0, nil, reason
+	}
+
+	l.queued++
+	return wait, l.done, ""
+}
+
+// dequeue marks a waiting message as sent or dropped
+func (l *rateLimiter) dequeue() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queued--
+}
+
+// flush releases every waiting message once the client stops reconnecting
+func (l *rateLimiter) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	close(l.done)
+	l.done = make(chan struct{})
+}
+
+// throttle blocks until a chat message may be sent under the rate limit. It
+// emits messagedropped and returns an error when the message is given up.
+func (c *Client) throttle(channel, message string) error {
+	if c.limiter == nil {
+		return nil
+	}
+
+	wait, done, reason := c.limiter.reserve(c.isModerator(channel))
+	if reason != "" {
+		c.state.log.Warn(fmt.Sprintf("[%s] Message dropped: %s", channel, reason))
+		c.Emit("messagedropped", channel, message, reason)
+		return ErrRateLimited
+	}
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	defer c.limiter.dequeue()
+
+	select {
+	case <-timer.C:
+		// Messages that were waiting when the connection dropped are sent
+		// on the next one
+		if !c.isConnected() && !c.awaitReconnect() {
+			c.Emit("messagedropped", channel, message, DropReasonDisconnected)
+			return ErrNotConnected
+		}
+		return nil
+	case <-done:
+		c.Emit("messagedropped", channel, message, DropReasonDisconnected)
+		return ErrNotConnected
+	}
+}
+
+// isModerator reports whether the bot is a moderator or the broadcaster in a
+// channel
+func (c *Client) isModerator(channel string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if channel == Channel(c.state.username) {
+		return true
+	}
+	if userstate, ok := c.state.userState[channel]; ok && userstate.Mod {
+		return true
+	}
+	return slices.Contains(c.state.moderators[channel], c.state.username)
+}
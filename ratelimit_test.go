@@ -0,0 +1,140 @@
+package tmigo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(opts *RateLimit, now *time.Time) *rateLimiter {
+	l := newRateLimiter(opts)
+	l.now = func() time.Time { return *now }
+	l.shared.last = *now
+	if l.normal != nil {
+		l.normal.last = *now
+	}
+	return l
+}
+
+func TestRateLimiter_NormalTier(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(&RateLimit{}, &now)
+
+	for i := range 20 {
+		if wait, _, reason := l.reserve(false); wait != 0 || reason != "" {
+			t.Fatalf("message %d: wait = %v, reason = %q, want immediate send", i, wait, reason)
+		}
+	}
+
+	wait, _, reason := l.reserve(false)
+	if reason != "" {
+		t.Fatalf("reason = %q, want queued", reason)
+	}
+	if wait != 1500*time.Millisecond {
+		t.Errorf("wait = %v, want 1.5s", wait)
+	}
+}
+
+func TestRateLimiter_ModeratorTier(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(&RateLimit{}, &now)
+
+	for i := range 100 {
+		if wait, _, _ := l.reserve(true); wait != 0 {
+			t.Fatalf("moderator message %d waited %v", i, wait)
+		}
+	}
+
+	if wait, _, _ := l.reserve(true); wait == 0 {
+		t.Error("moderator message 101 was not delayed")
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(&RateLimit{Policy: RateLimitReject}, &now)
+
+	for range 20 {
+		l.reserve(false)
+	}
+	if _, _, reason := l.reserve(false); reason != DropReasonRateLimited {
+		t.Fatalf("reason = %q, want %q", reason, DropReasonRateLimited)
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if wait, _, reason := l.reserve(false); wait != 0 || reason != "" {
+		t.Errorf("after refill: wait = %v, reason = %q, want immediate send", wait, reason)
+	}
+}
+
+func TestRateLimiter_QueueFull(t *testing.T) {
+	now := time.Now()
+	l := newTestLimiter(&RateLimit{MaxQueue: 1, NormalTier: RateLimitTier{Messages: 1, Per: time.Second}}, &now)
+
+	l.reserve(false)
+	if _, _, reason := l.reserve(false); reason != "" {
+		t.Fatalf("reason = %q, want queued", reason)
+	}
+	if _, _, reason := l.reserve(false); reason != DropReasonQueueFull {
+		t.Errorf("reason = %q, want %q", reason, DropReasonQueueFull)
+	}
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	if l := newRateLimiter(&RateLimit{Disabled: true}); l != nil {
+		t.Error("newRateLimiter() != nil for a disabled limiter")
+	}
+}
+
+func TestThrottle_EmitsMessageDropped(t *testing.T) {
+	client := NewClient(&ClientOptions{
+		RateLimit: &RateLimit{Policy: RateLimitReject, NormalTier: RateLimitTier{Messages: 1, Per: time.Minute}},
+	})
+
+	var gotReason DropReason
+	client.OnMessageDropped(func(channel string, message string, reason DropReason) {
+		gotReason = reason
+	})
+
+	if err := client.throttle("#test", "first"); err != nil {
+		t.Fatalf("first throttle() error = %v", err)
+	}
+	if err := client.throttle("#test", "second"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("second throttle() error = %v, want ErrRateLimited", err)
+	}
+	if gotReason != DropReasonRateLimited {
+		t.Errorf("reason = %q, want %q", gotReason, DropReasonRateLimited)
+	}
+}
+
+func TestThrottle_FlushDropsQueued(t *testing.T) {
+	client := NewClient(&ClientOptions{
+		RateLimit: &RateLimit{NormalTier: RateLimitTier{Messages: 1, Per: time.Minute}},
+	})
+	client.throttle("#test", "first")
+
+	errc := make(chan error)
+	go func() {
+		errc <- client.throttle("#test", "second")
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	client.limiter.flush()
+
+	if err := <-errc; !errors.Is(err, ErrNotConnected) {
+		t.Errorf("throttle() error = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestIsModerator(t *testing.T) {
+	client := NewClient(&ClientOptions{Identity: &Identity{Username: "bot"}})
+	client.state.userState["#modded"] = UserState{Mod: true}
+	client.state.moderators["#listed"] = []string{"bot"}
+
+	tests := map[string]bool{"#bot": true, "#modded": true, "#listed": true, "#other": false}
+	for channel, want := range tests {
+		if got := client.isModerator(channel); got != want {
+			t.Errorf("isModerator(%q) = %v, want %v", channel, got, want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package tmigo
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// reconnectPlan is the outcome of planReconnect, announced once c.mu is
+// released
+type reconnectPlan struct {
+	reconnect bool
+	maxed     bool
+	attempt   int
+	delay     time.Duration
+	cause     DisconnectReason
+	reason    string
+	timer     *time.Timer
+	change    stateChange
+}
+
+// planReconnect decides whether to reconnect after the connection was lost
+// and picks the delay from the backoff policy. The reconnect timer is created
+// stopped and started by announceReconnect once the ReconnectHook agreed.
+// Until the reconnect finishes or is given up, c.state.reconnected stays open
+// so pending outbound messages can wait for the next connection. The client
+// moves to Reconnecting, or to Closed without a reconnect. c.mu must be held.
+func (c *Client) planReconnect(cause DisconnectReason, reason string) reconnectPlan {
+	// A session that stayed up long enough starts the backoff over
+	if !c.state.loggedOnAt.IsZero() {
+		if time.Since(c.state.loggedOnAt) >= c.state.opts.Connection.BackoffReset {
+			c.state.reconnections = 0
+			c.state.reconnectDelay = 0
+		}
+		c.state.loggedOnAt = time.Time{}
+	}
+
+	plan := reconnectPlan{
+		reconnect: c.state.reconnect && c.state.reconnections < c.state.maxReconnectAttempts,
+		maxed:     c.state.reconnections >= c.state.maxReconnectAttempts,
+		cause:     cause,
+		reason:    reason,
+	}
+	if !plan.reconnect {
+		c.endReconnect()
+		plan.change, _ = c.transition(StateClosed, cause)
+		return plan
+	}
+	plan.change, _ = c.transition(StateReconnecting, cause)
+
+	if c.state.reconnected == nil {
+		c.state.reconnected = make(chan struct{})
+	}
+	c.state.reconnections++
+	plan.attempt = c.state.reconnections
+	plan.delay = max(c.state.opts.Connection.Backoff.Next(plan.attempt, c.state.reconnectDelay), 0)
+	c.state.reconnectDelay = plan.delay
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Hour, func() {
+		c.mu.Lock()
+		if c.state.reconnectAfter != timer {
+			// Canceled by Disconnect or Connect
+			c.mu.Unlock()
+			return
+		}
+		c.state.reconnectAfter = nil
+		change, err := c.transition(StateConnecting, "")
+		c.mu.Unlock()
+		if err != nil {
+			return
+		}
+
+		c.announceState(change)
+		c.openConnection(context.Background())
+	})
+	timer.Stop()
+	c.state.reconnectAfter = timer
+	plan.timer = timer
+
+	return plan
+}
+
+// announceReconnect consults the ReconnectHook, starts the reconnect timer
+// and emits the events for a reconnect plan. Messages waiting for a
+// connection are dropped when there will be no reconnect.
+func (c *Client) announceReconnect(plan reconnectPlan) {
+	if plan.reconnect {
+		delay := plan.delay
+		if hook := c.state.opts.Connection.ReconnectHook; hook != nil {
+			var ok bool
+			if delay, ok = hook(plan.attempt, delay, plan.reason); !ok {
+				c.state.log.Info("Reconnect canceled by the reconnect hook.")
+				c.abandonReconnect(plan)
+				return
+			}
+		}
+
+		c.mu.Lock()
+		armed := c.state.reconnectAfter == plan.timer
+		if armed {
+			plan.timer.Reset(delay)
+		}
+		c.mu.Unlock()
+		if !armed {
+			// Disconnect or Connect was called in the meantime
+			return
+		}
+
+		c.state.log.Error(fmt.Sprintf("Reconnecting in %v..", delay))
+		c.Emit("reconnect")
+		return
+	}
+
+	if c.limiter != nil {
+		c.limiter.flush()
+	}
+	if plan.maxed {
+		c.Emit("maxreconnect")
+		c.state.log.Error("Maximum reconnection attempts reached.")
+	}
+}
+
+// abandonReconnect gives up a planned reconnect that has not been replaced
+// and closes the client
+func (c *Client) abandonReconnect(plan reconnectPlan) {
+	c.mu.Lock()
+	if c.state.reconnectAfter != plan.timer {
+		c.mu.Unlock()
+		return
+	}
+	c.cancelReconnect()
+	c.endReconnect()
+	change, _ := c.transition(StateClosed, plan.cause)
+	c.mu.Unlock()
+
+	c.announceState(change)
+
+	if c.limiter != nil {
+		c.limiter.flush()
+	}
+}
+
+// cancelReconnect stops a scheduled reconnect and reports whether there was
+// one. c.mu must be held.
+func (c *Client) cancelReconnect() bool {
+	if c.state.reconnectAfter == nil {
+		return false
+	}
+	c.state.reconnectAfter.Stop()
+	c.state.reconnectAfter = nil
+	return true
+}
+
+// endReconnect releases everything waiting for a reconnect, either because
+// the client is connected again or because it gave up. Lost messages not
+// taken for sending by then fail. c.mu must be held.
+func (c *Client) endReconnect() {
+	if c.state.reconnected != nil {
+		close(c.state.reconnected)
+		c.state.reconnected = nil
+	}
+	for _, u := range c.state.unsent {
+		u.result <- errUnsent
+	}
+	c.state.unsent = nil
+}
+
+// awaitReconnect blocks while a reconnect is in progress and reports whether
+// the client reconnected
+func (c *Client) awaitReconnect() bool {
+	c.mu.RLock()
+	reconnected := c.state.reconnected
+	c.mu.RUnlock()
+
+	if reconnected == nil {
+		return false
+	}
+	<-reconnected
+	return c.isConnected()
+}
+
+// isReconnecting reports whether the client lost its connection and is
+// going to reconnect
+func (c *Client) isReconnecting() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state.reconnected != nil
+}
+
+// dropConnection closes the current connection so that the client reconnects
+func (c *Client) dropConnection(reason string) {
+	c.mu.Lock()
+	conn := c.state.conn
+	if conn == nil {
+		c.mu.Unlock()
+		return
+	}
+	c.state.closeReason = DisconnectServerClosed
+	c.state.closeMessage = reason
+	c.mu.Unlock()
+
+	conn.Close()
+}
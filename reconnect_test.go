@@ -0,0 +1,329 @@
+package tmigo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ktnuity/tmigo/tmigotest"
+)
+
+// connectTest connects a client and waits until it joined its channels
+func connectTest(t *testing.T, opts *ClientOptions) *Client {
+	t.Helper()
+
+	client := NewClient(opts)
+	wait := waitFor(t, client, "roomstate", len(opts.Channels))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	wait()
+
+	return client
+}
+
+// countLines counts the lines a client sent that start with prefix
+func countLines(server *tmigotest.Server, prefix string) int {
+	n := 0
+	for _, line := range server.Lines() {
+		if strings.HasPrefix(line, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestClient_SeamlessReconnect(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	opts.Connection.SeamlessReconnect = true
+	client := connectTest(t, opts)
+
+	var disconnects, messages atomic.Int32
+	client.On("disconnected", func(args ...any) { disconnects.Add(1) })
+	client.On("message", func(args ...any) { messages.Add(1) })
+
+	server.Reconnect()
+	seen := 0
+	if _, ok := server.WaitForLine(5*time.Second, func(line string) bool {
+		if line == "JOIN #a" {
+			seen++
+		}
+		return seen == 2
+	}); !ok {
+		t.Fatal("client did not join on a second connection")
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if h := client.handoff.Load(); h != nil && !h.active() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client did not switch to the new connection")
+		}
+	}
+
+	// Both connections receive the message until the old one is closed
+	wait := waitFor(t, client, "message", 1)
+	server.PrivMsg("#a", "viewer", "hello", nil)
+	wait()
+	time.Sleep(50 * time.Millisecond)
+
+	if n := messages.Load(); n != 1 {
+		t.Errorf("message fired %d times, want 1", n)
+	}
+	if n := disconnects.Load(); n != 0 {
+		t.Errorf("disconnected fired %d times during a seamless reconnect", n)
+	}
+	if n := server.ConnectionCount(); n != 2 {
+		t.Errorf("server saw %d connections, want 2", n)
+	}
+	if err := client.Say("#a", "still here"); err != nil {
+		t.Errorf("Say() error = %v after a seamless reconnect", err)
+	}
+	if _, ok := server.WaitForLine(time.Second, tmigotest.HasPrefix("PRIVMSG #a :still here")); !ok {
+		t.Error("Say() did not reach the server on the new connection")
+	}
+}
+
+func TestClient_ReconnectCommand(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	client := connectTest(t, opts)
+
+	waitDisconnect := waitFor(t, client, "disconnected", 1)
+	waitRejoin := waitFor(t, client, "roomstate", 1)
+	server.Reconnect()
+	waitDisconnect()
+	waitRejoin()
+
+	if n := server.ConnectionCount(); n != 2 {
+		t.Errorf("server saw %d connections, want 2", n)
+	}
+	if channels := client.GetChannels(); !slices.Equal(channels, []string{"#a"}) {
+		t.Errorf("GetChannels() = %v after RECONNECT, want [#a]", channels)
+	}
+}
+
+func TestClient_ReconnectRestoresJoins(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	client := connectTest(t, opts)
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := client.Join("#b"); err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+	wait()
+
+	wait = waitFor(t, client, "roomstate", 2)
+	server.Drop()
+	wait()
+
+	channels := client.GetChannels()
+	slices.Sort(channels)
+	if !slices.Equal(channels, []string{"#a", "#b"}) {
+		t.Errorf("GetChannels() = %v after reconnect, want [#a #b]", channels)
+	}
+}
+
+func TestClient_ReconnectKeepsQueuedMessages(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	opts.Connection.ReconnectInterval = 200 * time.Millisecond
+	opts.RateLimit = &RateLimit{NormalTier: RateLimitTier{Messages: 1, Per: 50 * time.Millisecond}}
+	client := connectTest(t, opts)
+
+	if err := client.Say("#a", "first"); err != nil {
+		t.Fatalf("Say() error = %v", err)
+	}
+
+	// The second message waits for the limiter while the connection drops
+	waitReconnect := waitFor(t, client, "reconnect", 1)
+	result := make(chan error, 1)
+	go func() { result <- client.Say("#a", "second") }()
+	server.Drop()
+	waitReconnect()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("Say() error = %v for a message queued across a reconnect", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Say() did not return after the reconnect")
+	}
+	if _, ok := server.WaitForLine(time.Second, tmigotest.HasPrefix("PRIVMSG #a :second")); !ok {
+		t.Error("queued message was not sent on the new connection")
+	}
+}
+
+// stallTransport dials websocket connections whose writes can be held until
+// the connection is lost, like a socket that stopped draining
+type stallTransport struct {
+	stall   atomic.Bool
+	stalled chan struct{}
+}
+
+func (s *stallTransport) Dial(ctx context.Context, opts *Connection) (Conn, error) {
+	conn, err := WebSocketTransport{}.Dial(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &stallConn{Conn: conn, transport: s, lost: make(chan struct{})}, nil
+}
+
+type stallConn struct {
+	Conn
+	transport *stallTransport
+	lost      chan struct{}
+	lostOnce  sync.Once
+}
+
+func (c *stallConn) ReadMessage() ([]byte, error) {
+	data, err := c.Conn.ReadMessage()
+	if err != nil {
+		c.lostOnce.Do(func() { close(c.lost) })
+	}
+	return data, err
+}
+
+func (c *stallConn) WriteMessage(data []byte) error {
+	if c.transport.stall.CompareAndSwap(true, false) {
+		close(c.transport.stalled)
+		<-c.lost
+	}
+	select {
+	case <-c.lost:
+		return errors.New("connection lost")
+	default:
+	}
+	return c.Conn.WriteMessage(data)
+}
+
+func TestClient_ReconnectKeepsWriterQueue(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	transport := &stallTransport{stalled: make(chan struct{})}
+	opts.Connection.Transport = transport
+	opts.RateLimit = &RateLimit{Disabled: true}
+	client := connectTest(t, opts)
+
+	// The first line is being written when the connection drops, the second
+	// one still waits in the writer's queue
+	transport.stall.Store(true)
+	first := make(chan error, 1)
+	go func() { first <- client.Say("#a", "first") }()
+	<-transport.stalled
+
+	second := make(chan error, 1)
+	go func() { second <- client.Say("#a", "second") }()
+	time.Sleep(50 * time.Millisecond)
+	server.Drop()
+
+	if err := <-first; err == nil {
+		t.Error("Say() error = nil for the line lost with the connection")
+	}
+	select {
+	case err := <-second:
+		if err != nil {
+			t.Fatalf("Say() error = %v for a line queued across a reconnect", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Say() did not return after the reconnect")
+	}
+	if _, ok := server.WaitForLine(time.Second, tmigotest.HasPrefix("PRIVMSG #a :second")); !ok {
+		t.Error("queued line was not sent on the new connection")
+	}
+}
+
+// failTransport dials websocket connections whose next write fails once
+// fail is set
+type failTransport struct {
+	fail atomic.Bool
+}
+
+func (f *failTransport) Dial(ctx context.Context, opts *Connection) (Conn, error) {
+	conn, err := WebSocketTransport{}.Dial(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &failConn{Conn: conn, transport: f}, nil
+}
+
+type failConn struct {
+	Conn
+	transport *failTransport
+}
+
+func (c *failConn) WriteMessage(data []byte) error {
+	if c.transport.fail.CompareAndSwap(true, false) {
+		return errors.New("broken pipe")
+	}
+	return c.Conn.WriteMessage(data)
+}
+
+func TestClient_WriteFailureInListener(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	transport := &failTransport{}
+	opts.Connection.Transport = transport
+	opts.RateLimit = &RateLimit{Disabled: true}
+	client := connectTest(t, opts)
+
+	// A listener runs on the read loop, which has to notice the lost
+	// connection, so its sends fail instead of waiting
+	errs := make(chan error, 2)
+	client.OnMessage(func(channel string, userstate ChatUserstate, message string, self bool) {
+		transport.fail.Store(true)
+		errs <- client.Say("#a", "first")
+		errs <- client.Say("#a", "second")
+	})
+
+	waitRejoin := waitFor(t, client, "roomstate", 1)
+	server.PrivMsg("#a", "viewer", "hello", nil)
+	for range 2 {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("Say() error = nil in a listener after a failed write")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Say() blocked the read loop after a failed write")
+		}
+	}
+	waitRejoin()
+
+	// The login goes out once per connection, nothing from the lost one
+	// is sent again
+	if n := countLines(server, "CAP REQ"); n != 2 {
+		t.Errorf("client requested capabilities %d times on 2 connections", n)
+	}
+	if n := countLines(server, "PRIVMSG #a :second"); n != 0 {
+		t.Errorf("failed message was sent %d times", n)
+	}
+}
+
+func TestClient_PartWhileReconnecting(t *testing.T) {
+	server, opts := testOptions(t, "#a", "#b")
+	opts.Connection.ReconnectInterval = 100 * time.Millisecond
+	client := connectTest(t, opts)
+
+	waitReconnect := waitFor(t, client, "reconnect", 1)
+	server.Drop()
+	waitReconnect()
+
+	if err := client.Part("#b"); err != nil {
+		t.Fatalf("Part() error = %v while reconnecting", err)
+	}
+
+	wait := waitFor(t, client, "roomstate", 1)
+	wait()
+	time.Sleep(50 * time.Millisecond)
+
+	if n := countLines(server, "JOIN #b"); n != 1 {
+		t.Errorf("client joined #b %d times, want 1", n)
+	}
+	if channels := client.GetChannels(); !slices.Equal(channels, []string{"#a"}) {
+		t.Errorf("GetChannels() = %v, want [#a]", channels)
+	}
+}
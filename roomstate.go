@@ -0,0 +1,59 @@
+package tmigo
+
+import "time"
+
+// normalizeRoomStateTags restores the numeric ROOMSTATE tags that the tag
+// parser turned into booleans ("0" and "1" for slow and followers-only).
+func normalizeRoomStateTags(tags map[string]any) {
+	for _, key := range []string{"slow", "followers-only"} {
+		if val, ok := tags[key].(bool); ok {
+			if val {
+				tags[key] = "1"
+			} else {
+				tags[key] = "0"
+			}
+		}
+	}
+}
+
+// slowMode reports whether slow mode is enabled and its delay in seconds
+func (r RoomState) slowMode() (bool, int) {
+	seconds := ParseInt(r.Slow)
+	return seconds > 0, seconds
+}
+
+// followersMode reports whether followers-only mode is enabled and the
+// required follow age in minutes
+func (r RoomState) followersMode() (bool, int) {
+	if r.FollowersOnly == "" || r.FollowersOnly == "-1" {
+		return false, 0
+	}
+	return true, ParseInt(r.FollowersOnly)
+}
+
+// SlowEnabled reports whether the room is in slow mode
+func (r RoomState) SlowEnabled() bool {
+	enabled, _ := r.slowMode()
+	return enabled
+}
+
+// SlowDuration returns the slow mode delay between messages, or 0 when slow
+// mode is off
+func (r RoomState) SlowDuration() time.Duration {
+	_, seconds := r.slowMode()
+	return time.Duration(seconds) * time.Second
+}
+
+// FollowersOnlyEnabled reports whether the room is in followers-only mode
+func (r RoomState) FollowersOnlyEnabled() bool {
+	enabled, _ := r.followersMode()
+	return enabled
+}
+
+// FollowersOnlyDuration returns how long a user must follow before chatting.
+// It is 0 both when followers-only mode is off and when any follower may
+// chat; use FollowersOnlyEnabled to tell them apart.
+func (r RoomState) FollowersOnlyDuration() time.Duration {
+	_, minutes := r.followersMode()
+	return time.Duration(minutes) * time.Minute
+}
@@ -0,0 +1,146 @@
+package tmigo
+
+import (
+	"testing"
+	"time"
+)
+
+const initialRoomState = "@emote-only=0;followers-only=-1;r9k=0;room-id=12345;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #test"
+
+func TestHandleRoomState_InitialStateOnly(t *testing.T) {
+	client := NewClient(nil)
+
+	var got []string
+	for _, event := range []string{"emoteonly", "subscribers", "r9kbeta", "slowmode", "followersonly"} {
+		client.On(event, func(args ...any) {
+			got = append(got, event)
+		})
+	}
+
+	var state RoomState
+	client.OnRoomstate(func(channel string, roomstate RoomState) {
+		state = roomstate
+	})
+
+	client.handleMessage(ParseMessage(initialRoomState))
+
+	if len(got) != 0 {
+		t.Errorf("initial ROOMSTATE emitted mode events %v", got)
+	}
+	if state.RoomID != "12345" || state.FollowersOnly != "-1" || state.Slow != "0" {
+		t.Errorf("roomstate = %+v, want the initial state", state)
+	}
+}
+
+func TestHandleRoomState_ModeChanges(t *testing.T) {
+	tests := []struct {
+		update string
+		event  string
+		args   []any
+	}{
+		{"emote-only=1", "emoteonly", []any{"#test", true}},
+		{"subs-only=1", "subscribers", []any{"#test", true}},
+		{"r9k=1", "r9kbeta", []any{"#test", true}},
+		{"slow=30", "slowmode", []any{"#test", true, 30}},
+		{"slow=1", "slowmode", []any{"#test", true, 1}},
+		{"followers-only=10", "followersonly", []any{"#test", true, 10}},
+		{"followers-only=0", "followersonly", []any{"#test", true, 0}},
+	}
+
+	for _, tt := range tests {
+		client := NewClient(nil)
+		client.handleMessage(ParseMessage(initialRoomState))
+
+		var got []any
+		client.On(tt.event, func(args ...any) {
+			got = args
+		})
+
+		client.handleMessage(ParseMessage("@room-id=12345;" + tt.update + " :tmi.twitch.tv ROOMSTATE #test"))
+
+		if len(got) != len(tt.args) {
+			t.Errorf("%s: %s args = %v, want %v", tt.update, tt.event, got, tt.args)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.args[i] {
+				t.Errorf("%s: %s args = %v, want %v", tt.update, tt.event, got, tt.args)
+				break
+			}
+		}
+	}
+}
+
+func TestHandleRoomState_UnchangedMode(t *testing.T) {
+	client := NewClient(nil)
+	client.handleMessage(ParseMessage(initialRoomState))
+
+	called := false
+	client.On("emoteonly", func(args ...any) {
+		called = true
+	})
+
+	client.handleMessage(ParseMessage("@emote-only=0;room-id=12345 :tmi.twitch.tv ROOMSTATE #test"))
+
+	if called {
+		t.Error("emoteonly emitted although the mode did not change")
+	}
+}
+
+func TestHandleRoomState_MergesPartialUpdates(t *testing.T) {
+	client := NewClient(nil)
+	client.handleMessage(ParseMessage(initialRoomState))
+	client.handleMessage(ParseMessage("@room-id=12345;slow=10 :tmi.twitch.tv ROOMSTATE #test"))
+
+	var state RoomState
+	client.OnRoomstate(func(channel string, roomstate RoomState) {
+		state = roomstate
+	})
+
+	client.handleMessage(ParseMessage("@emote-only=1;room-id=12345 :tmi.twitch.tv ROOMSTATE #test"))
+
+	if !state.EmoteOnly || state.Slow != "10" || state.FollowersOnly != "-1" {
+		t.Errorf("roomstate = %+v, want merged state", state)
+	}
+}
+
+func TestClientRoomState(t *testing.T) {
+	client := NewClient(nil)
+	client.state.username = "bot"
+
+	if _, ok := client.RoomState("test"); ok {
+		t.Fatal("RoomState() ok = true before joining")
+	}
+
+	client.handleMessage(ParseMessage(initialRoomState))
+	client.handleMessage(ParseMessage("@room-id=12345;slow=30 :tmi.twitch.tv ROOMSTATE #test"))
+	client.handleMessage(ParseMessage("@followers-only=0;room-id=12345 :tmi.twitch.tv ROOMSTATE #test"))
+
+	state, ok := client.RoomState("test")
+	if !ok {
+		t.Fatal("RoomState() ok = false after ROOMSTATE")
+	}
+	if !state.SlowEnabled() || state.SlowDuration() != 30*time.Second {
+		t.Errorf("slow = (%v, %v), want (true, 30s)", state.SlowEnabled(), state.SlowDuration())
+	}
+	if !state.FollowersOnlyEnabled() || state.FollowersOnlyDuration() != 0 {
+		t.Errorf("followers-only = (%v, %v), want (true, 0s)", state.FollowersOnlyEnabled(), state.FollowersOnlyDuration())
+	}
+
+	client.handleMessage(ParseMessage(":bot!bot@bot.tmi.twitch.tv PART #test"))
+
+	if _, ok := client.RoomState("#test"); ok {
+		t.Error("RoomState() ok = true after PART")
+	}
+}
+
+func TestRoomStateHelpers_Off(t *testing.T) {
+	state := RoomState{Slow: "0", FollowersOnly: "-1"}
+
+	if state.SlowEnabled() || state.SlowDuration() != 0 {
+		t.Error("slow mode reported enabled for slow=0")
+	}
+	if state.FollowersOnlyEnabled() || state.FollowersOnlyDuration() != 0 {
+		t.Error("followers-only reported enabled for followers-only=-1")
+	}
+}
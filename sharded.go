@@ -0,0 +1,469 @@
+package tmigo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ShardOptions configures how a ShardedClient spreads channels over
+// connections
+type ShardOptions struct {
+	ChannelsPerShard int // Channels per connection before another one is opened (default 50)
+	MaxShards        int // Upper limit on connections, after which shards take more channels (0 means no limit)
+}
+
+// ShardedClient spreads channels over several connections, so a slow or
+// broken connection only affects its own channels. It opens another shard
+// when the others are full, closes shards that have no channels left, and
+// moves the channels of a shard that stops reconnecting to the others.
+//
+// The embedded Client never connects itself. Events of every shard are
+// emitted on it, so the On* helpers work unchanged, and its commands are sent
+// on the shard that joined the channel. Commands without a channel, like
+// Whisper and Raw, use the first connected shard. The shards share the
+// account's chat and join budgets.
+type ShardedClient struct {
+	*Client
+
+	opts      *ClientOptions
+	shardOpts ShardOptions
+
+	mu     sync.RWMutex
+	shards []*Client
+	owners map[string]*Client // Channel to the shard that joined it
+}
+
+// NewShardedClient creates a sharded client. opts.Channels are spread over
+// the shards on Connect.
+func NewShardedClient(opts *ClientOptions, shardOpts *ShardOptions) *ShardedClient {
+	if shardOpts == nil {
+		shardOpts = &ShardOptions{}
+	}
+
+	front := NewClient(opts)
+	s := &ShardedClient{
+		Client:    front,
+		opts:      opts,
+		shardOpts: *shardOpts,
+		owners:    make(map[string]*Client),
+	}
+	s.shardOpts.ChannelsPerShard = cmp.Or(s.shardOpts.ChannelsPerShard, 50)
+	front.route = s.shardFor
+
+	return s
+}
+
+// Connect opens the shards for the configured channels and connects them
+func (s *ShardedClient) Connect() error {
+	var errs []error
+	for _, shard := range s.open() {
+		if err := shard.Connect(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ConnectContext connects every shard with ConnectContext and waits until
+// all of them are ready. If any shard fails, all of them are disconnected
+// and the first error is returned.
+func (s *ShardedClient) ConnectContext(ctx context.Context, until ConnectReady) error {
+	shards := s.open()
+
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Go(func() {
+			errs[i] = shard.ConnectContext(ctx, until)
+		})
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			s.Disconnect()
+			return err
+		}
+	}
+	return nil
+}
+
+// open creates the shards for the configured channels unless they exist
+func (s *ShardedClient) open() []*Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.shards) > 0 {
+		return slices.Clone(s.shards)
+	}
+
+	channels := ChannelAll(s.opts.Channels)
+	slices.Sort(channels)
+	channels = slices.Compact(channels)
+	count := max((len(channels)+s.shardOpts.ChannelsPerShard-1)/s.shardOpts.ChannelsPerShard, 1)
+	if s.shardOpts.MaxShards > 0 {
+		count = min(count, s.shardOpts.MaxShards)
+	}
+
+	size := (len(channels) + count - 1) / count
+	for i := range count {
+		group := channels[min(i*size, len(channels)):min((i+1)*size, len(channels))]
+		s.addShard(group)
+	}
+	return slices.Clone(s.shards)
+}
+
+// Disconnect closes every shard. The channels are kept, so Connect opens the
+// shards for them again.
+func (s *ShardedClient) Disconnect() error {
+	s.mu.Lock()
+	shards := s.shards
+	s.opts.Channels = slices.Sorted(maps.Keys(s.owners))
+	s.shards = nil
+	s.owners = make(map[string]*Client)
+	s.mu.Unlock()
+
+	if len(shards) == 0 {
+		return ErrNotConnected
+	}
+
+	var errs []error
+	for _, shard := range shards {
+		if err := shard.Disconnect(); err != nil && !errors.Is(err, ErrNotConnected) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Join joins a channel on the shard with the fewest channels, opening a new
+// shard when all of them are full
+func (s *ShardedClient) Join(channel string) error {
+	shard, opened, err := s.assign(Channel(channel))
+	if err != nil {
+		return err
+	}
+	if opened {
+		return shard.Connect()
+	}
+	return shard.joinOrQueue(channel)
+}
+
+// JoinContext joins a channel like Join and waits until the join is
+// confirmed
+func (s *ShardedClient) JoinContext(ctx context.Context, channel string) error {
+	shard, opened, err := s.assign(Channel(channel))
+	if err != nil {
+		return err
+	}
+	if opened {
+		return shard.ConnectContext(ctx, ReadyChannelsJoined)
+	}
+	return shard.JoinContext(ctx, channel)
+}
+
+// JoinMultiple joins 1 or more channels
+func (s *ShardedClient) JoinMultiple(channels []string) error {
+	var errs []error
+	for _, channel := range channels {
+		if err := s.Join(channel); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// JoinMultipleContext joins 1 or more channels and waits until every join is
+// confirmed. Failed joins are combined into the returned error.
+func (s *ShardedClient) JoinMultipleContext(ctx context.Context, channels []string) error {
+	errs := make([]error, len(channels))
+	var wg sync.WaitGroup
+	for i, channel := range channels {
+		wg.Go(func() {
+			errs[i] = s.JoinContext(ctx, channel)
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Part leaves a channel. A shard without channels left is closed.
+func (s *ShardedClient) Part(channel string) error {
+	shard, ok := s.Shard(channel)
+	if !ok {
+		return ErrNotConnected
+	}
+
+	err := shard.Part(channel)
+	s.release(Channel(channel), shard)
+	return err
+}
+
+// PartContext leaves a channel like Part and waits until Twitch confirms it
+func (s *ShardedClient) PartContext(ctx context.Context, channel string) error {
+	shard, ok := s.Shard(channel)
+	if !ok {
+		return ErrNotConnected
+	}
+
+	err := shard.PartContext(ctx, channel)
+	s.release(Channel(channel), shard)
+	return err
+}
+
+// Leave is an alias for Part
+func (s *ShardedClient) Leave(channel string) error {
+	return s.Part(channel)
+}
+
+// LeaveContext is an alias for PartContext
+func (s *ShardedClient) LeaveContext(ctx context.Context, channel string) error {
+	return s.PartContext(ctx, channel)
+}
+
+// GetChannels returns the joined channels of every shard
+func (s *ShardedClient) GetChannels() []string {
+	var channels []string
+	for _, shard := range s.Shards() {
+		channels = append(channels, shard.GetChannels()...)
+	}
+	slices.Sort(channels)
+	return channels
+}
+
+// IsMod checks if a username is a moderator in a channel
+func (s *ShardedClient) IsMod(channel, username string) bool {
+	shard, ok := s.Shard(channel)
+	return ok && shard.IsMod(channel, username)
+}
+
+// RoomState returns the last known state of a joined channel
+func (s *ShardedClient) RoomState(channel string) (RoomState, bool) {
+	shard, ok := s.Shard(channel)
+	if !ok {
+		return RoomState{}, false
+	}
+	return shard.RoomState(channel)
+}
+
+// ReadyState returns OPEN when every shard is open, CONNECTING while any
+// shard is connecting and CLOSED otherwise
+func (s *ShardedClient) ReadyState() string {
+	shards := s.Shards()
+	if len(shards) == 0 {
+		return "CLOSED"
+	}
+
+	state := "OPEN"
+	for _, shard := range shards {
+		switch shard.ReadyState() {
+		case "CONNECTING":
+			state = "CONNECTING"
+		case "CLOSED":
+			return "CLOSED"
+		}
+	}
+	return state
+}
+
+// Shards returns the current shards
+func (s *ShardedClient) Shards() []*Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.shards)
+}
+
+// Shard returns the shard that joined a channel
+func (s *ShardedClient) Shard(channel string) (*Client, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	shard, ok := s.owners[Channel(channel)]
+	return shard, ok
+}
+
+// shardFor routes the front client's commands: to the shard that joined the
+// channel, or the first connected shard. Without shards the front client
+// handles the command and reports that it is not connected.
+func (s *ShardedClient) shardFor(channel string) *Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if shard, ok := s.owners[Channel(channel)]; ok {
+		return shard
+	}
+	for _, shard := range s.shards {
+		if shard.isConnected() {
+			return shard
+		}
+	}
+	if len(s.shards) > 0 {
+		return s.shards[0]
+	}
+	return s.Client
+}
+
+// assign picks the shard for a channel. A channel that is already assigned
+// stays on its shard. When every shard is full, a new shard is created for
+// the channel and opened is set; the caller connects it.
+func (s *ShardedClient) assign(channel string) (shard *Client, opened bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if shard, ok := s.owners[channel]; ok {
+		return shard, false, nil
+	}
+	if len(s.shards) == 0 {
+		return nil, false, ErrNotConnected
+	}
+
+	loads := s.loads()
+	shard = slices.MinFunc(s.shards, func(a, b *Client) int {
+		return loads[a] - loads[b]
+	})
+	full := loads[shard] >= s.shardOpts.ChannelsPerShard
+	if full && (s.shardOpts.MaxShards == 0 || len(s.shards) < s.shardOpts.MaxShards) {
+		s.log().Info(fmt.Sprintf("Opening shard %d for %s..", len(s.shards)+1, channel))
+		return s.addShard([]string{channel}), true, nil
+	}
+
+	s.owners[channel] = shard
+	return shard, false, nil
+}
+
+// release forgets a parted channel and closes its shard when that was the
+// last channel on it and other shards remain
+func (s *ShardedClient) release(channel string, shard *Client) {
+	s.mu.Lock()
+	if s.owners[channel] != shard {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.owners, channel)
+	empty := s.loads()[shard] == 0 && len(s.shards) > 1
+	if empty {
+		s.shards = slices.DeleteFunc(s.shards, func(c *Client) bool { return c == shard })
+	}
+	s.mu.Unlock()
+
+	if empty {
+		shard.Disconnect()
+	}
+}
+
+// rebalance moves the channels of a shard that stopped reconnecting to the
+// other shards, opening new ones as needed
+func (s *ShardedClient) rebalance(dead *Client) {
+	s.mu.Lock()
+	if !slices.Contains(s.shards, dead) {
+		s.mu.Unlock()
+		return
+	}
+	s.shards = slices.DeleteFunc(s.shards, func(c *Client) bool { return c == dead })
+	var orphans []string
+	for channel, shard := range s.owners {
+		if shard == dead {
+			orphans = append(orphans, channel)
+			delete(s.owners, channel)
+		}
+	}
+	if len(s.shards) == 0 {
+		// Keep the channels so they go to the replacement shard
+		s.addShard(nil)
+	}
+	s.mu.Unlock()
+
+	slices.Sort(orphans)
+	s.log().Error(fmt.Sprintf("Shard closed, moving %d channels to other shards..", len(orphans)))
+	for _, channel := range orphans {
+		shard, _, err := s.assign(channel)
+		if err == nil {
+			err = shard.joinOrQueue(channel)
+		}
+		if err != nil {
+			s.log().Error(fmt.Sprintf("Could not move %s: %v", channel, err))
+		}
+	}
+
+	// Connect the shards opened for the orphans once they hold their channels
+	for _, shard := range s.Shards() {
+		if shard.State() == StateIdle {
+			if err := shard.Connect(); err != nil {
+				s.log().Error(fmt.Sprintf("Could not open shard: %v", err))
+			}
+		}
+	}
+}
+
+// loads counts the channels of every shard. s.mu must be held.
+func (s *ShardedClient) loads() map[*Client]int {
+	loads := make(map[*Client]int, len(s.shards))
+	for _, shard := range s.owners {
+		loads[shard]++
+	}
+	return loads
+}
+
+// addShard creates a shard for channels that shares the account's budgets
+// and emits its events on the front client. s.mu must be held.
+func (s *ShardedClient) addShard(channels []string) *Client {
+	opts := *s.opts
+	connection := *opts.Connection
+	options := *opts.Options
+	identity := *opts.Identity
+	rateLimit := *opts.RateLimit
+	health := *opts.Health
+	dispatch := *opts.Dispatch
+	streams := *opts.Streams
+	opts.Connection, opts.Options, opts.Identity = &connection, &options, &identity
+	opts.RateLimit, opts.Health, opts.Dispatch, opts.Streams = &rateLimit, &health, &dispatch, &streams
+	opts.Channels = slices.Clone(channels)
+
+	shard := NewClient(&opts)
+	shard.limiter = s.Client.limiter
+	shard.joins.bucket = s.Client.joins.bucket
+	shard.forward = func(m EventMeta, eventType string, args []any) {
+		// Internal events only concern the shard's own commands. The rest
+		// go through the front client's waiters and dispatcher.
+		if !strings.HasPrefix(eventType, "_") {
+			s.Client.emitMeta(m, eventType, args)
+		}
+	}
+	shard.On("statechange", func(args ...any) {
+		// Other shards would be rejected with the same credentials
+		reason, _ := args[2].(DisconnectReason)
+		if args[1] == StateClosed && reason != DisconnectUserClosed && reason != DisconnectAuthFailed {
+			go s.rebalance(shard)
+		}
+	})
+
+	for _, channel := range channels {
+		s.owners[channel] = shard
+	}
+	s.shards = append(s.shards, shard)
+	return shard
+}
+
+func (s *ShardedClient) log() Logger {
+	return s.Client.state.log
+}
+
+// joinOrQueue joins a channel, or adds it to the channels joined on login
+// when the client has not connected yet
+func (c *Client) joinOrQueue(channel string) error {
+	c.mu.Lock()
+	if c.state.conn == nil && c.state.reconnected == nil {
+		if !slices.Contains(c.state.opts.Channels, Channel(channel)) {
+			c.state.opts.Channels = append(c.state.opts.Channels, Channel(channel))
+		}
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+	return c.Join(channel)
+}
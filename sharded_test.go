@@ -0,0 +1,203 @@
+package tmigo
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ktnuity/tmigo/tmigotest"
+)
+
+// connectSharded connects a sharded client and waits until every shard
+// joined its channels
+func connectSharded(t *testing.T, opts *ClientOptions, shardOpts *ShardOptions) *ShardedClient {
+	t.Helper()
+
+	client := NewShardedClient(opts, shardOpts)
+	wait := waitFor(t, client.Client, "roomstate", len(opts.Channels))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	wait()
+
+	return client
+}
+
+// owners maps each channel to the index of its shard
+func owners(client *ShardedClient, channels ...string) map[string]int {
+	shards := client.Shards()
+	owned := make(map[string]int)
+	for _, channel := range channels {
+		if shard, ok := client.Shard(channel); ok {
+			owned[channel] = slices.Index(shards, shard)
+		}
+	}
+	return owned
+}
+
+func TestShardedClient_Distribute(t *testing.T) {
+	server, opts := testOptions(t, "#a", "#b", "#c", "#d", "#e")
+	client := connectSharded(t, opts, &ShardOptions{ChannelsPerShard: 2})
+
+	if n := len(client.Shards()); n != 3 {
+		t.Fatalf("%d shards, want 3", n)
+	}
+	if n := server.ConnectionCount(); n != 3 {
+		t.Errorf("server saw %d connections, want 3", n)
+	}
+
+	want := map[string]int{"#a": 0, "#b": 0, "#c": 1, "#d": 1, "#e": 2}
+	if got := owners(client, "#a", "#b", "#c", "#d", "#e"); !maps.Equal(got, want) {
+		t.Errorf("owners = %v, want %v", got, want)
+	}
+	if got := client.GetChannels(); !slices.Equal(got, []string{"#a", "#b", "#c", "#d", "#e"}) {
+		t.Errorf("GetChannels() = %v", got)
+	}
+	if state := client.ReadyState(); state != "OPEN" {
+		t.Errorf("ReadyState() = %s, want OPEN", state)
+	}
+}
+
+func TestShardedClient_MaxShards(t *testing.T) {
+	_, opts := testOptions(t, "#a", "#b", "#c", "#d", "#e")
+	client := connectSharded(t, opts, &ShardOptions{ChannelsPerShard: 1, MaxShards: 2})
+
+	if n := len(client.Shards()); n != 2 {
+		t.Fatalf("%d shards, want 2", n)
+	}
+
+	// Full shards take more channels once the limit is reached
+	if err := client.JoinContext(t.Context(), "#f"); err != nil {
+		t.Fatalf("JoinContext() error = %v", err)
+	}
+	if n := len(client.Shards()); n != 2 {
+		t.Errorf("%d shards after a join, want 2", n)
+	}
+}
+
+func TestShardedClient_Events(t *testing.T) {
+	server, opts := testOptions(t, "#a", "#b")
+	client := connectSharded(t, opts, &ShardOptions{ChannelsPerShard: 1})
+
+	// The test server sends to every connection, like two shards in one channel
+	var messages atomic.Int32
+	client.OnMessage(func(channel string, userstate ChatUserstate, message string, self bool) {
+		messages.Add(1)
+	})
+
+	wait := waitFor(t, client.Client, "message", 2)
+	server.PrivMsg("#a", "viewer", "hello", nil)
+	wait()
+
+	if n := messages.Load(); n != 2 {
+		t.Errorf("OnMessage fired %d times, want once per shard", n)
+	}
+}
+
+func TestShardedClient_EventsUseFrontDispatcher(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	opts.Dispatch = &Dispatch{Async: true}
+	client := connectSharded(t, opts, nil)
+
+	// Shard events resolve the front client's waiters and run its listeners
+	// under its own panic recovery
+	waiter := client.Client.waiters.add("message", nil)
+	defer client.Client.waiters.remove("message", waiter)
+	client.OnMessage(func(channel string, userstate ChatUserstate, message string, self bool) {
+		panic("listener failed")
+	})
+	wait := waitFor(t, client.Client, "error", 1)
+	server.PrivMsg("#a", "viewer", "hello", nil)
+	wait()
+
+	select {
+	case <-waiter.ch:
+	case <-time.After(time.Second):
+		t.Error("front client waiter was not resolved by a shard event")
+	}
+	if n := client.DispatchStats().Panicked; n != 1 {
+		t.Errorf("Panicked = %d on the front client, want 1", n)
+	}
+}
+
+func TestShardedClient_RoutesCommands(t *testing.T) {
+	server, opts := testOptions(t, "#a", "#b")
+	client := connectSharded(t, opts, &ShardOptions{ChannelsPerShard: 1})
+
+	if err := client.Say("#b", "hello"); err != nil {
+		t.Fatalf("Say() error = %v", err)
+	}
+	if _, ok := server.WaitForLine(5*time.Second, tmigotest.HasPrefix("PRIVMSG #b :hello")); !ok {
+		t.Fatal("server did not receive the message")
+	}
+
+	// With the owning shard closed, Say fails even though #a's shard is open
+	owner, _ := client.Shard("#b")
+	owner.Disconnect()
+	if err := client.Say("#b", "again"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Say() error = %v on a closed shard, want ErrNotConnected", err)
+	}
+	if err := client.Say("#a", "still here"); err != nil {
+		t.Errorf("Say() error = %v on an open shard", err)
+	}
+}
+
+func TestShardedClient_JoinScales(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	client := connectSharded(t, opts, &ShardOptions{ChannelsPerShard: 1})
+
+	if err := client.JoinContext(t.Context(), "#b"); err != nil {
+		t.Fatalf("JoinContext() error = %v", err)
+	}
+	if n := len(client.Shards()); n != 2 {
+		t.Fatalf("%d shards after joining a second channel, want 2", n)
+	}
+	if n := server.ConnectionCount(); n != 2 {
+		t.Errorf("server saw %d connections, want 2", n)
+	}
+
+	// Parting the last channel of a shard closes it
+	if err := client.PartContext(t.Context(), "#b"); err != nil {
+		t.Fatalf("PartContext() error = %v", err)
+	}
+	if n := len(client.Shards()); n != 1 {
+		t.Errorf("%d shards after parting, want 1", n)
+	}
+	if _, ok := client.Shard("#b"); ok {
+		t.Error("Shard(#b) still assigned after parting")
+	}
+}
+
+func TestShardedClient_Rebalance(t *testing.T) {
+	server, opts := testOptions(t, "#a", "#b", "#c")
+	var veto atomic.Bool
+	opts.Connection.ReconnectHook = func(attempt int, delay time.Duration, reason string) (time.Duration, bool) {
+		return delay, !veto.Load()
+	}
+	client := connectSharded(t, opts, &ShardOptions{ChannelsPerShard: 2})
+
+	// The first shard stops reconnecting: #a moves next to #c, #b gets a new shard
+	dead, _ := client.Shard("#a")
+	wait := waitFor(t, client.Client, "roomstate", 2)
+	veto.Store(true)
+	dead.mu.RLock()
+	dead.state.conn.Close()
+	dead.mu.RUnlock()
+	wait()
+
+	shards := client.Shards()
+	if len(shards) != 2 || slices.Contains(shards, dead) {
+		t.Fatalf("shards = %v after rebalancing, want 2 without the closed one", shards)
+	}
+	want := map[string]int{"#a": 0, "#b": 1, "#c": 0}
+	if got := owners(client, "#a", "#b", "#c"); !maps.Equal(got, want) {
+		t.Errorf("owners = %v, want %v", got, want)
+	}
+	if n := server.ConnectionCount(); n != 3 {
+		t.Errorf("server saw %d connections, want 3", n)
+	}
+}
@@ -0,0 +1,93 @@
+package tmigo
+
+import (
+	"errors"
+	"io"
+	"slices"
+
+	"github.com/gorilla/websocket"
+)
+
+// ConnState is a stage of the client's connection lifecycle:
+//
+//	Idle → Connecting → Authenticating → Connected
+//	          ↓               ↓              ↓
+//	          └──── Reconnecting / Closed ───┘
+//
+// A lost connection goes to Reconnecting when the client reconnects and to
+// Closed otherwise. Reconnecting goes back to Connecting when the reconnect
+// timer fires, and Closed goes to Connecting on the next Connect.
+type ConnState string
+
+const (
+	StateIdle           ConnState = "idle"           // Never connected
+	StateConnecting     ConnState = "connecting"     // Dialing the server
+	StateAuthenticating ConnState = "authenticating" // Connection open, waiting for the login to complete
+	StateConnected      ConnState = "connected"      // Logged in
+	StateReconnecting   ConnState = "reconnecting"   // Waiting for the next reconnect attempt
+	StateClosed         ConnState = "closed"         // Disconnected without a reconnect planned
+)
+
+// connTransitions lists the states each state may move to
+var connTransitions = map[ConnState][]ConnState{
+	StateIdle:           {StateConnecting},
+	StateConnecting:     {StateAuthenticating, StateReconnecting, StateClosed},
+	StateAuthenticating: {StateConnected, StateReconnecting, StateClosed},
+	StateConnected:      {StateReconnecting, StateClosed},
+	StateReconnecting:   {StateConnecting, StateClosed},
+	StateClosed:         {StateConnecting},
+}
+
+// DisconnectReason classifies why a connection ended
+type DisconnectReason string
+
+const (
+	DisconnectServerClosed DisconnectReason = "serverclosed" // Closed by the server, including RECONNECT
+	DisconnectPingTimeout  DisconnectReason = "pingtimeout"  // The server did not answer a keepalive PING
+	DisconnectAuthFailed   DisconnectReason = "authfailed"   // The login was rejected
+	DisconnectUserClosed   DisconnectReason = "userclosed"   // Disconnect was called
+	DisconnectNetworkError DisconnectReason = "networkerror" // Dialing or reading failed
+)
+
+// stateChange is a transition to announce once c.mu is released
+type stateChange struct {
+	from, to ConnState
+	reason   DisconnectReason
+}
+
+// transition moves the connection to another state. It returns a
+// *TransitionError when the lifecycle does not allow the move. reason is
+// only set for transitions caused by a lost connection. c.mu must be held.
+func (c *Client) transition(to ConnState, reason DisconnectReason) (stateChange, error) {
+	from := c.state.connState
+	if !slices.Contains(connTransitions[from], to) {
+		return stateChange{}, &TransitionError{From: from, To: to}
+	}
+
+	c.state.connState = to
+	return stateChange{from: from, to: to, reason: reason}, nil
+}
+
+// announceState emits statechange for a transition made with transition
+func (c *Client) announceState(change stateChange) {
+	if change.from == change.to {
+		return
+	}
+	c.Emit("statechange", change.from, change.to, change.reason)
+}
+
+// State returns the current connection state
+func (c *Client) State() ConnState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state.connState
+}
+
+// disconnectReason classifies the error that ended a connection
+func disconnectReason(err error) DisconnectReason {
+	var closeErr *websocket.CloseError
+	if err == nil || errors.Is(err, io.EOF) || errors.As(err, &closeErr) {
+		return DisconnectServerClosed
+	}
+	return DisconnectNetworkError
+}
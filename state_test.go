@@ -0,0 +1,162 @@
+package tmigo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// recordStates collects the client's state transitions as "from>to" or
+// "from>to:reason"
+func recordStates(client *Client) func() []string {
+	var mu sync.Mutex
+	var changes []string
+	client.OnStateChange(func(old, new ConnState, reason DisconnectReason) {
+		mu.Lock()
+		defer mu.Unlock()
+		change := fmt.Sprintf("%s>%s", old, new)
+		if reason != "" {
+			change += ":" + string(reason)
+		}
+		changes = append(changes, change)
+	})
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(changes)
+	}
+}
+
+func TestClient_StateTransitions(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	client := NewClient(opts)
+	changes := recordStates(client)
+
+	if state := client.State(); state != StateIdle {
+		t.Errorf("State() = %s before Connect, want idle", state)
+	}
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	wait()
+
+	wait = waitFor(t, client, "roomstate", 1)
+	server.Drop()
+	wait()
+
+	if err := client.Disconnect(); err != nil {
+		t.Fatalf("Disconnect() error = %v", err)
+	}
+
+	want := []string{
+		"idle>connecting",
+		"connecting>authenticating",
+		"authenticating>connected",
+		"connected>reconnecting:serverclosed",
+		"reconnecting>connecting",
+		"connecting>authenticating",
+		"authenticating>connected",
+		"connected>closed:userclosed",
+	}
+	if got := changes(); !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+	if state := client.State(); state != StateClosed {
+		t.Errorf("State() = %s after Disconnect, want closed", state)
+	}
+}
+
+func TestClient_ConnectWhileConnected(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	client := connectTest(t, opts)
+
+	err := client.Connect()
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) || !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Connect() error = %v, want a TransitionError", err)
+	}
+	if transitionErr.From != StateConnected || transitionErr.To != StateConnecting {
+		t.Errorf("TransitionError = %+v, want connected to connecting", transitionErr)
+	}
+
+	if err := client.ConnectContext(t.Context(), ReadyLoggedOn); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ConnectContext() error = %v, want ErrInvalidTransition", err)
+	}
+	if state := client.State(); state != StateConnected {
+		t.Errorf("State() = %s, want connected", state)
+	}
+	if n := server.ConnectionCount(); n != 1 {
+		t.Errorf("server saw %d connections, want 1", n)
+	}
+}
+
+func TestClient_StateAuthFailed(t *testing.T) {
+	server, opts := testOptions(t)
+	server.RejectLogin = true
+	client := NewClient(opts)
+	changes := recordStates(client)
+
+	wait := waitFor(t, client, "disconnected", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	wait()
+
+	want := []string{
+		"idle>connecting",
+		"connecting>authenticating",
+		"authenticating>closed:authfailed",
+	}
+	if got := changes(); !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+	if err := client.Disconnect(); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Disconnect() error = %v after an auth failure, want ErrNotConnected", err)
+	}
+}
+
+func TestClient_StatePingTimeout(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	opts.Health = &Health{PingTimeout: time.Millisecond}
+	client := connectTest(t, opts)
+	changes := recordStates(client)
+
+	// Close the connection before the PONG can arrive
+	wait := waitFor(t, client, "roomstate", 1)
+	client.startPingTimeout()
+	wait()
+
+	if got := changes(); len(got) == 0 || got[0] != "connected>reconnecting:pingtimeout" {
+		t.Errorf("transitions = %v, want connected>reconnecting:pingtimeout first", got)
+	}
+	if n := server.ConnectionCount(); n != 2 {
+		t.Errorf("server saw %d connections, want 2", n)
+	}
+}
+
+func TestDisconnectReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want DisconnectReason
+	}{
+		{nil, DisconnectServerClosed},
+		{io.EOF, DisconnectServerClosed},
+		{&websocket.CloseError{Code: websocket.CloseAbnormalClosure}, DisconnectServerClosed},
+		{errors.New("connection reset by peer"), DisconnectNetworkError},
+	}
+
+	for _, tt := range tests {
+		if got := disconnectReason(tt.err); got != tt.want {
+			t.Errorf("disconnectReason(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package tmigo
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// EventFilter selects the events of a stream opened with Events
+type EventFilter struct {
+	Events []string         // Event types to deliver, every type with an event struct when empty
+	Match  func(Event) bool // Delivers only events it accepts when set
+}
+
+// eventStream delivers events on a channel until its context is done
+type eventStream[T Event] struct {
+	ctx      context.Context
+	overflow OverflowPolicy
+	ch       chan T
+
+	mu     sync.RWMutex // Held for reading while sending, so close waits for senders
+	closed bool
+}
+
+// send delivers e, or drops an event as the overflow policy says when the
+// buffer is full. A blocked send gives up once the context is done.
+func (s *eventStream[T]) send(e T) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+
+	for {
+		select {
+		case s.ch <- e:
+			return
+		default:
+		}
+
+		switch s.overflow {
+		case OverflowDropNewest:
+			return
+		case OverflowDropOldest:
+			select {
+			case <-s.ch:
+			default:
+			}
+		default:
+			select {
+			case s.ch <- e:
+			case <-s.ctx.Done():
+			}
+			return
+		}
+	}
+}
+
+func (s *eventStream[T]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.ch)
+}
+
+// openStream registers listeners for the filtered events of type T. They are
+// removed and the channel is closed when ctx is done.
+func openStream[T Event](c *Client, ctx context.Context, filter EventFilter) <-chan T {
+	opts := c.state.opts.Streams
+	s := &eventStream[T]{
+		ctx:      ctx,
+		overflow: opts.Overflow,
+		ch:       make(chan T, opts.BufferSize),
+	}
+
+	names := filter.Events
+	if len(names) == 0 {
+		names = slices.Sorted(maps.Keys(eventDecoders))
+	}
+
+	var subs []*Subscription
+	for _, name := range names {
+		if _, ok := eventDecoders[name]; !ok {
+			c.state.log.Warn(fmt.Sprintf("No event struct for %s, not streaming it", name))
+			continue
+		}
+		subs = append(subs, c.onEvent(name, func(e Event) {
+			if filter.Match != nil && !filter.Match(e) {
+				return
+			}
+			if t, ok := e.(T); ok {
+				s.send(t)
+			}
+		}))
+	}
+
+	go func() {
+		<-ctx.Done()
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+		s.close()
+	}()
+
+	return s.ch
+}
+
+// Events returns a channel of the events selected by filter. The channel is
+// closed once ctx is done. Streams.BufferSize and Streams.Overflow decide
+// what happens when the receiver falls behind.
+//
+//	for e := range client.Events(ctx, tmigo.EventFilter{Events: []string{"ban", "timeout"}}) {
+//	    switch e := e.(type) {
+//	    case tmigo.BanEvent:
+//	        log.Printf("%s banned in %s", e.Username, e.Channel)
+//	    case tmigo.TimeoutEvent:
+//	        log.Printf("%s timed out for %ds", e.Username, e.Duration)
+//	    }
+//	}
+func (c *Client) Events(ctx context.Context, filter EventFilter) <-chan Event {
+	return openStream[Event](c, ctx, filter)
+}
+
+// Messages returns a channel of chat, action and whisper messages that is
+// closed once ctx is done
+func (c *Client) Messages(ctx context.Context) <-chan MessageEvent {
+	return openStream[MessageEvent](c, ctx, EventFilter{Events: []string{"message"}})
+}
+
+// Subs returns a channel of new subscriptions that is closed once ctx is done
+func (c *Client) Subs(ctx context.Context) <-chan SubEvent {
+	return openStream[SubEvent](c, ctx, EventFilter{Events: []string{"subscription"}})
+}
+
+// Resubs returns a channel of resubscriptions that is closed once ctx is done
+func (c *Client) Resubs(ctx context.Context) <-chan ResubEvent {
+	return openStream[ResubEvent](c, ctx, EventFilter{Events: []string{"resub"}})
+}
+
+// Bans returns a channel of permanent bans that is closed once ctx is done
+func (c *Client) Bans(ctx context.Context) <-chan BanEvent {
+	return openStream[BanEvent](c, ctx, EventFilter{Events: []string{"ban"}})
+}
+
+// Timeouts returns a channel of timeouts that is closed once ctx is done
+func (c *Client) Timeouts(ctx context.Context) <-chan TimeoutEvent {
+	return openStream[TimeoutEvent](c, ctx, EventFilter{Events: []string{"timeout"}})
+}
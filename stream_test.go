@@ -0,0 +1,122 @@
+package tmigo
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestClient_MessagesStream(t *testing.T) {
+	client := NewClient(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	messages := client.Messages(ctx)
+	client.Emit("message", "#a", ChatUserstate{Username: "viewer"}, "hello", false)
+
+	got := <-messages
+	if got.Channel != "#a" || got.Message != "hello" || got.Userstate.Username != "viewer" || got.Self {
+		t.Errorf("received %+v, want hello from viewer in #a", got)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-messages:
+		if ok {
+			t.Error("stream delivered an event after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream not closed after cancel")
+	}
+	if n := client.ListenerCount("message"); n != 0 {
+		t.Errorf("ListenerCount = %d after cancel, want 0", n)
+	}
+}
+
+func TestClient_EventsFilter(t *testing.T) {
+	client := NewClient(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := client.Events(ctx, EventFilter{
+		Events: []string{"ban", "timeout"},
+		Match:  func(e Event) bool { return e.Name() != "timeout" || e.(TimeoutEvent).Duration > 60 },
+	})
+
+	client.Emit("message", "#a", ChatUserstate{}, "hello", false)
+	client.Emit("timeout", "#a", "short", "", 10, TimeoutUserstate{})
+	client.Emit("timeout", "#a", "long", "", 600, TimeoutUserstate{})
+	client.Emit("ban", "#a", "spammer", "spam", BanUserstate{})
+
+	var got []string
+	for range 2 {
+		switch e := (<-events).(type) {
+		case TimeoutEvent:
+			got = append(got, "timeout "+e.Username)
+		case BanEvent:
+			got = append(got, "ban "+e.Username)
+		}
+	}
+	if want := []string{"timeout long", "ban spammer"}; !slices.Equal(got, want) {
+		t.Errorf("received %v, want %v", got, want)
+	}
+}
+
+func TestClient_StreamOverflow(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		want   []string
+	}{
+		{OverflowDropNewest, []string{"1", "2"}},
+		{OverflowDropOldest, []string{"3", "4"}},
+		{"", []string{"3", "4"}}, // A stream never holds up the read loop by default
+	}
+
+	for _, tt := range tests {
+		t.Run(cmp.Or(string(tt.policy), "default"), func(t *testing.T) {
+			client := NewClient(&ClientOptions{Streams: &Streams{BufferSize: 2, Overflow: tt.policy}})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			messages := client.Messages(ctx)
+			for _, msg := range []string{"1", "2", "3", "4"} {
+				client.Emit("message", "#a", ChatUserstate{}, msg, false)
+			}
+
+			got := []string{(<-messages).Message, (<-messages).Message}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("received %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_StreamBlockedUntilCancel(t *testing.T) {
+	client := NewClient(&ClientOptions{Streams: &Streams{BufferSize: 1, Overflow: OverflowBlock}})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	messages := client.Messages(ctx)
+	client.Emit("message", "#a", ChatUserstate{}, "1", false)
+
+	emitted := make(chan struct{})
+	go func() {
+		client.Emit("message", "#a", ChatUserstate{}, "2", false)
+		close(emitted)
+	}()
+
+	select {
+	case <-emitted:
+		t.Fatal("Emit did not wait for the full stream")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-emitted:
+	case <-time.After(time.Second):
+		t.Fatal("Emit still blocked after cancel")
+	}
+	for range messages {
+	}
+}
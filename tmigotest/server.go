@@ -0,0 +1,407 @@
+// Package tmigotest provides an in-process Twitch IRC server for testing
+// bots built on tmigo without connecting to Twitch.
+//
+// Point the client's connection at the server:
+//
+//	srv := tmigotest.NewServer()
+//	defer srv.Close()
+//
+//	client := tmigo.NewClient(&tmigo.ClientOptions{
+//	    Connection: &tmigo.Connection{Server: srv.Host(), Port: srv.Port()},
+//	    Identity:   &tmigo.Identity{Username: "bot", Password: "oauth:token"},
+//	    Channels:   []string{"channel"},
+//	})
+//
+// The server answers the login, JOIN, PART and PING the way Twitch does.
+// Chat lines are injected with Send, PrivMsg, UserNotice and ClearChat, and
+// everything the client sent can be inspected with Lines or WaitForLine.
+package tmigotest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Server is a local websocket server speaking Twitch's IRC dialect
+type Server struct {
+	// RejectLogin makes the server answer NICK with a login failure NOTICE
+	RejectLogin bool
+	// Token, when set, makes the server reject logins that did not send it
+	// with PASS
+	Token string
+
+	server      *httptest.Server
+	mu          sync.Mutex
+	conns       []*conn
+	connections int
+	header      http.Header
+	moderator   map[string]bool
+	lines       []string
+	changed     chan struct{}
+	messageIDs  atomic.Int64
+}
+
+// conn is one client connection
+type conn struct {
+	mu       sync.Mutex
+	ws       *websocket.Conn
+	username string
+	pass     string
+}
+
+// send writes lines to the client as a single websocket message
+func (c *conn) send(lines ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ws.WriteMessage(websocket.TextMessage, []byte(strings.Join(lines, "\r\n")))
+}
+
+// NewServer starts a server on a local port. Close it when done.
+func NewServer() *Server {
+	s := newServer()
+	s.server.Start()
+	return s
+}
+
+// NewTLSServer starts a server that serves wss:// with a self-signed
+// certificate. Connect with Secure set and the config from TLSConfig.
+func NewTLSServer() *Server {
+	s := newServer()
+	s.server.StartTLS()
+	return s
+}
+
+func newServer() *Server {
+	s := &Server{
+		moderator: make(map[string]bool),
+		changed:   make(chan struct{}),
+	}
+
+	upgrader := websocket.Upgrader{}
+	s.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		c := &conn{ws: ws}
+		s.mu.Lock()
+		s.conns = append(s.conns, c)
+		s.connections++
+		s.header = r.Header.Clone()
+		s.mu.Unlock()
+
+		s.serve(c)
+	}))
+
+	return s
+}
+
+// Close drops every connection and shuts the server down
+func (s *Server) Close() {
+	s.Drop()
+	s.server.Close()
+}
+
+// Host returns the host for Connection.Server
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.server.Listener.Addr().String())
+	return host
+}
+
+// Port returns the port for Connection.Port
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.server.Listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// TLSConfig returns a client TLS config that trusts the certificate of a
+// server started with NewTLSServer
+func (s *Server) TLSConfig() *tls.Config {
+	pool := x509.NewCertPool()
+	if cert := s.server.Certificate(); cert != nil {
+		pool.AddCert(cert)
+	}
+	return &tls.Config{RootCAs: pool}
+}
+
+// RequestHeader returns the headers of the most recent websocket handshake
+func (s *Server) RequestHeader() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.header.Clone()
+}
+
+// ConnectionCount returns how many connections the server has accepted
+func (s *Server) ConnectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// SetModerator makes the USERSTATE sent on joining a channel mark the
+// client as a moderator
+func (s *Server) SetModerator(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.moderator[channelName(channel)] = true
+}
+
+// Send writes raw IRC lines to every connected client
+func (s *Server) Send(lines ...string) {
+	s.mu.Lock()
+	conns := slices.Clone(s.conns)
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.send(lines...)
+	}
+}
+
+// PrivMsg sends a chat message from a user to a channel. Every message gets
+// its own UUID-shaped id like on Twitch, unless tags sets one.
+func (s *Server) PrivMsg(channel, username, message string, tags map[string]string) {
+	id := fmt.Sprintf("00000000-0000-4000-8000-%012x", s.messageIDs.Add(1))
+	all := map[string]string{"display-name": username, "user-id": "1", "id": id}
+	maps.Copy(all, tags)
+	s.Send(fmt.Sprintf("%s:%[2]s!%[2]s@%[2]s.tmi.twitch.tv PRIVMSG %s :%s", formatTags(all), username, channelName(channel), message))
+}
+
+// UserNotice sends a USERNOTICE with the given msg-id, e.g. "sub" or "raid"
+func (s *Server) UserNotice(channel, msgID, message string, tags map[string]string) {
+	all := map[string]string{"msg-id": msgID}
+	maps.Copy(all, tags)
+
+	line := fmt.Sprintf("%s:tmi.twitch.tv USERNOTICE %s", formatTags(all), channelName(channel))
+	if message != "" {
+		line += " :" + message
+	}
+	s.Send(line)
+}
+
+// ClearChat sends a CLEARCHAT for a user, or for the whole channel when
+// username is empty
+func (s *Server) ClearChat(channel, username string, tags map[string]string) {
+	line := fmt.Sprintf("%s:tmi.twitch.tv CLEARCHAT %s", formatTags(tags), channelName(channel))
+	if username != "" {
+		line += " :" + username
+	}
+	s.Send(line)
+}
+
+// Notice sends a NOTICE with the given msg-id
+func (s *Server) Notice(channel, msgID, message string) {
+	s.Send(fmt.Sprintf("@msg-id=%s :tmi.twitch.tv NOTICE %s :%s", msgID, channelName(channel), message))
+}
+
+// Reconnect asks every client to reconnect, like Twitch does before a
+// server restart
+func (s *Server) Reconnect() {
+	s.Send(":tmi.twitch.tv RECONNECT")
+}
+
+// Drop closes every connection from the server side
+func (s *Server) Drop() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.ws.Close()
+	}
+}
+
+// Lines returns every line the clients have sent so far
+func (s *Server) Lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.lines)
+}
+
+// WaitForLine waits until a client has sent a line that matches and returns
+// it. Lines sent before the call count as well.
+func (s *Server) WaitForLine(timeout time.Duration, match func(line string) bool) (string, bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	seen := 0
+	for {
+		s.mu.Lock()
+		lines := s.lines[seen:]
+		seen = len(s.lines)
+		changed := s.changed
+		s.mu.Unlock()
+
+		for _, line := range lines {
+			if match(line) {
+				return line, true
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return "", false
+		}
+	}
+}
+
+// HasPrefix matches lines starting with prefix, for use with WaitForLine
+func HasPrefix(prefix string) func(string) bool {
+	return func(line string) bool {
+		return strings.HasPrefix(line, prefix)
+	}
+}
+
+// record stores a line sent by a client and wakes WaitForLine
+func (s *Server) record(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, line)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) serve(c *conn) {
+	defer c.ws.Close()
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\r\n") {
+			if line == "" {
+				continue
+			}
+			s.record(line)
+			s.reply(c, line)
+		}
+	}
+}
+
+// reply answers a client line the way Twitch would
+func (s *Server) reply(c *conn, line string) {
+	command, params, _ := strings.Cut(line, " ")
+
+	switch command {
+	case "CAP":
+		caps := strings.TrimPrefix(params, "REQ :")
+		c.send(fmt.Sprintf(":tmi.twitch.tv CAP * ACK :%s", caps))
+
+	case "PASS":
+		c.pass = strings.TrimPrefix(params, "oauth:")
+
+	case "NICK":
+		if s.RejectLogin || (s.Token != "" && c.pass != s.Token) {
+			c.send(":tmi.twitch.tv NOTICE * :Login authentication failed")
+			return
+		}
+
+		c.username = params
+		c.send(
+			fmt.Sprintf(":tmi.twitch.tv 001 %s :Welcome, GLHF!", params),
+			fmt.Sprintf(":tmi.twitch.tv 002 %s :Your host is tmi.twitch.tv", params),
+			fmt.Sprintf(":tmi.twitch.tv 003 %s :This server is rather new", params),
+			fmt.Sprintf(":tmi.twitch.tv 004 %s :-", params),
+			fmt.Sprintf(":tmi.twitch.tv 375 %s :-", params),
+			fmt.Sprintf(":tmi.twitch.tv 372 %s :You are in a maze of twisty passages, all alike.", params),
+			fmt.Sprintf(":tmi.twitch.tv 376 %s :>", params),
+		)
+		if !isAnonymous(params) {
+			c.send(fmt.Sprintf("@badge-info=;badges=;color=;display-name=%s;emote-sets=0;user-id=1;user-type= :tmi.twitch.tv GLOBALUSERSTATE", params))
+		}
+
+	case "JOIN":
+		for _, channel := range strings.Split(params, ",") {
+			s.join(c, channel)
+		}
+
+	case "PART":
+		c.send(fmt.Sprintf(":%[1]s!%[1]s@%[1]s.tmi.twitch.tv PART %[2]s", c.username, params))
+
+	case "PING":
+		c.send("PONG :tmi.twitch.tv")
+	}
+}
+
+// join answers a JOIN for a single channel
+func (s *Server) join(c *conn, channel string) {
+	s.mu.Lock()
+	mod := s.moderator[channel]
+	s.mu.Unlock()
+
+	lines := []string{
+		fmt.Sprintf(":%[1]s!%[1]s@%[1]s.tmi.twitch.tv JOIN %[2]s", c.username, channel),
+		fmt.Sprintf(":%[1]s.tmi.twitch.tv 353 %[1]s = %[2]s :%[1]s", c.username, channel),
+		fmt.Sprintf(":%[1]s.tmi.twitch.tv 366 %[1]s %[2]s :End of /NAMES list", c.username, channel),
+	}
+
+	// Anonymous users do not receive USERSTATE
+	if !isAnonymous(c.username) {
+		userType := ""
+		if mod {
+			userType = "mod"
+		}
+		lines = append(lines, fmt.Sprintf("@badge-info=;badges=;color=;display-name=%s;emote-sets=0;mod=%s;subscriber=0;user-type=%s :tmi.twitch.tv USERSTATE %s",
+			c.username, boolTag(mod), userType, channel))
+	}
+
+	lines = append(lines, fmt.Sprintf("@emote-only=0;followers-only=-1;r9k=0;room-id=12345;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE %s", channel))
+	c.send(lines...)
+}
+
+// formatTags renders tags as an IRCv3 tag prefix, including the trailing
+// space, in a stable order
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		parts = append(parts, key+"="+escapeTag(tags[key]))
+	}
+	return "@" + strings.Join(parts, ";") + " "
+}
+
+var tagEscaper = strings.NewReplacer("\\", "\\\\", ";", "\\:", " ", "\\s", "\r", "\\r", "\n", "\\n")
+
+func escapeTag(value string) string {
+	return tagEscaper.Replace(value)
+}
+
+func channelName(channel string) string {
+	channel = strings.ToLower(channel)
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+	return channel
+}
+
+func isAnonymous(username string) bool {
+	return strings.HasPrefix(username, "justinfan")
+}
+
+func boolTag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
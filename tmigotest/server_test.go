@@ -0,0 +1,178 @@
+package tmigotest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ktnuity/tmigo"
+	"github.com/ktnuity/tmigo/tmigotest"
+)
+
+func newClient(t *testing.T, srv *tmigotest.Server, channels ...string) *tmigo.Client {
+	t.Helper()
+
+	client := tmigo.NewClient(&tmigo.ClientOptions{
+		Connection: &tmigo.Connection{
+			Server:            srv.Host(),
+			Port:              srv.Port(),
+			ReconnectInterval: 10 * time.Millisecond,
+		},
+		Identity: &tmigo.Identity{Username: "bot", Password: "oauth:token"},
+		Channels: channels,
+	})
+	t.Cleanup(func() { client.Disconnect() })
+
+	return client
+}
+
+// receive returns a channel that gets a value every time event fires
+func receive(client *tmigo.Client, event string) <-chan []any {
+	ch := make(chan []any, 16)
+	client.On(event, func(args ...any) {
+		select {
+		case ch <- args:
+		default:
+		}
+	})
+	return ch
+}
+
+func await(t *testing.T, ch <-chan []any, event string) []any {
+	t.Helper()
+
+	select {
+	case args := <-ch:
+		return args
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s was not emitted", event)
+		return nil
+	}
+}
+
+func TestServer_LoginAndJoin(t *testing.T) {
+	srv := tmigotest.NewServer()
+	defer srv.Close()
+
+	client := newClient(t, srv, "#channel")
+	roomstate := receive(client, "roomstate")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	await(t, roomstate, "roomstate")
+
+	for _, prefix := range []string{"CAP REQ :", "PASS oauth:token", "NICK bot", "JOIN #channel"} {
+		if _, ok := srv.WaitForLine(time.Second, tmigotest.HasPrefix(prefix)); !ok {
+			t.Errorf("client did not send %q, lines = %q", prefix, srv.Lines())
+		}
+	}
+	if got := client.GetChannels(); len(got) != 1 || got[0] != "#channel" {
+		t.Errorf("GetChannels() = %v, want [#channel]", got)
+	}
+}
+
+func TestServer_Inject(t *testing.T) {
+	srv := tmigotest.NewServer()
+	defer srv.Close()
+
+	client := newClient(t, srv, "#channel")
+	roomstate := receive(client, "roomstate")
+	messages := receive(client, "message")
+	raids := receive(client, "raided")
+	timeouts := receive(client, "timeout")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	await(t, roomstate, "roomstate")
+
+	srv.PrivMsg("channel", "viewer", "hello world", nil)
+	args := await(t, messages, "message")
+	if args[2] != "hello world" {
+		t.Errorf("message text = %v, want hello world", args[2])
+	}
+	first := args[1].(tmigo.ChatUserstate).ID
+
+	srv.PrivMsg("channel", "viewer", "hello again", nil)
+	args = await(t, messages, "message")
+	if id := args[1].(tmigo.ChatUserstate).ID; first == "" || id == first {
+		t.Errorf("message ids = %q and %q, want a different id per message", first, id)
+	}
+
+	srv.UserNotice("channel", "raid", "", map[string]string{
+		"login":                 "raider",
+		"msg-param-displayName": "Raider",
+		"msg-param-viewerCount": "15",
+	})
+	args = await(t, raids, "raided")
+	if args[1] != "raider" {
+		t.Errorf("raided username = %v, want raider", args[1])
+	}
+
+	srv.ClearChat("channel", "viewer", map[string]string{"ban-duration": "600"})
+	args = await(t, timeouts, "timeout")
+	if args[1] != "viewer" {
+		t.Errorf("timeout username = %v, want viewer", args[1])
+	}
+}
+
+func TestServer_Say(t *testing.T) {
+	srv := tmigotest.NewServer()
+	defer srv.Close()
+
+	client := newClient(t, srv, "#channel")
+	roomstate := receive(client, "roomstate")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	await(t, roomstate, "roomstate")
+
+	if err := client.Say("channel", "hi there"); err != nil {
+		t.Fatalf("Say() error = %v", err)
+	}
+	line, ok := srv.WaitForLine(time.Second, tmigotest.HasPrefix("PRIVMSG #channel"))
+	if !ok {
+		t.Fatalf("client did not send PRIVMSG, lines = %q", srv.Lines())
+	}
+	if !strings.HasSuffix(line, ":hi there") {
+		t.Errorf("PRIVMSG = %q, want message hi there", line)
+	}
+}
+
+func TestServer_Reconnect(t *testing.T) {
+	srv := tmigotest.NewServer()
+	defer srv.Close()
+
+	client := newClient(t, srv, "#channel")
+	roomstate := receive(client, "roomstate")
+	connected := receive(client, "connected")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	await(t, connected, "connected")
+	await(t, roomstate, "roomstate")
+
+	srv.Reconnect()
+	await(t, connected, "connected")
+	await(t, roomstate, "roomstate")
+
+	if n := srv.ConnectionCount(); n != 2 {
+		t.Errorf("ConnectionCount() = %d, want 2", n)
+	}
+}
+
+func TestServer_RejectLogin(t *testing.T) {
+	srv := tmigotest.NewServer()
+	srv.RejectLogin = true
+	defer srv.Close()
+
+	client := newClient(t, srv)
+	disconnected := receive(client, "disconnected")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	args := await(t, disconnected, "disconnected")
+	if reason, _ := args[0].(string); !strings.Contains(reason, "Login authentication failed") {
+		t.Errorf("disconnected reason = %v, want login failure", args[0])
+	}
+}
@@ -0,0 +1,183 @@
+package tmigo
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TwitchTokenURL is Twitch's OAuth token endpoint
+const TwitchTokenURL = "https://id.twitch.tv/oauth2/token"
+
+// TokenProvider supplies the OAuth token for the login. When set on the
+// Identity it replaces Password: Token is called before every PASS, so a
+// reconnect uses the current token.
+type TokenProvider interface {
+	// Token returns the token for the next login
+	Token(ctx context.Context) (string, error)
+	// Refresh is called when Twitch rejects the token. After it succeeds the
+	// client reconnects once and calls Token again; when that login is
+	// rejected as well, the client gives up like it does with Password.
+	Refresh(ctx context.Context) error
+}
+
+// OAuthToken is the result of a refresh
+type OAuthToken struct {
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	ExpiresIn    int      `json:"expires_in"` // Seconds
+	Scope        []string `json:"scope"`
+	TokenType    string   `json:"token_type"`
+}
+
+// TokenError is returned when the token endpoint rejects a refresh, e.g.
+// because the refresh token was revoked
+type TokenError struct {
+	Status  int
+	Message string
+}
+
+// Error implements the error interface
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("token refresh failed: %d %s", e.Status, e.Message)
+}
+
+// RefreshingTokenProvider is a TokenProvider that renews the access token
+// with the OAuth refresh token grant. It refreshes when Twitch rejects the
+// token and shortly before a known expiry. Twitch may hand out a new refresh
+// token with every refresh; OnRefresh receives it to store it.
+type RefreshingTokenProvider struct {
+	ClientID     string
+	ClientSecret string
+	AccessToken  string // Current token; refreshed before the first login when empty
+	RefreshToken string
+	TokenURL     string               // Token endpoint (default TwitchTokenURL)
+	HTTPClient   *http.Client         // Client for the token endpoint (default http.DefaultClient)
+	OnRefresh    func(tok OAuthToken) // Called after every successful refresh
+
+	mu        sync.Mutex
+	expiresAt time.Time
+}
+
+// Token returns the access token, refreshing it first when it is missing or
+// about to expire
+func (p *RefreshingTokenProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	expiring := !p.expiresAt.IsZero() && time.Until(p.expiresAt) < time.Minute
+	if p.AccessToken == "" || expiring {
+		if err := p.refresh(ctx); err != nil {
+			return "", err
+		}
+	}
+	return p.AccessToken, nil
+}
+
+// Refresh exchanges the refresh token for a new access token
+func (p *RefreshingTokenProvider) Refresh(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.refresh(ctx)
+}
+
+// refresh performs the refresh token grant. p.mu must be held.
+func (p *RefreshingTokenProvider) refresh(ctx context.Context) error {
+	if p.RefreshToken == "" {
+		return errors.New("token refresh failed: no refresh token")
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {p.RefreshToken},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cmp.Or(p.TokenURL, TwitchTokenURL), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := cmp.Or(p.HTTPClient, http.DefaultClient).Do(req)
+	if err != nil {
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return &TokenError{Status: resp.StatusCode, Message: cmp.Or(body.Message, resp.Status)}
+	}
+
+	var tok OAuthToken
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return fmt.Errorf("token refresh failed: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return errors.New("token refresh failed: no access token in the response")
+	}
+
+	p.AccessToken = tok.AccessToken
+	p.RefreshToken = cmp.Or(tok.RefreshToken, p.RefreshToken)
+	p.expiresAt = time.Time{}
+	if tok.ExpiresIn > 0 {
+		p.expiresAt = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	}
+	if p.OnRefresh != nil {
+		p.OnRefresh(tok)
+	}
+	return nil
+}
+
+// password returns the PASS token from the TokenProvider, or Password
+func (c *Client) password() (string, error) {
+	provider := c.state.opts.Identity.TokenProvider
+	if provider == nil {
+		return c.state.opts.Identity.Password, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.state.opts.Connection.Timeout)
+	defer cancel()
+	return provider.Token(ctx)
+}
+
+// refreshToken asks the TokenProvider for a new token after Twitch rejected
+// the login. It reports whether the client should retry the login; only one
+// retry is made until a login succeeds.
+func (c *Client) refreshToken() bool {
+	provider := c.state.opts.Identity.TokenProvider
+	if provider == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	retried := c.state.tokenRetry
+	c.state.tokenRetry = !retried
+	c.mu.Unlock()
+	if retried {
+		return false
+	}
+
+	c.state.log.Info("Login rejected, refreshing the token..")
+	ctx, cancel := context.WithTimeout(context.Background(), c.state.opts.Connection.Timeout)
+	defer cancel()
+	if err := provider.Refresh(ctx); err != nil {
+		c.state.log.Error(fmt.Sprintf("Could not refresh the token: %v", err))
+		c.mu.Lock()
+		c.state.tokenRetry = false
+		c.mu.Unlock()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package tmigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// tokenEndpoint stands in for Twitch's token endpoint. It hands out
+// "token1", "token2", .. with a rotated refresh token, and rejects refresh
+// tokens other than the last one handed out.
+func tokenEndpoint(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var refreshes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("client_id") != "id" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		n := refreshes.Load()
+		if r.Form.Get("refresh_token") != fmt.Sprintf("refresh%d", n) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":400,"message":"Invalid refresh token"}`)
+			return
+		}
+		n = refreshes.Add(1)
+		fmt.Fprintf(w, `{"access_token":"token%d","refresh_token":"refresh%[1]d","expires_in":3600,"scope":["chat:read"],"token_type":"bearer"}`, n)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &refreshes
+}
+
+func TestRefreshingTokenProvider(t *testing.T) {
+	srv, _ := tokenEndpoint(t)
+
+	var stored []string
+	p := &RefreshingTokenProvider{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		RefreshToken: "refresh0",
+		TokenURL:     srv.URL,
+		OnRefresh:    func(tok OAuthToken) { stored = append(stored, tok.RefreshToken) },
+	}
+
+	// The first Token refreshes, the second reuses the unexpired token
+	for range 2 {
+		token, err := p.Token(t.Context())
+		if err != nil || token != "token1" {
+			t.Fatalf("Token() = %q, %v, want token1", token, err)
+		}
+	}
+	if err := p.Refresh(t.Context()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if token, _ := p.Token(t.Context()); token != "token2" {
+		t.Errorf("Token() = %q after Refresh, want token2", token)
+	}
+	if fmt.Sprint(stored) != "[refresh1 refresh2]" {
+		t.Errorf("OnRefresh got %v, want the rotated refresh tokens", stored)
+	}
+
+	p.RefreshToken = "revoked"
+	var tokenErr *TokenError
+	if err := p.Refresh(t.Context()); !errors.As(err, &tokenErr) || tokenErr.Status != 400 || tokenErr.Message != "Invalid refresh token" {
+		t.Errorf("Refresh() error = %v, want a 400 TokenError", err)
+	}
+}
+
+func TestClient_TokenRefreshOnAuthFailure(t *testing.T) {
+	server, opts := testOptions(t, "#a")
+	server.Token = "token1"
+	srv, refreshes := tokenEndpoint(t)
+	opts.Identity.Password = ""
+	opts.Identity.TokenProvider = &RefreshingTokenProvider{
+		ClientID:     "id",
+		AccessToken:  "expired",
+		RefreshToken: "refresh0",
+		TokenURL:     srv.URL,
+	}
+	client := NewClient(opts)
+
+	var authFailed atomic.Int32
+	client.On("authfailed", func(args ...any) { authFailed.Add(1) })
+
+	if err := client.ConnectContext(t.Context(), ReadyChannelsJoined); err != nil {
+		t.Fatalf("ConnectContext() error = %v", err)
+	}
+	defer client.Disconnect()
+
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("token refreshed %d times, want 1", n)
+	}
+	if n := authFailed.Load(); n != 0 {
+		t.Errorf("authfailed fired %d times for a refreshed token", n)
+	}
+	if n := countLines(server, "PASS oauth:token1"); n != 1 {
+		t.Errorf("server saw %d logins with the new token, want 1", n)
+	}
+}
+
+func TestClient_TokenRefreshGivesUp(t *testing.T) {
+	server, opts := testOptions(t)
+	server.RejectLogin = true
+	srv, refreshes := tokenEndpoint(t)
+	opts.Identity.TokenProvider = &RefreshingTokenProvider{
+		ClientID:     "id",
+		AccessToken:  "expired",
+		RefreshToken: "refresh0",
+		TokenURL:     srv.URL,
+	}
+	client := NewClient(opts)
+
+	err := client.ConnectContext(t.Context(), ReadyLoggedOn)
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("ConnectContext() error = %v, want ErrAuthFailed", err)
+	}
+
+	// One refresh and one retry, not a loop
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("token refreshed %d times, want 1", n)
+	}
+	if n := server.ConnectionCount(); n != 2 {
+		t.Errorf("server saw %d connections, want 2", n)
+	}
+}
+
+// staticToken is a TokenProvider whose refresh fails
+type staticToken string
+
+func (s staticToken) Token(ctx context.Context) (string, error) { return string(s), nil }
+func (s staticToken) Refresh(ctx context.Context) error         { return errors.New("cannot refresh") }
+
+func TestClient_TokenRefreshFails(t *testing.T) {
+	server, opts := testOptions(t)
+	server.Token = "other"
+	opts.Identity.TokenProvider = staticToken("mine")
+	client := NewClient(opts)
+
+	if err := client.ConnectContext(t.Context(), ReadyLoggedOn); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("ConnectContext() error = %v, want ErrAuthFailed", err)
+	}
+	if n := server.ConnectionCount(); n != 1 {
+		t.Errorf("server saw %d connections, want 1", n)
+	}
+	if n := countLines(server, "PASS oauth:mine"); n != 1 {
+		t.Errorf("server saw %d logins with the provider's token, want 1", n)
+	}
+}
@@ -0,0 +1,194 @@
+package tmigo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Conn is an open connection to the chat server. ReadMessage and Close may
+// be called concurrently with the other methods; WriteMessage and
+// SetWriteDeadline are only called by the connection's writer.
+type Conn interface {
+	// ReadMessage returns the next message from the server. A message holds
+	// one or more lines separated by CRLF.
+	ReadMessage() ([]byte, error)
+	// WriteMessage writes a single line to the server
+	WriteMessage(data []byte) error
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+// Transport opens connections to the chat server. Dial is called for the
+// first connection and for every reconnect with the client's connection
+// options; it should honor Server, Port, Secure and the dialing options
+// (Dialer, Proxy, TLSConfig, Header) that apply to it, and give up when ctx
+// is done.
+type Transport interface {
+	Dial(ctx context.Context, opts *Connection) (Conn, error)
+}
+
+// transportDefaults is implemented by transports that know which Twitch
+// endpoint they connect to when Connection.Server and Port are not set
+type transportDefaults interface {
+	defaults() (server string, port, securePort int)
+}
+
+// WebSocketTransport connects to Twitch's IRC-over-websocket endpoint. This
+// is the default transport.
+type WebSocketTransport struct{}
+
+// Dial opens a websocket connection. Connection.WebSocketDialer is used as a
+// template when set; the proxy, TLS and timeout options on the connection
+// take precedence over its fields.
+func (WebSocketTransport) Dial(ctx context.Context, opts *Connection) (Conn, error) {
+	protocol := "ws"
+	if opts.Secure {
+		protocol = "wss"
+	}
+
+	dialer := websocket.Dialer{}
+	if opts.WebSocketDialer != nil {
+		dialer = *opts.WebSocketDialer
+	} else {
+		dialer.HandshakeTimeout = websocket.DefaultDialer.HandshakeTimeout
+	}
+	addr := net.JoinHostPort(opts.Server, strconv.Itoa(opts.Port))
+	if opts.WebSocketDialer == nil || opts.Dialer != nil || opts.Proxy != nil {
+		// Like websocket.DefaultDialer, use HTTP_PROXY, HTTPS_PROXY and
+		// NO_PROXY when no proxy is configured
+		proxied := opts
+		if opts.Proxy == nil {
+			proxy, err := environmentProxy(opts.Secure, addr)
+			if err != nil {
+				return nil, err
+			}
+			if proxy != nil {
+				withProxy := *opts
+				withProxy.Proxy = proxy
+				proxied = &withProxy
+			}
+		}
+
+		// Proxies are dialed by dialTCP so both transports share them
+		dialer.Proxy = nil
+		dialer.NetDial = nil
+		dialer.NetDialTLSContext = nil
+		dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialTCP(ctx, proxied, addr)
+		}
+	}
+	if opts.TLSConfig != nil {
+		dialer.TLSClientConfig = opts.TLSConfig
+	}
+
+	url := fmt.Sprintf("%s://%s/", protocol, addr)
+	ws, resp, err := dialer.DialContext(ctx, url, opts.Header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%w (%s)", err, resp.Status)
+		}
+		return nil, err
+	}
+	return &wsConn{ws}, nil
+}
+
+func (WebSocketTransport) defaults() (string, int, int) {
+	return "irc-ws.chat.twitch.tv", 80, 443
+}
+
+// wsConn adapts a websocket connection to Conn
+type wsConn struct {
+	*websocket.Conn
+}
+
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	_, data, err := c.Conn.ReadMessage()
+	return data, err
+}
+
+func (c *wsConn) WriteMessage(data []byte) error {
+	return c.Conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// TCPTransport connects to Twitch's plain IRC endpoint over TCP, or over TLS
+// when the connection is secure. Use it where proxies interfere with
+// websocket upgrades. Connection.Header and WebSocketDialer do not apply.
+type TCPTransport struct{}
+
+// Dial opens a TCP or TLS connection
+func (TCPTransport) Dial(ctx context.Context, opts *Connection) (Conn, error) {
+	addr := net.JoinHostPort(opts.Server, strconv.Itoa(opts.Port))
+
+	conn, err := dialTCP(ctx, opts, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Secure {
+		config := &tls.Config{}
+		if opts.TLSConfig != nil {
+			config = opts.TLSConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = opts.Server
+		}
+
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	return newTCPConn(conn), nil
+}
+
+func (TCPTransport) defaults() (string, int, int) {
+	return "irc.chat.twitch.tv", 6667, 6697
+}
+
+// tcpConn frames IRC lines on a stream connection. Each read returns one
+// line and each write is terminated with CRLF.
+type tcpConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func newTCPConn(conn net.Conn) *tcpConn {
+	return &tcpConn{Conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// ReadMessage returns the next line without its line ending. Servers that
+// end lines with a bare LF are accepted.
+func (c *tcpConn) ReadMessage() ([]byte, error) {
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 {
+			return line, nil
+		}
+	}
+}
+
+// WriteMessage writes a line followed by CRLF
+func (c *tcpConn) WriteMessage(data []byte) error {
+	line := make([]byte, 0, len(data)+2)
+	line = append(line, bytes.TrimRight(data, "\r\n")...)
+	line = append(line, '\r', '\n')
+
+	_, err := c.Conn.Write(line)
+	return err
+}
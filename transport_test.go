@@ -0,0 +1,179 @@
+package tmigo
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTCPConn_ReadMessage(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		io.WriteString(server, "PING :tmi.twitch.tv\r\n\r\n:tmi.twitch.tv 001 bot :Welcome\n:tmi.twitch.tv 376 bot :>\r\n")
+	}()
+
+	conn := newTCPConn(client)
+	want := []string{"PING :tmi.twitch.tv", ":tmi.twitch.tv 001 bot :Welcome", ":tmi.twitch.tv 376 bot :>"}
+	for _, w := range want {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		if string(data) != w {
+			t.Errorf("ReadMessage() = %q, want %q", data, w)
+		}
+	}
+}
+
+func TestTCPConn_WriteMessage(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	conn := newTCPConn(client)
+	go func() {
+		conn.WriteMessage([]byte("PRIVMSG #channel :hello"))
+		conn.WriteMessage([]byte("PING\r\n"))
+	}()
+
+	reader := bufio.NewReader(server)
+	for _, want := range []string{"PRIVMSG #channel :hello\r\n", "PING\r\n"} {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v", err)
+		}
+		if line != want {
+			t.Errorf("line = %q, want %q", line, want)
+		}
+	}
+}
+
+func TestNewClient_TransportDefaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection *Connection
+		wantServer string
+		wantPort   int
+		wantSecure bool
+	}{
+		{"websocket", &Connection{}, "irc-ws.chat.twitch.tv", 80, false},
+		{"websocket secure", &Connection{Secure: true}, "irc-ws.chat.twitch.tv", 443, true},
+		{"tcp", &Connection{Transport: TCPTransport{}}, "irc.chat.twitch.tv", 6667, false},
+		{"tcp secure", &Connection{Transport: TCPTransport{}, Secure: true}, "irc.chat.twitch.tv", 6697, true},
+		{"tcp tls port", &Connection{Transport: TCPTransport{}, Port: 6697}, "irc.chat.twitch.tv", 6697, true},
+		{"secure custom port", &Connection{Secure: true, Port: 8443}, "irc-ws.chat.twitch.tv", 8443, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&ClientOptions{Connection: tt.connection})
+			conn := client.state.opts.Connection
+
+			if conn.Server != tt.wantServer || conn.Port != tt.wantPort || conn.Secure != tt.wantSecure {
+				t.Errorf("Connection = %s:%d secure=%v, want %s:%d secure=%v",
+					conn.Server, conn.Port, conn.Secure, tt.wantServer, tt.wantPort, tt.wantSecure)
+			}
+		})
+	}
+}
+
+// newTCPIRCServer starts a plain IRC server that answers the login and JOIN,
+// over TLS when config is set
+func newTCPIRCServer(t *testing.T, config *tls.Config) *net.TCPAddr {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	if config != nil {
+		listener = tls.NewListener(listener, config)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					command, params, _ := strings.Cut(strings.TrimSuffix(scanner.Text(), "\r"), " ")
+					switch command {
+					case "NICK":
+						fmt.Fprintf(conn, ":tmi.twitch.tv 001 %[1]s :Welcome, GLHF!\r\n:tmi.twitch.tv 376 %[1]s :>\r\n", params)
+					case "JOIN":
+						fmt.Fprintf(conn, ":bot!bot@bot.tmi.twitch.tv JOIN %[1]s\r\n@mod=0;user-type= :tmi.twitch.tv USERSTATE %[1]s\r\n@room-id=12345;slow=0 :tmi.twitch.tv ROOMSTATE %[1]s\r\n", params)
+					}
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr)
+}
+
+func TestClient_TCPTransport(t *testing.T) {
+	addr := newTCPIRCServer(t, nil)
+	client := NewClient(&ClientOptions{
+		Connection: &Connection{
+			Transport: TCPTransport{},
+			Server:    addr.IP.String(),
+			Port:      addr.Port,
+		},
+		Identity: &Identity{Username: "bot", Password: "oauth:token"},
+		Channels: []string{"#a"},
+	})
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Disconnect()
+	wait()
+
+	if channels := client.GetChannels(); len(channels) != 1 || channels[0] != "#a" {
+		t.Errorf("GetChannels() = %v, want [#a]", channels)
+	}
+}
+
+func TestClient_TCPTransportTLS(t *testing.T) {
+	// Borrow a self-signed certificate from httptest
+	certServer := httptest.NewTLSServer(nil)
+	defer certServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(certServer.Certificate())
+
+	addr := newTCPIRCServer(t, certServer.TLS)
+	client := NewClient(&ClientOptions{
+		Connection: &Connection{
+			Transport: TCPTransport{},
+			Server:    addr.IP.String(),
+			Port:      addr.Port,
+			Secure:    true,
+			TLSConfig: &tls.Config{RootCAs: roots},
+		},
+		Identity: &Identity{Username: "bot", Password: "oauth:token"},
+		Channels: []string{"#a"},
+	})
+
+	wait := waitFor(t, client, "roomstate", 1)
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Disconnect()
+	wait()
+}
@@ -31,6 +31,11 @@
 package tmigo
 
 import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -41,6 +46,11 @@ type ClientOptions struct {
 	Options    *Options
 	Connection *Connection
 	Identity   *Identity
+	RateLimit  *RateLimit
+	Health     *Health
+	Dispatch   *Dispatch
+	Streams    *Streams
+	Middleware *Middleware
 	Channels   []string
 	Logger     Logger
 }
@@ -54,7 +64,8 @@ type Options struct {
 	MessagesLogLevel     string
 }
 
-// Connection contains WebSocket connection options
+// Connection contains connection options. The dialing options apply to the
+// first connection and to every reconnect.
 type Connection struct {
 	Server               string
 	Port                 int
@@ -65,12 +76,139 @@ type Connection struct {
 	MaxReconnectInterval time.Duration
 	MaxReconnectAttempts int
 	Timeout              time.Duration
+	WriteTimeout         time.Duration
+	WriteQueueSize       int
+	Transport            Transport
+
+	Dialer           *net.Dialer       // Dials the TCP connection, to the proxy when one is set
+	WebSocketDialer  *websocket.Dialer // Template for the websocket handshake
+	Proxy            *url.URL          // http://, socks5:// or socks5h:// proxy, with optional user info
+	TLSConfig        *tls.Config       // Used for secure connections, e.g. pinned roots or client certificates
+	Header           http.Header       // Extra websocket handshake headers
+	HandshakeTimeout time.Duration     // Limit for dialing, proxy and TLS handshakes (0 means no extra limit)
+
+	SeamlessReconnect bool // On RECONNECT, join on a new connection before closing the old one
+
+	Backoff       BackoffPolicy // Delay before each reconnect (default built from the Reconnect* fields)
+	BackoffReset  time.Duration // Uptime after which the backoff starts over (0 resets on every login)
+	ReconnectHook ReconnectHook // Can delay or veto a reconnect
+}
+
+// RateLimit configures the outbound chat message limiter and the join
+// budget. The zero value applies Twitch's limits for a regular account and
+// queues messages.
+type RateLimit struct {
+	Disabled      bool
+	Verified      bool            // Account is a verified bot
+	Policy        RateLimitPolicy // RateLimitQueue (default) or RateLimitReject
+	MaxQueue      int             // Messages allowed to wait at once (default 100)
+	NormalTier    RateLimitTier   // Default 20 messages per 30 seconds
+	ModeratorTier RateLimitTier   // Channels where the bot is moderator or broadcaster, default 100 per 30 seconds
+	VerifiedTier  RateLimitTier   // Default 7500 messages per 30 seconds
+
+	JoinTier         RateLimitTier // Default 20 joins per 10 seconds
+	VerifiedJoinTier RateLimitTier // Default 2000 joins per 10 seconds
+}
+
+// Health configures the keepalive and the latency statistics. The zero value
+// pings every 60 seconds and never reports the connection as degraded.
+type Health struct {
+	PingInterval    time.Duration // Time between keepalive PINGs (default 60s)
+	PingTimeout     time.Duration // Time to wait for the PONG before reconnecting (default Connection.Timeout)
+	Window          int           // Latency samples kept for the statistics (default 100)
+	DegradedLatency time.Duration // Latency from which the connection counts as degraded (0 disables)
+}
+
+// Dispatch configures how event listeners are run. The zero value runs them
+// on the goroutine that emits the event, usually the connection's read loop.
+// With Async set, events are queued to a pool of workers: events of one
+// channel always go to the same worker, so they keep their order. A listener
+// that panics is recovered in both modes and reported as an error event.
+type Dispatch struct {
+	Async     bool           // Run listeners on the worker pool
+	Workers   int            // Worker goroutines (default 4)
+	QueueSize int            // Events waiting per worker (default 256)
+	Overflow  OverflowPolicy // OverflowBlock (default), OverflowDropOldest or OverflowDropNewest
+}
+
+// Streams configures the channels returned by Events, Messages and the other
+// stream methods. Streams are fed from the listeners, which run on the
+// connection's read loop unless Dispatch.Async is set. With OverflowBlock, a
+// stream that is not drained holds up that loop, and with it PING replies,
+// the other listeners and the responses the ...Context commands wait for,
+// until the receiver catches up or the stream's context is done. A receiver
+// that sends commands from its loop would wait forever, so streams drop the
+// oldest event by default.
+type Streams struct {
+	BufferSize int            // Events buffered per stream (default 64)
+	Overflow   OverflowPolicy // OverflowDropOldest (default), OverflowDropNewest or OverflowBlock
+}
+
+// Middleware intercepts messages between the connection and the client.
+// Inbound middleware sees every parsed message before the client handles it,
+// outbound middleware every message and command before it is written. Each
+// list runs in order, the first entry seeing a message first.
+type Middleware struct {
+	Inbound  []InboundMiddleware
+	Outbound []OutboundMiddleware
+}
+
+// OverflowPolicy decides what happens to an event emitted while its queue
+// or stream buffer is full
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits until the queue has room. A listener that emits
+	// into its own full queue waits forever.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest drops the longest waiting event to make room
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest drops the event being emitted
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// RateLimitTier is a budget of messages per time window
+type RateLimitTier struct {
+	Messages int
+	Per      time.Duration
 }
 
+// RateLimitPolicy decides what happens to a message sent while the rate limit
+// is exhausted
+type RateLimitPolicy string
+
+const (
+	// RateLimitQueue holds the message until it can be sent
+	RateLimitQueue RateLimitPolicy = "queue"
+	// RateLimitReject drops the message and returns ErrRateLimited
+	RateLimitReject RateLimitPolicy = "reject"
+)
+
+// ConnectReady decides when ConnectContext considers the session ready
+type ConnectReady string
+
+const (
+	// ReadyLoggedOn waits until the server has accepted the login
+	ReadyLoggedOn ConnectReady = "loggedon"
+	// ReadyChannelsJoined also waits until every channel in
+	// ClientOptions.Channels has been joined
+	ReadyChannelsJoined ConnectReady = "channelsjoined"
+)
+
+// DropReason explains why an outbound message was not sent
+type DropReason string
+
+const (
+	DropReasonRateLimited  DropReason = "ratelimited"
+	DropReasonQueueFull    DropReason = "queuefull"
+	DropReasonDisconnected DropReason = "disconnected"
+)
+
 // Identity contains authentication credentials
 type Identity struct {
-	Username string
-	Password string
+	Username      string
+	Password      string
+	TokenProvider TokenProvider // Supplies the token before every login instead of Password
 }
 
 // IRCMessage represents a parsed IRC message
@@ -80,6 +218,8 @@ type IRCMessage struct {
 	Prefix  string
 	Command string
 	Params  []string
+
+	ctx context.Context // Set by the inbound middleware chain
 }
 
 // BadgeInfo represents badge-info from Twitch
@@ -211,6 +351,61 @@ const (
 	MsgIDWhisperLimitPerMin     MsgID = "whisper_limit_per_min"
 	MsgIDWhisperLimitPerSec     MsgID = "whisper_limit_per_sec"
 	MsgIDWhisperRestrictedRecip MsgID = "whisper_restricted_recipient"
+
+	MsgIDAlreadyR9kOff               MsgID = "already_r9k_off"
+	MsgIDAlreadyR9kOn                MsgID = "already_r9k_on"
+	MsgIDBadDeleteMessageBroadcaster MsgID = "bad_delete_message_broadcaster"
+	MsgIDBadDeleteMessageError       MsgID = "bad_delete_message_error"
+	MsgIDBadDeleteMessageMod         MsgID = "bad_delete_message_mod"
+	MsgIDBadHostError                MsgID = "bad_host_error"
+	MsgIDBadTimeoutBroadcaster       MsgID = "bad_timeout_broadcaster"
+	MsgIDBadTimeoutDuration          MsgID = "bad_timeout_duration"
+	MsgIDBadUnvipGranteeNotVip       MsgID = "bad_unvip_grantee_not_vip"
+	MsgIDBadVipAchievementIncomplete MsgID = "bad_vip_achievement_incomplete"
+	MsgIDBadVipGranteeAlreadyVip     MsgID = "bad_vip_grantee_already_vip"
+	MsgIDBadVipGranteeBanned         MsgID = "bad_vip_grantee_banned"
+	MsgIDBadVipMaxVipsReached        MsgID = "bad_vip_max_vips_reached"
+	MsgIDDeleteMessageSuccess        MsgID = "delete_message_success"
+	MsgIDFollowersOff                MsgID = "followers_off"
+	MsgIDFollowersOn                 MsgID = "followers_on"
+	MsgIDFollowersOnZero             MsgID = "followers_on_zero"
+	MsgIDHostOff                     MsgID = "host_off"
+	MsgIDHostOn                      MsgID = "host_on"
+	MsgIDInvalidUser                 MsgID = "invalid_user"
+	MsgIDMsgBadCharacters            MsgID = "msg_bad_characters"
+	MsgIDMsgChannelBlocked           MsgID = "msg_channel_blocked"
+	MsgIDMsgFollowersonly            MsgID = "msg_followersonly"
+	MsgIDMsgFollowersonlyFollowed    MsgID = "msg_followersonly_followed"
+	MsgIDMsgFollowersonlyZero        MsgID = "msg_followersonly_zero"
+	MsgIDMsgRejected                 MsgID = "msg_rejected"
+	MsgIDMsgRejectedMandatory        MsgID = "msg_rejected_mandatory"
+	MsgIDMsgRoomNotFound             MsgID = "msg_room_not_found"
+	MsgIDMsgSlowmode                 MsgID = "msg_slowmode"
+	MsgIDMsgSuspended                MsgID = "msg_suspended"
+	MsgIDNoMods                      MsgID = "no_mods"
+	MsgIDNoVips                      MsgID = "no_vips"
+	MsgIDR9kOff                      MsgID = "r9k_off"
+	MsgIDR9kOn                       MsgID = "r9k_on"
+	MsgIDRoomMods                    MsgID = "room_mods"
+	MsgIDSlowOff                     MsgID = "slow_off"
+	MsgIDSlowOn                      MsgID = "slow_on"
+	MsgIDSubsOff                     MsgID = "subs_off"
+	MsgIDSubsOn                      MsgID = "subs_on"
+	MsgIDTosBan                      MsgID = "tos_ban"
+	MsgIDTurboOnlyColor              MsgID = "turbo_only_color"
+	MsgIDUnavailableCommand          MsgID = "unavailable_command"
+	MsgIDUntimeoutSuccess            MsgID = "untimeout_success"
+	MsgIDUnvipSuccess                MsgID = "unvip_success"
+	MsgIDUsageDelete                 MsgID = "usage_delete"
+	MsgIDUsageFollowersOff           MsgID = "usage_followers_off"
+	MsgIDUsageFollowersOn            MsgID = "usage_followers_on"
+	MsgIDUsageUnvip                  MsgID = "usage_unvip"
+	MsgIDUsageVip                    MsgID = "usage_vip"
+	MsgIDUsageVips                   MsgID = "usage_vips"
+	MsgIDVipSuccess                  MsgID = "vip_success"
+	MsgIDVipsSuccess                 MsgID = "vips_success"
+	MsgIDWhisperInvalidLogin         MsgID = "whisper_invalid_login"
+	MsgIDWhisperRestricted           MsgID = "whisper_restricted"
 )
 
 // CommonUserstate contains fields common to all userstate types
@@ -307,8 +502,9 @@ type SubUserstate struct {
 // SubMysteryGiftUserstate represents userstate for mystery gift subs
 type SubMysteryGiftUserstate struct {
 	CommonSubUserstate
-	MsgParamSenderCount string `json:"msg-param-sender-count,omitempty"`
-	MsgParamOriginID    string `json:"msg-param-origin-id"`
+	MsgParamMassGiftCount string `json:"msg-param-mass-gift-count,omitempty"`
+	MsgParamSenderCount   string `json:"msg-param-sender-count,omitempty"`
+	MsgParamOriginID      string `json:"msg-param-origin-id"`
 }
 
 // SubGiftUserstate represents userstate for gifted subs
@@ -326,6 +522,7 @@ type AnonSubGiftUserstate struct {
 // AnonSubMysteryGiftUserstate represents userstate for anonymous mystery gift subs
 type AnonSubMysteryGiftUserstate struct {
 	CommonSubUserstate
+	MsgParamMassGiftCount string `json:"msg-param-mass-gift-count,omitempty"`
 }
 
 // SubGiftUpgradeUserstate represents userstate for gift subscription upgrades
@@ -359,6 +556,34 @@ type RitualUserstate struct {
 	MsgParamRitualName string `json:"msg-param-ritual-name,omitempty"` // "new_chatter"
 }
 
+// UnraidUserstate represents userstate for cancelled raids
+type UnraidUserstate struct {
+	UserNoticeState
+}
+
+// BitsBadgeTierUserstate represents userstate for bits badge tier events
+type BitsBadgeTierUserstate struct {
+	UserNoticeState
+	MsgParamThreshold string `json:"msg-param-threshold,omitempty"`
+}
+
+// ViewerMilestoneUserstate represents userstate for viewer milestone events
+type ViewerMilestoneUserstate struct {
+	UserNoticeState
+	MsgParamCategory   string `json:"msg-param-category,omitempty"` // "watch-streak"
+	MsgParamValue      string `json:"msg-param-value,omitempty"`
+	MsgParamCopoReward string `json:"msg-param-copoReward,omitempty"`
+}
+
+// SharedChatNoticeUserstate represents userstate for notices relayed from
+// another channel in a shared chat session
+type SharedChatNoticeUserstate struct {
+	UserNoticeState
+	SourceMsgID  string `json:"source-msg-id,omitempty"`
+	SourceRoomID string `json:"source-room-id,omitempty"`
+	SourceID     string `json:"source-id,omitempty"`
+}
+
 // BanUserstate represents userstate for ban events
 type BanUserstate struct {
 	RoomID       string `json:"room-id,omitempty"`
@@ -416,26 +641,30 @@ type UserState struct {
 // Client state
 type clientState struct {
 	// Connection
-	ws             *websocket.Conn
+	conn           Conn
+	writer         *outboundWriter
+	connCtx        context.Context
+	connCancel     context.CancelFunc
 	server         string
 	port           int
 	secure         bool
-	reconnecting   bool
 	reconnections  int
-	reconnectTimer time.Duration
-	wasCloseCalled bool
-	reason         string
+	reconnectDelay time.Duration
+	loggedOnAt     time.Time
+	reconnectAfter *time.Timer
+	reconnected    chan struct{}
+	unsent         []unsentLine // Messages lost with the connection, sent after the next login
+	connState      ConnState
+	closeReason    DisconnectReason
+	closeMessage   string
+	tokenRetry     bool // A login is being retried with a refreshed token
 	currentLatency time.Duration
 	latency        time.Time
-	pingLoop       *time.Ticker
 	pingTimeout    *time.Timer
 
 	// Connection settings
 	maxReconnectAttempts int
-	maxReconnectInterval time.Duration
 	reconnect            bool
-	reconnectDecay       float64
-	reconnectInterval    time.Duration
 
 	// User state
 	username        string
@@ -446,6 +675,7 @@ type clientState struct {
 	userState       map[string]UserState
 	lastJoined      string
 	moderators      map[string][]string
+	roomStates      map[string]RoomState
 
 	// Settings
 	opts                 *ClientOptions
@@ -0,0 +1,212 @@
+package tmigo
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errUnsent is the result of lines still queued when their connection was
+// lost or failed a write. They never reached the connection and can be sent
+// on the next one.
+var errUnsent = fmt.Errorf("line lost with the connection: %w", ErrNotConnected)
+
+// writeRequest is a single outbound line waiting for the writer
+type writeRequest struct {
+	data   []byte
+	result chan error
+}
+
+// outboundWriter owns the write side of one connection. Connections allow a
+// single concurrent writer, so every line goes through its
+// goroutine in the order it was queued.
+type outboundWriter struct {
+	conn      Conn
+	timeout   time.Duration
+	queue     chan writeRequest
+	closing   chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	discard   bool // Set by abandon, before closing is closed
+	failed    bool // A write failed, owned by run
+
+	// Set while the connection's read loop handles a line. Listeners run on
+	// that loop by default, and the loop must be free to notice the loss of
+	// the connection.
+	reading atomic.Bool
+}
+
+// newOutboundWriter starts the writer for a connection. At most size lines
+// wait in the queue; further writers block until there is room.
+func newOutboundWriter(conn Conn, size int, timeout time.Duration) *outboundWriter {
+	w := &outboundWriter{
+		conn:    conn,
+		timeout: timeout,
+		queue:   make(chan writeRequest, size),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+// write queues a line and waits until it has been written
+func (w *outboundWriter) write(data []byte) error {
+	req := writeRequest{data: data, result: make(chan error, 1)}
+
+	select {
+	case w.queue <- req:
+	case <-w.closing:
+		return ErrNotConnected
+	}
+
+	select {
+	case err := <-req.result:
+		return err
+	case <-w.done:
+		// The writer may have finished this request right before stopping
+		select {
+		case err := <-req.result:
+			return err
+		default:
+			return ErrNotConnected
+		}
+	}
+}
+
+// close stops accepting lines, writes the ones already queued and waits
+// for the writer to stop
+func (w *outboundWriter) close() {
+	w.closeOnce.Do(func() {
+		close(w.closing)
+	})
+	<-w.done
+}
+
+// abandon stops the writer of a lost connection. Lines still queued are not
+// written; their writers get errUnsent.
+func (w *outboundWriter) abandon() {
+	w.closeOnce.Do(func() {
+		w.discard = true
+		close(w.closing)
+	})
+	<-w.done
+}
+
+// run writes queued lines until the writer is closed
+func (w *outboundWriter) run() {
+	defer close(w.done)
+
+	for {
+		select {
+		case req := <-w.queue:
+			req.result <- w.writeRequest(req.data)
+		case <-w.closing:
+			for {
+				select {
+				case req := <-w.queue:
+					if w.discard {
+						req.result <- errUnsent
+					} else {
+						req.result <- w.writeRequest(req.data)
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeRequest writes a queued line. After a failed write the connection is
+// closed so the client reconnects, and the lines after it are not written.
+func (w *outboundWriter) writeRequest(data []byte) error {
+	if w.failed {
+		return errUnsent
+	}
+	err := w.writeMessage(data)
+	if err != nil {
+		w.failed = true
+		w.conn.Close()
+	}
+	return err
+}
+
+// writeMessage writes a single line with the write deadline applied
+func (w *outboundWriter) writeMessage(data []byte) error {
+	if w.timeout > 0 {
+		if err := w.conn.SetWriteDeadline(time.Now().Add(w.timeout)); err != nil {
+			return err
+		}
+	}
+	return w.conn.WriteMessage(data)
+}
+
+// write sends a raw line through the connection's writer. Login lines,
+// keepalive pings and PONGs go through here; a line lost with its connection
+// is not sent again, the next connection sends its own.
+func (c *Client) write(line string) error {
+	_, err := c.writeOnce(line)
+	return err
+}
+
+// writeOnce writes a line through the current writer and returns the writer
+// it used
+func (c *Client) writeOnce(line string) (*outboundWriter, error) {
+	c.mu.RLock()
+	w := c.state.writer
+	c.mu.RUnlock()
+
+	if w == nil {
+		return nil, ErrNotConnected
+	}
+	return w, w.write([]byte(line))
+}
+
+// unsentLine is a message or command lost with its connection, waiting to
+// be sent after the next login
+type unsentLine struct {
+	line   string
+	result chan error
+}
+
+// writeUnsent writes a message or command. A line still queued when the
+// connection dropped is sent once the client logged in on the next one,
+// unless the connection's read loop is busy: waiting would hold up the loop
+// that notices the loss, for example when a listener sends, so the error is
+// returned right away.
+func (c *Client) writeUnsent(line string) error {
+	for {
+		w, err := c.writeOnce(line)
+		if !errors.Is(err, errUnsent) || w.reading.Load() {
+			return err
+		}
+
+		c.mu.Lock()
+		switch {
+		case c.state.writer != nil && c.state.writer != w && c.state.connState == StateConnected:
+			// Already logged in on the next connection
+			c.mu.Unlock()
+			continue
+		case c.state.writer == nil && c.state.reconnected == nil:
+			c.mu.Unlock()
+			return err
+		}
+		result := make(chan error, 1)
+		c.state.unsent = append(c.state.unsent, unsentLine{line: line, result: result})
+		c.mu.Unlock()
+
+		return <-result
+	}
+}
+
+// flushUnsent sends the lines lost with the previous connection in order
+func (c *Client) flushUnsent(lines []unsentLine) {
+	for _, u := range lines {
+		u.result <- c.writeUnsent(u.line)
+	}
+}
@@ -0,0 +1,138 @@
+package tmigo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newEchoLineServer starts a websocket server that forwards every received
+// line to the returned channel
+func newEchoLineServer(t *testing.T) (Conn, <-chan string) {
+	t.Helper()
+
+	lines := make(chan string, 1024)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- string(data)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &wsConn{conn}, lines
+}
+
+func TestOutboundWriter_ConcurrentWrites(t *testing.T) {
+	conn, lines := newEchoLineServer(t)
+	w := newOutboundWriter(conn, 4, time.Second)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.write([]byte("PING")); err != nil {
+				t.Errorf("write() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	w.close()
+
+	for i := range 50 {
+		select {
+		case line := <-lines:
+			if line != "PING" {
+				t.Fatalf("line %d = %q, want PING", i, line)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("received %d lines, want 50", i)
+		}
+	}
+}
+
+func TestOutboundWriter_DrainOnClose(t *testing.T) {
+	conn, lines := newEchoLineServer(t)
+	w := newOutboundWriter(conn, 16, time.Second)
+
+	errs := make(chan error, 10)
+	for range 10 {
+		go func() {
+			errs <- w.write([]byte("PRIVMSG #test :hi"))
+		}()
+	}
+
+	// Let the writers queue their lines before closing
+	time.Sleep(20 * time.Millisecond)
+	w.close()
+
+	written := 0
+	for range 10 {
+		if err := <-errs; err == nil {
+			written++
+		} else if !errors.Is(err, ErrNotConnected) {
+			t.Errorf("write() error = %v", err)
+		}
+	}
+
+	for range written {
+		select {
+		case <-lines:
+		case <-time.After(time.Second):
+			t.Fatalf("server did not receive all %d written lines", written)
+		}
+	}
+}
+
+func TestOutboundWriter_WriteAfterClose(t *testing.T) {
+	conn, _ := newEchoLineServer(t)
+	w := newOutboundWriter(conn, 1, time.Second)
+	w.close()
+
+	if err := w.write([]byte("PING")); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("write() error = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestOutboundWriter_ReportsWriteErrors(t *testing.T) {
+	conn, _ := newEchoLineServer(t)
+	w := newOutboundWriter(conn, 1, time.Second)
+	defer w.close()
+
+	conn.Close()
+
+	if err := w.write([]byte("PING")); err == nil {
+		t.Error("write() error = nil on a closed connection")
+	}
+}
+
+func TestClientWrite_NotConnected(t *testing.T) {
+	client := NewClient(nil)
+
+	if err := client.write("PING"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("write() error = %v, want ErrNotConnected", err)
+	}
+}